@@ -0,0 +1,212 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/HungSloth/sloth-incubator/internal/config"
+	"github.com/HungSloth/sloth-incubator/internal/template"
+	"github.com/spf13/cobra"
+)
+
+// newCacheCmd builds `incubator cache`, for inspecting and repairing the
+// template cache.
+func newCacheCmd() *cobra.Command {
+	cacheCmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Inspect and repair the template cache",
+	}
+
+	statusCmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show what is cached for each template repo",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCacheStatus(os.Stdout)
+		},
+	}
+
+	clearCmd := &cobra.Command{
+		Use:   "clear [repo...]",
+		Short: "Delete cached templates, for every repo or the given ones",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCacheClear(os.Stdout, args)
+		},
+	}
+
+	var fix bool
+	verifyCmd := &cobra.Command{
+		Use:   "verify",
+		Short: "Check every cached repo for damage, local changes and broken templates",
+		Long:  "Check every cached repo for a damaged git checkout, local changes, a commit that differs from templates.lock and templates that fail to load. With --fix, broken caches and leftovers of interrupted fetches are removed and fetched again.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			return runCacheVerify(os.Stdout, fix)
+		},
+	}
+	verifyCmd.Flags().BoolVar(&fix, "fix", false, "Remove broken caches and fetch them again")
+
+	cacheCmd.AddCommand(statusCmd, clearCmd, verifyCmd)
+	return cacheCmd
+}
+
+func runCacheStatus(w io.Writer) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	cacheDir := config.ConfigDir()
+	cache := template.NewCache(cacheDir)
+	cache.TTL = cfg.GetTemplateCacheTTL()
+	info, err := cache.LoadInfo()
+	if err != nil {
+		return err
+	}
+	lock, err := template.ReadLock(cacheDir)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "Cache:       %s\n", template.ReposDir(cacheDir))
+	lastFetch := "never"
+	if !info.LastFetch.IsZero() {
+		lastFetch = fmt.Sprintf("%s (%s ago)", info.LastFetch.Local().Format("2006-01-02 15:04"), time.Since(info.LastFetch).Round(time.Minute))
+		if cache.IsStale() {
+			lastFetch += ", stale"
+		}
+	}
+	fmt.Fprintf(w, "Last fetch:  %s\n", lastFetch)
+	fmt.Fprintf(w, "Refresh:     every %s", cache.TTL)
+	if !cfg.AutoUpdateCheck {
+		fmt.Fprint(w, " with `incubator update` (auto_update_check is off)")
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "Offline:     %t\n\n", template.Offline())
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "REPO\tSTATUS\tCOMMIT\tFETCHED")
+	for _, repo := range cfg.GetTemplateRepos() {
+		loader := template.NewLoader(cacheDir, repo)
+		status := "not fetched"
+		if loader.InPlace() {
+			status = "local directory"
+		} else if _, err := os.Stat(loader.TemplatesDir()); err == nil {
+			status = "fetched"
+		}
+		commit, fetched := "-", "-"
+		if locked, ok := lock.Repos[repo]; ok && status == "fetched" {
+			commit = orDash(locked.Commit)
+			if len(commit) > 12 {
+				commit = commit[:12]
+			}
+			fetched = locked.FetchedAt.Local().Format("2006-01-02 15:04")
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", repo, status, commit, fetched)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if leftovers := template.CacheLeftovers(cacheDir); len(leftovers) > 0 {
+		fmt.Fprintf(w, "\n%d leftover(s) of interrupted fetches; `incubator cache verify --fix` removes them.\n", len(leftovers))
+	}
+	return nil
+}
+
+func runCacheClear(w io.Writer, repos []string) error {
+	cacheDir := config.ConfigDir()
+	if len(repos) == 0 {
+		if err := template.ClearCache(cacheDir); err != nil {
+			return err
+		}
+		fmt.Fprintln(w, "Cleared the template cache. Templates are fetched again on the next launch or `incubator update`.")
+		return nil
+	}
+	for _, arg := range repos {
+		repo, err := template.NormalizeRepo(arg)
+		if err != nil {
+			return err
+		}
+		if err := template.ClearRepoCache(cacheDir, repo); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "Cleared cached templates for %s\n", repo)
+	}
+	return nil
+}
+
+func runCacheVerify(w io.Writer, fix bool) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	cacheDir := config.ConfigDir()
+
+	broken := 0
+	for _, repo := range cfg.GetTemplateRepos() {
+		if _, err := os.Stat(template.NewLoader(cacheDir, repo).TemplatesDir()); err != nil {
+			fmt.Fprintf(w, "-       %s (not fetched)\n", repo)
+			continue
+		}
+		problems := template.VerifyRepoCache(cacheDir, repo)
+		if len(problems) == 0 {
+			fmt.Fprintf(w, "ok      %s\n", repo)
+			continue
+		}
+		fmt.Fprintf(w, "BROKEN  %s\n", repo)
+		for _, p := range problems {
+			fmt.Fprintf(w, "  %s\n", p)
+		}
+		if !fix {
+			broken++
+			continue
+		}
+		if err := repairRepoCache(cacheDir, repo); err != nil {
+			fmt.Fprintf(w, "  could not repair: %v\n", err)
+			broken++
+			continue
+		}
+		fmt.Fprintf(w, "  repaired\n")
+	}
+
+	leftovers := template.CacheLeftovers(cacheDir)
+	for _, path := range leftovers {
+		if fix {
+			if err := os.RemoveAll(path); err != nil {
+				return err
+			}
+			fmt.Fprintf(w, "removed %s\n", path)
+		} else {
+			fmt.Fprintf(w, "leftover %s\n", path)
+		}
+	}
+
+	if broken > 0 || (len(leftovers) > 0 && !fix) {
+		hint := "run `incubator cache verify --fix` to repair"
+		if fix {
+			hint = "run `incubator cache clear` to start over"
+		}
+		return fmt.Errorf("template cache has problems; %s", hint)
+	}
+	return nil
+}
+
+// repairRepoCache throws away the cached copy of repo and fetches it again,
+// unless offline, in which case the cache is left for a later fetch.
+func repairRepoCache(cacheDir, repo string) error {
+	loader := template.NewLoader(cacheDir, repo)
+	if loader.InPlace() {
+		return fmt.Errorf("%s is a local directory; fix it in place", repo)
+	}
+	if template.Offline() {
+		return template.ErrOffline
+	}
+	if err := template.ClearRepoCache(cacheDir, repo); err != nil {
+		return err
+	}
+	return loader.FetchTemplates()
+}
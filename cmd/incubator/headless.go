@@ -0,0 +1,287 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/HungSloth/sloth-incubator/internal/config"
+	"github.com/HungSloth/sloth-incubator/internal/scaffold"
+	"github.com/HungSloth/sloth-incubator/internal/template"
+	"gopkg.in/yaml.v3"
+)
+
+// headlessOptions holds the flags for non-interactive scaffolding.
+type headlessOptions struct {
+	templateName string
+	answersFile  string
+	sets         []string
+	yes          bool
+	output       string
+	noHooks      bool
+	trustHooks   bool
+}
+
+// runHeadlessNew scaffolds a project without the TUI, using flags and an answers file.
+func runHeadlessNew(opts headlessOptions) error {
+	if opts.output != "text" && opts.output != "json" {
+		return fmt.Errorf("unsupported --output %q: use text or json", opts.output)
+	}
+
+	cfg, _ := config.Load()
+	if cfg == nil {
+		cfg = config.DefaultConfig()
+	}
+	if !template.Offline() && template.NewCache(config.ConfigDir()).NeedsInitialFetch() {
+		// Scripts often run on fresh machines that have never fetched templates.
+		fmt.Fprintln(os.Stderr, "Fetching templates...")
+		if err := fetchTemplates(cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not fetch templates: %v\n", err)
+		}
+	}
+	manifest, err := findTemplateVersion(loadAllTemplates(cfg), opts.templateName, config.ConfigDir())
+	if err != nil {
+		return err
+	}
+
+	provided, err := readAnswers(manifest, opts.answersFile, opts.sets)
+	if err != nil {
+		return err
+	}
+	answers, err := template.ResolveAnswers(manifest, provided)
+	if err != nil {
+		return err
+	}
+
+	runOpts := scaffold.Options{
+		Manifest: manifest,
+		Answers:  answers,
+		Config:   cfg,
+		NoHooks:  opts.noHooks,
+	}
+
+	trustHook, err := checkHookConsent(runOpts, opts.trustHooks)
+	if err != nil {
+		return err
+	}
+
+	if !opts.yes {
+		printHeadlessPlan(os.Stderr, runOpts)
+		ok, err := confirmPrompt(os.Stdin, os.Stderr, "Proceed?")
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("aborted")
+		}
+	}
+
+	if trustHook {
+		if err := scaffold.TrustHook(runOpts); err != nil {
+			return err
+		}
+	}
+
+	report := textReporter(os.Stdout)
+	runOpts.HookOutput = os.Stdout
+	if opts.output == "json" {
+		report = jsonReporter(os.Stdout)
+		// Keep stdout machine-readable; hook output goes to stderr instead.
+		runOpts.HookOutput = os.Stderr
+	}
+
+	result, runErr := scaffold.Run(runOpts, report)
+	if opts.output == "json" {
+		summary := map[string]interface{}{
+			"status":      "done",
+			"project_dir": result.ProjectDir,
+		}
+		if result.RepoURL != "" {
+			summary["repo_url"] = result.RepoURL
+		}
+		if scaffold.GitHubSkippedOffline(runOpts) {
+			summary["notice"] = scaffold.OfflineGitHubNote
+		}
+		if runErr != nil {
+			summary["status"] = "failed"
+			summary["error"] = runErr.Error()
+		}
+		_ = json.NewEncoder(os.Stdout).Encode(summary)
+	} else if runErr == nil {
+		fmt.Printf("\nProject is ready: %s\n", result.ProjectDir)
+		if result.RepoURL != "" {
+			fmt.Printf("Remote: %s\n", result.RepoURL)
+		}
+		if scaffold.GitHubSkippedOffline(runOpts) {
+			fmt.Println(scaffold.OfflineGitHubNote)
+		}
+	}
+
+	return runErr
+}
+
+// checkHookConsent refuses to run an untrusted post-create hook unless the user
+// passed --trust-hooks. It reports whether the hook should be recorded as trusted
+// once the run is confirmed.
+func checkHookConsent(opts scaffold.Options, trustHooks bool) (bool, error) {
+	if !scaffold.ShouldRunHooks(opts) {
+		return false, nil
+	}
+	trusted, err := scaffold.HookTrusted(opts)
+	if err != nil {
+		return false, err
+	}
+	if trusted {
+		return false, nil
+	}
+	if !trustHooks {
+		return false, fmt.Errorf("template %s has an untrusted post-create hook (%s): review it and pass --trust-hooks to run it or --no-hooks to skip it", opts.Manifest.Name, opts.Manifest.Hooks.PostCreate)
+	}
+	return true, nil
+}
+
+// findTemplate looks up a template by plain or repo-qualified name
+// (owner/repo:template). A plain name shared by several repos is ambiguous.
+func findTemplate(manifests []*template.TemplateManifest, name string) (*template.TemplateManifest, error) {
+	var matches []*template.TemplateManifest
+	for _, m := range manifests {
+		if m.QualifiedName() == name {
+			return m, nil
+		}
+		if m.Name == name {
+			matches = append(matches, m)
+		}
+	}
+
+	switch len(matches) {
+	case 1:
+		return matches[0], nil
+	case 0:
+		names := make([]string, 0, len(manifests))
+		for _, m := range manifests {
+			names = append(names, m.QualifiedName())
+		}
+		return nil, fmt.Errorf("template %q not found (available: %s)", name, strings.Join(names, ", "))
+	default:
+		names := make([]string, 0, len(matches))
+		for _, m := range matches {
+			names = append(names, m.QualifiedName())
+		}
+		return nil, fmt.Errorf("template name %q is ambiguous, use one of: %s", name, strings.Join(names, ", "))
+	}
+}
+
+// findTemplateVersion is findTemplate for names that may end in @version, e.g.
+// go-cli@1.2.0. A remote template at another version is checked out from the
+// history of its repo.
+func findTemplateVersion(manifests []*template.TemplateManifest, name, cacheDir string) (*template.TemplateManifest, error) {
+	name, version := template.SplitRef(name)
+	manifest, err := findTemplate(manifests, name)
+	if err != nil || version == "" {
+		return manifest, err
+	}
+	return template.LoadTemplateVersion(manifest, cacheDir, version)
+}
+
+// readAnswers merges the answers file with --set overrides, later values winning.
+func readAnswers(manifest *template.TemplateManifest, answersFile string, sets []string) (map[string]interface{}, error) {
+	answers := map[string]interface{}{}
+
+	if answersFile != "" {
+		data, err := os.ReadFile(answersFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading answers file: %w", err)
+		}
+		if err := yaml.Unmarshal(data, &answers); err != nil {
+			return nil, fmt.Errorf("parsing answers file: %w", err)
+		}
+	}
+
+	for _, set := range sets {
+		key, raw, ok := strings.Cut(set, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid --set %q: expected key=value", set)
+		}
+		value, err := template.ParseAnswerValue(manifest, key, raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --set %s: %w", key, err)
+		}
+		answers[key] = value
+	}
+
+	return answers, nil
+}
+
+func printHeadlessPlan(w io.Writer, opts scaffold.Options) {
+	fmt.Fprintf(w, "Template:  %s\n", opts.Manifest.QualifiedName())
+	fmt.Fprintf(w, "Directory: %s\n", scaffold.ProjectDir(opts))
+
+	shown := template.PersistableAnswers(opts.Manifest, opts.Answers)
+	keys := make([]string, 0, len(opts.Answers))
+	for key := range opts.Answers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	fmt.Fprintln(w, "Answers:")
+	for _, key := range keys {
+		if value, ok := shown[key]; ok {
+			fmt.Fprintf(w, "  %s: %s\n", key, template.FormatAnswer(value))
+		} else {
+			fmt.Fprintf(w, "  %s: ********\n", key)
+		}
+	}
+	fmt.Fprintln(w, "Steps:")
+	for _, step := range scaffold.Steps(opts) {
+		fmt.Fprintf(w, "  - %s\n", step)
+	}
+	if scaffold.GitHubSkippedOffline(opts) {
+		fmt.Fprintln(w, "  (creating the GitHub repo is skipped in offline mode)")
+	}
+	if scaffold.ShouldRunHooks(opts) {
+		if script, err := scaffold.HookScript(opts); err == nil {
+			fmt.Fprintf(w, "Post-create hook (%s):\n", opts.Manifest.Hooks.PostCreate)
+			for _, line := range strings.Split(strings.TrimRight(script, "\n"), "\n") {
+				fmt.Fprintf(w, "  | %s\n", line)
+			}
+		}
+	}
+}
+
+// confirmPrompt asks a yes/no question on the given reader, defaulting to no.
+func confirmPrompt(r io.Reader, w io.Writer, question string) (bool, error) {
+	fmt.Fprintf(w, "%s [y/N] ", question)
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && line == "" {
+		return false, fmt.Errorf("no confirmation received (use --yes to skip)")
+	}
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "y", "yes":
+		return true, nil
+	}
+	return false, nil
+}
+
+func textReporter(w io.Writer) func(scaffold.Event) {
+	return func(e scaffold.Event) {
+		switch e.Status {
+		case scaffold.EventRunning:
+			fmt.Fprintf(w, "• %s...\n", e.Step)
+		case scaffold.EventDone:
+			fmt.Fprintf(w, "✓ %s\n", e.Step)
+		case scaffold.EventFailed:
+			fmt.Fprintf(w, "✗ %s: %s\n", e.Step, e.Error)
+		}
+	}
+}
+
+func jsonReporter(w io.Writer) func(scaffold.Event) {
+	enc := json.NewEncoder(w)
+	return func(e scaffold.Event) {
+		_ = enc.Encode(e)
+	}
+}
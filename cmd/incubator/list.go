@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/HungSloth/sloth-incubator/internal/config"
+	"github.com/HungSloth/sloth-incubator/internal/template"
+)
+
+// listOptions holds the flags for `incubator list`.
+type listOptions struct {
+	all    bool
+	json   bool
+	source string
+	search string
+}
+
+// listEntry is one row of `incubator list`, valid or not.
+type listEntry struct {
+	Name        string `json:"name"`
+	Source      string `json:"source"`
+	Repo        string `json:"repo,omitempty"`
+	Version     string `json:"version,omitempty"`
+	Author      string `json:"author,omitempty"`
+	Description string `json:"description,omitempty"`
+	Path        string `json:"path,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+func runList(w io.Writer, opts listOptions) error {
+	switch opts.source {
+	case "", template.SourceBuiltin, template.SourceLocal, template.SourceRemote:
+	default:
+		return fmt.Errorf("invalid --source %q: use builtin, local or remote", opts.source)
+	}
+
+	cfg, _ := config.Load()
+	manifests, invalid := loadTemplates(cfg)
+	return printList(w, os.Stderr, listEntries(manifests, invalid, config.ConfigDir()), opts)
+}
+
+// listEntries turns the loaded and the failed templates into list rows.
+func listEntries(manifests []*template.TemplateManifest, invalid []template.InvalidTemplate, cacheDir string) []listEntry {
+	var entries []listEntry
+	for _, m := range manifests {
+		entries = append(entries, listEntry{
+			Name:        m.QualifiedName(),
+			Source:      m.Source(),
+			Repo:        m.Repo,
+			Version:     m.Version,
+			Author:      m.Author,
+			Description: m.Description,
+			Path:        template.TemplateDir(m, cacheDir),
+		})
+	}
+	for _, t := range invalid {
+		entry := listEntry{
+			Name:   t.QualifiedName(),
+			Source: t.Source(),
+			Repo:   t.Repo,
+			Path:   t.Path,
+			Error:  t.Err.Error(),
+		}
+		if t.Manifest != nil {
+			entry.Version, entry.Author, entry.Description = t.Manifest.Version, t.Manifest.Author, t.Manifest.Description
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// printList filters entries by opts and writes them to w as a table or JSON.
+// Without --all, failed templates are left out and counted on errW.
+func printList(w, errW io.Writer, entries []listEntry, opts listOptions) error {
+	entries = filterListEntries(entries, opts.source, opts.search)
+
+	hidden := 0
+	if !opts.all {
+		valid := entries[:0]
+		for _, e := range entries {
+			if e.Error != "" {
+				hidden++
+				continue
+			}
+			valid = append(valid, e)
+		}
+		entries = valid
+	}
+
+	if opts.json {
+		if entries == nil {
+			entries = []listEntry{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tSOURCE\tVERSION\tAUTHOR\tPATH\tDESCRIPTION")
+	for _, e := range entries {
+		description := e.Description
+		if e.Error != "" {
+			description = "INVALID: " + strings.Join(strings.Fields(e.Error), " ")
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", e.Name, e.Source, orDash(e.Version), orDash(e.Author), orDash(e.Path), description)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if hidden > 0 {
+		fmt.Fprintf(errW, "\n%d template(s) failed to load; run `incubator list --all` to see why.\n", hidden)
+	}
+	return nil
+}
+
+// filterListEntries keeps entries from source (if set) whose name or
+// description contains search, ignoring case.
+func filterListEntries(entries []listEntry, source, search string) []listEntry {
+	query := strings.ToLower(strings.TrimSpace(search))
+	var kept []listEntry
+	for _, e := range entries {
+		if source != "" && e.Source != source {
+			continue
+		}
+		if query != "" && !strings.Contains(strings.ToLower(e.Name), query) && !strings.Contains(strings.ToLower(e.Description), query) {
+			continue
+		}
+		kept = append(kept, e)
+	}
+	return kept
+}
+
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
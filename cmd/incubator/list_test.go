@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/HungSloth/sloth-incubator/internal/config"
+	"github.com/HungSloth/sloth-incubator/internal/template"
+)
+
+func testListEntries() []listEntry {
+	return listEntries(
+		[]*template.TemplateManifest{
+			template.GetBuiltinManifest(),
+			{Name: "go-api", Repo: "acme/templates", Description: "Go HTTP service", SourcePath: "go-api"},
+			{Name: "notes", Description: "Personal API notes", SourcePath: "/home/me/.incubator/local-templates/notes"},
+		},
+		[]template.InvalidTemplate{
+			{Name: "broken", Repo: "acme/templates", Path: "/cache/broken", Err: errors.New("parsing template.yaml:\n  bad indent")},
+		},
+		"/cache",
+	)
+}
+
+func TestFilterListEntries(t *testing.T) {
+	entries := testListEntries()
+	tests := []struct {
+		source string
+		search string
+		want   []string
+	}{
+		{"", "", []string{"empty", "acme/templates:go-api", "notes", "acme/templates:broken"}},
+		{template.SourceRemote, "", []string{"acme/templates:go-api", "acme/templates:broken"}},
+		{template.SourceLocal, "", []string{"notes"}},
+		{"", "API", []string{"acme/templates:go-api", "notes"}},
+		{"", "http service", []string{"acme/templates:go-api"}},
+		{template.SourceBuiltin, "api", nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, e := range filterListEntries(entries, tt.source, tt.search) {
+			got = append(got, e.Name)
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Fatalf("source %q search %q: expected %v, got %v", tt.source, tt.search, tt.want, got)
+		}
+	}
+}
+
+func TestPrintListHidesInvalidTemplatesUnlessAll(t *testing.T) {
+	entries := testListEntries()
+
+	var out, errOut bytes.Buffer
+	if err := printList(&out, &errOut, entries, listOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out.String(), "broken") || !strings.Contains(errOut.String(), "1 template(s) failed to load") {
+		t.Fatalf("expected the invalid template to be hidden and counted:\n%s\n%s", out.String(), errOut.String())
+	}
+
+	out.Reset()
+	errOut.Reset()
+	if err := printList(&out, &errOut, entries, listOptions{all: true}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "INVALID: parsing template.yaml: bad indent") || errOut.Len() != 0 {
+		t.Fatalf("expected --all to show the error on one line:\n%s\n%s", out.String(), errOut.String())
+	}
+
+	// Filters apply before the hidden count.
+	out.Reset()
+	errOut.Reset()
+	if err := printList(&out, &errOut, entries, listOptions{json: true, source: template.SourceLocal}); err != nil {
+		t.Fatal(err)
+	}
+	var decoded []listEntry
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatalf("expected JSON output: %v\n%s", err, out.String())
+	}
+	if len(decoded) != 1 || decoded[0].Name != "notes" || errOut.Len() != 0 {
+		t.Fatalf("expected only the local template, got %+v (%s)", decoded, errOut.String())
+	}
+}
+
+func TestLoadTemplatesReportsUnreadableLocalDir(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	// A file where the directory should be cannot be read as one.
+	localDir := filepath.Join(t.TempDir(), "local-templates")
+	if err := os.WriteFile(localDir, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.DefaultConfig()
+	cfg.LocalTemplateDir = localDir
+
+	_, invalid := loadTemplates(cfg)
+	if len(invalid) != 1 || invalid[0].Path != localDir || invalid[0].Source() != template.SourceLocal {
+		t.Fatalf("expected the local directory to be reported, got %+v", invalid)
+	}
+}
@@ -2,13 +2,16 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/HungSloth/sloth-incubator/internal/config"
 	"github.com/HungSloth/sloth-incubator/internal/container"
+	"github.com/HungSloth/sloth-incubator/internal/diff"
 	"github.com/HungSloth/sloth-incubator/internal/preview"
+	"github.com/HungSloth/sloth-incubator/internal/scaffold"
 	"github.com/HungSloth/sloth-incubator/internal/template"
 	"github.com/HungSloth/sloth-incubator/internal/tui"
 	tea "github.com/charmbracelet/bubbletea"
@@ -18,22 +21,54 @@ import (
 var version = "dev"
 
 func main() {
+	var rootNoHooks, offline bool
+
 	rootCmd := &cobra.Command{
 		Use:   "incubator",
 		Short: "Sloth Incubator — scaffold new projects with ease",
 		Long:  "A CLI/TUI tool that standardizes how projects are created. Pick a template, answer a few questions, and get a fully scaffolded project.",
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			if offline {
+				template.SetOffline(true)
+			}
+			if template.Offline() {
+				config.DetectGitHubUser = false
+			}
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return launchTUI()
+			return launchTUI(rootNoHooks)
 		},
 	}
+	rootCmd.Flags().BoolVar(&rootNoHooks, "no-hooks", false, "Never run template post-create hooks")
+	rootCmd.PersistentFlags().BoolVar(&offline, "offline", false, "Use cached templates only and never touch the network (also INCUBATOR_OFFLINE=1)")
+
+	var newOpts headlessOptions
 
 	newCmd := &cobra.Command{
 		Use:   "new",
 		Short: "Create a new project",
+		Long:  "Create a new project. Without --template the interactive TUI is launched; with --template the project is scaffolded non-interactively from --answers and --set.",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return launchTUI()
+			if newOpts.templateName == "" {
+				if newOpts.answersFile != "" || len(newOpts.sets) > 0 || newOpts.yes {
+					return fmt.Errorf("--answers, --set and --yes require --template")
+				}
+				return launchTUI(newOpts.noHooks)
+			}
+			cmd.SilenceUsage = true
+			return runHeadlessNew(newOpts)
 		},
 	}
+	newCmd.Flags().StringVar(&newOpts.templateName, "template", "", "Template to scaffold non-interactively, optionally as name@version")
+	newCmd.Flags().StringVar(&newOpts.answersFile, "answers", "", "YAML or JSON file with prompt answers")
+	newCmd.Flags().StringArrayVar(&newOpts.sets, "set", nil, "Set a prompt answer (key=value, repeatable)")
+	newCmd.Flags().BoolVarP(&newOpts.yes, "yes", "y", false, "Skip the confirmation prompt")
+	newCmd.Flags().StringVar(&newOpts.output, "output", "text", "Progress output format: text or json")
+	newCmd.Flags().BoolVar(&newOpts.noHooks, "no-hooks", false, "Never run template post-create hooks")
+	newCmd.Flags().BoolVar(&newOpts.trustHooks, "trust-hooks", false, "Trust and run the template's post-create hook without asking")
+
+	var initNoHooks bool
+	var initDryRun bool
 
 	initCmd := &cobra.Command{
 		Use:   "init [path]",
@@ -55,22 +90,26 @@ func main() {
 			if !info.IsDir() {
 				return fmt.Errorf("target path is not a directory: %s", absDir)
 			}
-			return launchInitTUI(absDir)
+			return launchInitTUI(absDir, initNoHooks, initDryRun)
 		},
 	}
+	initCmd.Flags().BoolVar(&initNoHooks, "no-hooks", false, "Never run template post-create hooks")
+	initCmd.Flags().BoolVar(&initDryRun, "dry-run", false, "Show what would be created and diff existing files without writing anything")
+
+	var listOpts listOptions
 
 	listCmd := &cobra.Command{
 		Use:   "list",
 		Short: "List available templates",
-		Run: func(cmd *cobra.Command, args []string) {
-			cfg, _ := config.Load()
-			manifests := loadAllTemplates(cfg)
-			fmt.Printf("%-15s %s\n", "NAME", "DESCRIPTION")
-			for _, m := range manifests {
-				fmt.Printf("%-15s %s\n", m.Name, m.Description)
-			}
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			return runList(os.Stdout, listOpts)
 		},
 	}
+	listCmd.Flags().BoolVar(&listOpts.all, "all", false, "Also show templates that failed to load, with their errors")
+	listCmd.Flags().BoolVar(&listOpts.json, "json", false, "Print the templates as JSON")
+	listCmd.Flags().StringVar(&listOpts.source, "source", "", "Only show templates from this source: builtin, local or remote")
+	listCmd.Flags().StringVar(&listOpts.search, "search", "", "Only show templates whose name or description contains this text")
 
 	versionCmd := &cobra.Command{
 		Use:   "version",
@@ -84,18 +123,28 @@ func main() {
 		Use:   "update",
 		Short: "Update templates and check for binary updates",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if template.Offline() {
+				cmd.SilenceUsage = true
+				return fmt.Errorf("cannot update templates: %w", template.ErrOffline)
+			}
 			cfg, err := config.Load()
 			if err != nil {
 				return err
 			}
 
-			// Refresh templates
+			// Refresh templates from every configured repo
 			cacheDir := config.ConfigDir()
-			loader := template.NewLoader(cacheDir, cfg.TemplateRepo)
+			repos := cfg.GetTemplateRepos()
 			fmt.Println("Refreshing templates...")
-			if err := loader.FetchTemplates(); err != nil {
-				fmt.Fprintf(os.Stderr, "Warning: failed to refresh templates: %v\n", err)
-			} else {
+			failed := template.FetchAllTemplates(cacheDir, repos)
+			for _, repo := range repos {
+				if err, ok := failed[repo]; ok {
+					fmt.Fprintf(os.Stderr, "Warning: failed to refresh %s: %v\n", repo, err)
+				} else {
+					fmt.Printf("  %s updated\n", repo)
+				}
+			}
+			if len(failed) < len(repos) {
 				cache := template.NewCache(cacheDir)
 				cache.MarkFetched()
 				fmt.Println("Templates updated.")
@@ -133,19 +182,96 @@ func main() {
 	configCmd.Flags().Bool("show", false, "Show current config without editing")
 
 	addRepoCmd := &cobra.Command{
-		Use:   "add-repo [url]",
+		Use:   "add-repo [repo]",
 		Short: "Add a community template repository",
+		Long:  "Add a community template repository: owner/repo on GitHub, any git URL (https://, ssh://, git@host:path, file://) or a local directory. Append @tag, @branch or @commit, e.g. owner/repo@v1.4.0, to pin it instead of following its default branch.",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			cfg, err := config.Load()
 			if err != nil {
 				return err
 			}
-			cfg.AddTemplateRepo(args[0])
+			repo, err := template.NormalizeRepo(args[0])
+			if err != nil {
+				return err
+			}
+			cfg.AddTemplateRepo(repo)
 			if err := cfg.Save(); err != nil {
 				return err
 			}
-			fmt.Printf("Added template repo: %s\n", args[0])
+			fmt.Printf("Added template repo: %s\n", repo)
+			return nil
+		},
+	}
+
+	removeRepoCmd := &cobra.Command{
+		Use:   "remove-repo [repo]",
+		Short: "Remove a community template repository",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+			repo, err := template.NormalizeRepo(args[0])
+			if err != nil {
+				return err
+			}
+			if repo == cfg.TemplateRepo {
+				return fmt.Errorf("%s is the primary template repo; change template_repo with `incubator config` instead", repo)
+			}
+			if !containsString(cfg.TemplateRepos, repo) {
+				return fmt.Errorf("template repo %s is not configured", repo)
+			}
+			cfg.RemoveTemplateRepo(repo)
+			if err := cfg.Save(); err != nil {
+				return err
+			}
+			// A local directory read in place is the user's own, not a cache.
+			if loader := template.NewLoader(config.ConfigDir(), repo); !loader.InPlace() {
+				if err := os.RemoveAll(loader.TemplatesDir()); err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: could not remove cached templates for %s: %v\n", repo, err)
+				}
+			}
+			if err := template.RemoveLock(config.ConfigDir(), repo); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: could not update %s: %v\n", template.LockFileName, err)
+			}
+			fmt.Printf("Removed template repo: %s\n", repo)
+			return nil
+		},
+	}
+
+	reposCmd := &cobra.Command{
+		Use:   "repos",
+		Short: "List configured template repositories",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+			cacheDir := config.ConfigDir()
+			lock, err := template.ReadLock(cacheDir)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("%-45s %-10s %s\n", "REPO", "ROLE", "CACHE")
+			for _, repo := range cfg.GetTemplateRepos() {
+				role := "community"
+				if repo == cfg.TemplateRepo {
+					role = "primary"
+				}
+				loader := template.NewLoader(cacheDir, repo)
+				status := "not fetched"
+				if loader.InPlace() {
+					status = "local directory"
+				} else if _, err := os.Stat(loader.TemplatesDir()); err == nil {
+					status = "fetched"
+					if locked, ok := lock.Repos[repo]; ok && len(locked.Commit) >= 12 {
+						status += " at " + locked.Commit[:12]
+					}
+				}
+				fmt.Printf("%-45s %-10s %s\n", repo, role, status)
+			}
 			return nil
 		},
 	}
@@ -265,54 +391,190 @@ func main() {
 	cleanCmd.Flags().BoolVar(&cleanDryRun, "dry-run", false, "Show planned actions without making changes")
 	cleanCmd.Flags().BoolVar(&cleanVolumes, "volumes", false, "Also remove container volumes")
 
-	rootCmd.AddCommand(newCmd, initCmd, listCmd, versionCmd, updateCmd, configCmd, addRepoCmd, createTemplateCmd, previewCmd, cleanCmd)
+	var upgradeOpts upgradeOptions
+
+	upgradeCmd := &cobra.Command{
+		Use:   "upgrade [path]",
+		Short: "Update a project to the latest version of its template",
+		Long:  "Re-render the project's template at its latest version and three-way merge the changes with your edits, using the template and answers recorded in .incubator/state.yaml.",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			projectDir := "."
+			if len(args) == 1 {
+				projectDir = args[0]
+			}
+			cmd.SilenceUsage = true
+			return runUpgrade(projectDir, upgradeOpts)
+		},
+	}
+	upgradeCmd.Flags().StringArrayVar(&upgradeOpts.sets, "set", nil, "Set a prompt answer, e.g. for prompts added by the new version (key=value, repeatable)")
+	upgradeCmd.Flags().BoolVarP(&upgradeOpts.yes, "yes", "y", false, "Apply without the review screen; conflicts are written with conflict markers")
+	upgradeCmd.Flags().BoolVar(&upgradeOpts.dryRun, "dry-run", false, "List the changes without writing anything")
+
+	rootCmd.AddCommand(newCmd, initCmd, upgradeCmd, listCmd, versionCmd, updateCmd, configCmd, addRepoCmd, removeRepoCmd, reposCmd, newCacheCmd(), createTemplateCmd, newTemplateCmd(), previewCmd, cleanCmd)
 
 	if err := rootCmd.Execute(); err != nil {
 		os.Exit(1)
 	}
 }
 
-func launchTUI() error {
+func launchTUI(noHooks bool) error {
 	cfg, _ := config.Load()
 	manifests := loadAllTemplates(cfg)
-	p := tea.NewProgram(tui.NewApp(manifests, cfg), tea.WithAltScreen())
+	app := tui.NewApp(manifests, cfg).WithNoHooks(noHooks)
+	if refresh, firstFetch := templateRefresh(cfg); refresh != nil {
+		app = app.WithTemplateRefresh(refresh, firstFetch)
+	}
+	p := tea.NewProgram(app, tea.WithAltScreen())
 	_, err := p.Run()
 	return err
 }
 
-func launchInitTUI(initDir string) error {
+func launchInitTUI(initDir string, noHooks, dryRun bool) error {
 	cfg, _ := config.Load()
 	manifests := loadAllTemplates(cfg)
-	p := tea.NewProgram(tui.NewInitApp(manifests, cfg, initDir), tea.WithAltScreen())
-	_, err := p.Run()
-	return err
+	app := tui.NewInitApp(manifests, cfg, initDir).WithNoHooks(noHooks).WithDryRun(dryRun)
+	if refresh, firstFetch := templateRefresh(cfg); refresh != nil {
+		app = app.WithTemplateRefresh(refresh, firstFetch)
+	}
+	p := tea.NewProgram(app, tea.WithAltScreen())
+	finalModel, err := p.Run()
+	if err != nil || !dryRun {
+		return err
+	}
+
+	if finalApp, ok := finalModel.(tui.App); ok {
+		if files, actions, ok := finalApp.DryRunResult(); ok {
+			printInitDryRun(os.Stdout, files, actions)
+		}
+	}
+	return nil
+}
+
+// printInitDryRun lists what init would do with each file, followed by the diff
+// of every existing file the template would change.
+func printInitDryRun(w io.Writer, files []scaffold.InitFile, actions map[string]scaffold.InitAction) {
+	for _, f := range files {
+		switch {
+		case !f.Exists():
+			fmt.Fprintf(w, "create     %s\n", f.Path)
+		case f.Identical():
+			fmt.Fprintf(w, "identical  %s\n", f.Path)
+		case actions[f.Path] == scaffold.InitOverwrite:
+			fmt.Fprintf(w, "overwrite  %s\n", f.Path)
+		case actions[f.Path] == scaffold.InitWriteNew:
+			fmt.Fprintf(w, "create     %s%s\n", f.Path, scaffold.IncubatorNewSuffix)
+		default:
+			fmt.Fprintf(w, "skip       %s (differs from template)\n", f.Path)
+		}
+	}
+
+	for _, f := range files {
+		if f.Exists() && !f.Identical() {
+			fmt.Fprintf(w, "\n%s", diff.Unified("existing/"+f.Path, "template/"+f.Path, string(f.Existing), string(f.Rendered), 3))
+		}
+	}
+	fmt.Fprintln(w, "\nDry run: nothing was written.")
 }
 
 func loadAllTemplates(cfg *config.Config) []*template.TemplateManifest {
+	manifests, _ := loadTemplates(cfg)
+	return manifests
+}
+
+// templateRefresh returns the fetch the TUI runs in the background on launch:
+// the first fetch when nothing has been fetched yet (firstFetch), or a refresh
+// when auto_update_check is on and the cache is older than template_cache_ttl.
+// It returns nil when the cache is fresh or in offline mode.
+func templateRefresh(cfg *config.Config) (refresh func() ([]*template.TemplateManifest, error), firstFetch bool) {
+	if cfg == nil {
+		cfg = config.DefaultConfig()
+	}
+	cacheDir := config.ConfigDir()
+	cache := template.NewCache(cacheDir)
+	cache.TTL = cfg.GetTemplateCacheTTL()
+
+	if template.Offline() {
+		return nil, false
+	}
+	firstFetch = cache.NeedsInitialFetch()
+	if !firstFetch && !(cfg.AutoUpdateCheck && cache.IsStale()) {
+		return nil, false
+	}
+	return func() ([]*template.TemplateManifest, error) {
+		err := fetchTemplates(cfg)
+		return loadAllTemplates(cfg), err
+	}, firstFetch
+}
+
+// fetchTemplates fetches every configured repo and marks the cache fresh if
+// any of them succeeded. The error names the repos that failed.
+func fetchTemplates(cfg *config.Config) error {
+	cacheDir := config.ConfigDir()
+	repos := cfg.GetTemplateRepos()
+	failed := template.FetchAllTemplates(cacheDir, repos)
+	if len(failed) < len(repos) {
+		template.NewCache(cacheDir).MarkFetched()
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	var names []string
+	for _, repo := range repos {
+		if _, ok := failed[repo]; ok {
+			names = append(names, repo)
+		}
+	}
+	if len(failed) == 1 {
+		return fmt.Errorf("%s: %v", names[0], failed[names[0]])
+	}
+	return fmt.Errorf("%d repos failed: %s", len(failed), strings.Join(names, ", "))
+}
+
+// loadTemplates loads the built-in, cached remote and local templates, and
+// returns the ones that failed to load or compose separately.
+func loadTemplates(cfg *config.Config) ([]*template.TemplateManifest, []template.InvalidTemplate) {
 	// Start with the built-in template
 	manifests := []*template.TemplateManifest{
 		template.GetBuiltinManifest(),
 	}
+	var invalid []template.InvalidTemplate
+
+	if cfg == nil {
+		cfg = config.DefaultConfig()
+	}
 
-	// Try loading from cache
+	// Load every configured repo that has been fetched, or is read in place
 	cacheDir := config.ConfigDir()
-	loader := template.NewLoader(cacheDir, cfg.TemplateRepo)
-	cache := template.NewCache(cacheDir)
+	remote, broken := template.LoadRepoTemplates(cacheDir, cfg.GetTemplateRepos())
+	manifests = append(manifests, remote...)
+	invalid = append(invalid, broken...)
+
+	localDir := cfg.GetLocalTemplateDir()
+	local, broken, err := template.LoadLocalTemplates(localDir)
+	if err != nil {
+		// An unreadable directory is listed like a broken template instead of
+		// hiding every local template without a word.
+		broken = append(broken, template.InvalidTemplate{Name: filepath.Base(localDir), Path: localDir, Err: err})
+	}
+	manifests = append(manifests, local...)
+	invalid = append(invalid, broken...)
 
-	// Background refresh if stale
-	if !cache.NeedsInitialFetch() {
-		if remote, err := loader.LoadAllManifests(); err == nil && len(remote) > 0 {
-			manifests = append(manifests, remote...)
-		}
+	composed, failed := template.ComposeManifests(manifests)
+	for _, f := range failed {
+		f.Path = template.TemplateDir(f.Manifest, cacheDir)
+		invalid = append(invalid, f)
 	}
+	return composed, invalid
+}
 
-	if cfg != nil {
-		if local, err := template.LoadLocalManifests(cfg.GetLocalTemplateDir()); err == nil && len(local) > 0 {
-			manifests = append(manifests, local...)
+func containsString(values []string, want string) bool {
+	for _, v := range values {
+		if v == want {
+			return true
 		}
 	}
-
-	return manifests
+	return false
 }
 
 func printDevcontainers(containers []container.DevContainer) {
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/HungSloth/sloth-incubator/internal/config"
+	"github.com/HungSloth/sloth-incubator/internal/template"
+	"github.com/HungSloth/sloth-incubator/internal/templatetest"
+	"github.com/spf13/cobra"
+)
+
+// newTemplateCmd builds `incubator template`, the commands for template authors.
+func newTemplateCmd() *cobra.Command {
+	templateCmd := &cobra.Command{
+		Use:   "template",
+		Short: "Tools for template authors",
+	}
+
+	var lintJSON bool
+	lintCmd := &cobra.Command{
+		Use:   "lint [dir]",
+		Short: "Check a template for mistakes without rendering it",
+		Long:  "Parse template.yaml strictly, compile every .tmpl file and `when` expression, and flag undefined variables, missing hooks and file rules that match nothing.",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir := "."
+			if len(args) == 1 {
+				dir = args[0]
+			}
+			cmd.SilenceUsage = true
+			return runTemplateLint(os.Stdout, dir, lintJSON)
+		},
+	}
+	lintCmd.Flags().BoolVar(&lintJSON, "json", false, "Print the issues as JSON")
+
+	var testOpts templatetest.Options
+	testCmd := &cobra.Command{
+		Use:   "test [dir]",
+		Short: "Render a template's test cases and compare them with golden files",
+		Long:  "Render every tests/<case>/answers.yaml of the template and diff the output against tests/<case>/expected/. Use --update to regenerate the golden files.",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir := "."
+			if len(args) == 1 {
+				dir = args[0]
+			}
+			cmd.SilenceUsage = true
+			return runTemplateTest(os.Stdout, dir, testOpts)
+		},
+	}
+	testCmd.Flags().BoolVar(&testOpts.Update, "update", false, "Rewrite the expected/ trees from the current render")
+	testCmd.Flags().StringVar(&testOpts.Verify, "verify", "", "Shell command to run inside each rendered project, e.g. \"go build ./...\"")
+
+	templateCmd.AddCommand(lintCmd, testCmd)
+	return templateCmd
+}
+
+// lintReport is the JSON form of `incubator template lint`.
+type lintReport struct {
+	Template string               `json:"template"`
+	Issues   []template.LintIssue `json:"issues"`
+}
+
+func runTemplateLint(w io.Writer, dir string, asJSON bool) error {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("resolving template directory: %w", err)
+	}
+	cfg, _ := config.Load()
+	issues, err := template.LintTemplate(absDir, loadAllTemplates(cfg), config.ConfigDir())
+	if err != nil {
+		return err
+	}
+
+	if asJSON {
+		if issues == nil {
+			issues = []template.LintIssue{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(lintReport{Template: absDir, Issues: issues}); err != nil {
+			return err
+		}
+	} else {
+		errors, warnings := 0, 0
+		for _, issue := range issues {
+			fmt.Fprintln(w, issue)
+			if issue.Severity == template.LintError {
+				errors++
+			} else {
+				warnings++
+			}
+		}
+		if len(issues) == 0 {
+			fmt.Fprintln(w, "No problems found.")
+		} else {
+			fmt.Fprintf(w, "\n%d error(s), %d warning(s)\n", errors, warnings)
+		}
+	}
+
+	if template.HasLintErrors(issues) {
+		return fmt.Errorf("template %s has lint errors", dir)
+	}
+	return nil
+}
+
+func runTemplateTest(w io.Writer, dir string, opts templatetest.Options) error {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("resolving template directory: %w", err)
+	}
+	cfg, _ := config.Load()
+	opts.Dir = absDir
+	opts.Templates = loadAllTemplates(cfg)
+	opts.CacheDir = config.ConfigDir()
+
+	results, err := templatetest.Run(opts)
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, r := range results {
+		switch {
+		case !r.Passed():
+			failed++
+			fmt.Fprintf(w, "FAIL    %s\n", r.Name)
+			for _, d := range r.Diffs {
+				fmt.Fprintf(w, "\n%s", d)
+			}
+			if r.Err != nil {
+				fmt.Fprintf(w, "  %v\n", r.Err)
+			}
+			if r.VerifyOutput != "" {
+				fmt.Fprintf(w, "%s\n", r.VerifyOutput)
+			}
+		case r.Updated:
+			fmt.Fprintf(w, "updated %s\n", r.Name)
+		default:
+			fmt.Fprintf(w, "ok      %s\n", r.Name)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d template test(s) failed", failed, len(results))
+	}
+	return nil
+}
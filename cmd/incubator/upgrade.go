@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/HungSloth/sloth-incubator/internal/config"
+	"github.com/HungSloth/sloth-incubator/internal/template"
+	"github.com/HungSloth/sloth-incubator/internal/tui"
+	"github.com/HungSloth/sloth-incubator/internal/upgrade"
+)
+
+// upgradeOptions holds the flags for `incubator upgrade`.
+type upgradeOptions struct {
+	sets   []string
+	yes    bool
+	dryRun bool
+}
+
+// runUpgrade brings a scaffolded project up to date with the latest version of
+// the template recorded in its .incubator/state.yaml.
+func runUpgrade(projectDir string, opts upgradeOptions) error {
+	absDir, err := filepath.Abs(projectDir)
+	if err != nil {
+		return fmt.Errorf("resolving project directory: %w", err)
+	}
+	state, err := template.ReadState(absDir)
+	if err != nil {
+		return err
+	}
+
+	cfg, _ := config.Load()
+	cacheDir := config.ConfigDir()
+	if state.Repo != "" && !template.Offline() {
+		if err := template.NewLoader(cacheDir, state.Repo).FetchTemplates(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not refresh %s, using cached templates: %v\n", state.Repo, err)
+		}
+	}
+
+	manifests := loadAllTemplates(cfg)
+	manifest, err := findTemplate(manifests, state.QualifiedName())
+	if repo, ref := template.SplitRef(state.Repo); err != nil && ref != "" {
+		// Projects created from name@version upgrade to the repo as configured.
+		if unpinned, unpinnedErr := findTemplate(manifests, repo+":"+state.Template); unpinnedErr == nil {
+			manifest, err = unpinned, nil
+		}
+	}
+	if err != nil {
+		return err
+	}
+	overrides, err := readAnswers(manifest, "", opts.sets)
+	if err != nil {
+		return err
+	}
+
+	licenseHolder := ""
+	if cfg != nil {
+		licenseHolder = cfg.GitHubUser
+	}
+	plan, err := upgrade.Prepare(upgrade.Options{
+		ProjectDir:    absDir,
+		Manifest:      manifest,
+		CacheDir:      cacheDir,
+		Answers:       overrides,
+		LicenseHolder: licenseHolder,
+	})
+	if err != nil {
+		return err
+	}
+	if plan.Warning != "" {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", plan.Warning)
+	}
+
+	if len(plan.Changes) == 0 {
+		if opts.dryRun {
+			fmt.Println("No file changes.")
+			return nil
+		}
+		if err := plan.Apply(); err != nil {
+			return err
+		}
+		fmt.Printf("Already up to date with %s %s.\n", manifest.QualifiedName(), manifest.Version)
+		return nil
+	}
+
+	if opts.dryRun {
+		printUpgradePlan(os.Stdout, plan)
+		return nil
+	}
+
+	if !opts.yes {
+		confirmed, err := tui.RunUpgradeReview(plan)
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			fmt.Println("Upgrade cancelled.")
+			return nil
+		}
+	}
+
+	if err := plan.Apply(); err != nil {
+		return err
+	}
+	printUpgradePlan(os.Stdout, plan)
+
+	var unresolved []string
+	for _, c := range plan.Changes {
+		if c.Action == upgrade.ActionConflict && c.Resolution == upgrade.ResolveProposed && c.Reason == "" {
+			unresolved = append(unresolved, c.Path)
+		}
+	}
+	fmt.Printf("\nUpgraded %s from %s to %s.\n", manifest.QualifiedName(), plan.From.Version, plan.To.Version)
+	if len(unresolved) > 0 {
+		fmt.Println("Resolve the conflict markers in:")
+		for _, p := range unresolved {
+			fmt.Printf("  %s\n", p)
+		}
+	}
+	return nil
+}
+
+func printUpgradePlan(w io.Writer, plan *upgrade.Plan) {
+	for _, c := range plan.Changes {
+		line := fmt.Sprintf("  %-8s %s", c.Action, c.Path)
+		if c.Reason != "" {
+			line += " (" + c.Reason + ")"
+		}
+		fmt.Fprintln(w, line)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -21,8 +22,13 @@ type Config struct {
 	TemplateRepo      string   `yaml:"template_repo"`
 	TemplateRepos     []string `yaml:"template_repos,omitempty"`
 	AutoUpdateCheck   bool     `yaml:"auto_update_check"`
+	TemplateCacheTTL  string   `yaml:"template_cache_ttl,omitempty"`
 }
 
+// DefaultTemplateCacheTTL is how old the template cache may get before the
+// TUI refreshes it in the background.
+const DefaultTemplateCacheTTL = 24 * time.Hour
+
 // DefaultConfig returns a config with sensible defaults
 func DefaultConfig() *Config {
 	homeDir, _ := os.UserHomeDir()
@@ -34,6 +40,7 @@ func DefaultConfig() *Config {
 		Editor:            "none",
 		TemplateRepo:      "HungSloth/incubator-templates",
 		AutoUpdateCheck:   true,
+		TemplateCacheTTL:  DefaultTemplateCacheTTL.String(),
 	}
 }
 
@@ -48,6 +55,10 @@ func ConfigPath() string {
 	return filepath.Join(ConfigDir(), "config.yaml")
 }
 
+// DetectGitHubUser lets Load ask the gh CLI, and so the GitHub API, for the
+// user when it writes a new config. Offline mode turns it off.
+var DetectGitHubUser = true
+
 // Load loads the config from disk, creating defaults if it doesn't exist
 func Load() (*Config, error) {
 	cfg := DefaultConfig()
@@ -56,7 +67,9 @@ func Load() (*Config, error) {
 	if err != nil {
 		if os.IsNotExist(err) {
 			// Try to detect GitHub user
-			cfg.GitHubUser = detectGitHubUser()
+			if DetectGitHubUser {
+				cfg.GitHubUser = detectGitHubUser()
+			}
 			// Save the defaults
 			if saveErr := cfg.Save(); saveErr != nil {
 				return cfg, nil // return defaults even if save fails
@@ -115,6 +128,16 @@ func (c *Config) GetLocalTemplateDir() string {
 	return dir
 }
 
+// GetTemplateCacheTTL returns template_cache_ttl as a duration such as "12h",
+// falling back to DefaultTemplateCacheTTL when it is unset or invalid.
+func (c *Config) GetTemplateCacheTTL() time.Duration {
+	ttl, err := time.ParseDuration(strings.TrimSpace(c.TemplateCacheTTL))
+	if err != nil || ttl <= 0 {
+		return DefaultTemplateCacheTTL
+	}
+	return ttl
+}
+
 // GetTemplateRepos returns all template repos (primary + additional)
 func (c *Config) GetTemplateRepos() []string {
 	repos := []string{c.TemplateRepo}
@@ -3,6 +3,7 @@ package config
 import (
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestGetLocalTemplateDirExpandsHome(t *testing.T) {
@@ -28,3 +29,18 @@ func TestGetLocalTemplateDirUsesDefaultWhenEmpty(t *testing.T) {
 		t.Fatalf("expected %q, got %q", want, got)
 	}
 }
+
+func TestGetTemplateCacheTTL(t *testing.T) {
+	for value, want := range map[string]time.Duration{
+		"":      DefaultTemplateCacheTTL,
+		"6h":    6 * time.Hour,
+		"90m":   90 * time.Minute,
+		"daily": DefaultTemplateCacheTTL,
+		"-1h":   DefaultTemplateCacheTTL,
+	} {
+		cfg := &Config{TemplateCacheTTL: value}
+		if got := cfg.GetTemplateCacheTTL(); got != want {
+			t.Fatalf("template_cache_ttl %q: expected %v, got %v", value, want, got)
+		}
+	}
+}
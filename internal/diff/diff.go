@@ -0,0 +1,239 @@
+// Package diff computes line diffs and renders them in unified format.
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Kind identifies whether a line is shared, added or removed.
+type Kind int
+
+const (
+	Equal Kind = iota
+	Insert
+	Delete
+)
+
+// Line is a single line of a diff.
+type Line struct {
+	Kind Kind
+	Text string
+}
+
+// SplitLines splits text into lines without their trailing newlines.
+func SplitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// Lines returns the shortest edit script turning a into b, using Myers' algorithm.
+func Lines(a, b []string) []Line {
+	n, m := len(a), len(b)
+	if n == 0 && m == 0 {
+		return nil
+	}
+
+	max := n + m
+	offset := max + 1
+	v := make([]int, 2*max+3)
+	// trace[d] holds the furthest x for diagonals -d-1..d+1 before round d.
+	var trace [][]int
+
+	for d := 0; d <= max; d++ {
+		trace = append(trace, append([]int(nil), v[offset-d-1:offset+d+2]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(trace, a, b)
+			}
+		}
+	}
+	return nil
+}
+
+func backtrack(trace [][]int, a, b []string) []Line {
+	x, y := len(a), len(b)
+	var reversed []Line
+
+	for d := len(trace) - 1; d >= 0; d-- {
+		prev := trace[d]
+		at := func(k int) int { return prev[k+d+1] }
+
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			reversed = append(reversed, Line{Kind: Equal, Text: a[x-1]})
+			x--
+			y--
+		}
+		if d > 0 {
+			if x == prevX {
+				reversed = append(reversed, Line{Kind: Insert, Text: b[y-1]})
+			} else {
+				reversed = append(reversed, Line{Kind: Delete, Text: a[x-1]})
+			}
+		}
+		x, y = prevX, prevY
+	}
+
+	lines := make([]Line, len(reversed))
+	for i, line := range reversed {
+		lines[len(reversed)-1-i] = line
+	}
+	return lines
+}
+
+// HasChanges reports whether any line was added or removed.
+func HasChanges(lines []Line) bool {
+	for _, line := range lines {
+		if line.Kind != Equal {
+			return true
+		}
+	}
+	return false
+}
+
+// Unified renders the difference between from and to as a unified diff with the
+// given number of context lines. It returns "" when the texts are identical.
+// Binary content is not diffed line by line; a single line says the files differ.
+func Unified(fromName, toName, from, to string, context int) string {
+	if from == to {
+		return ""
+	}
+	if strings.IndexByte(from, 0) >= 0 || strings.IndexByte(to, 0) >= 0 {
+		return fmt.Sprintf("Binary files %s and %s differ\n", fromName, toName)
+	}
+
+	lines := Lines(SplitLines(from), SplitLines(to))
+	if !HasChanges(lines) {
+		return ""
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", fromName, toName)
+	for _, h := range Hunks(lines, context) {
+		b.WriteString(h.Header())
+		b.WriteString("\n")
+		for _, line := range lines[h.Start:h.End] {
+			b.WriteString(Prefix(line.Kind))
+			b.WriteString(line.Text)
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+// Prefix returns the unified diff marker for a line kind.
+func Prefix(kind Kind) string {
+	switch kind {
+	case Insert:
+		return "+"
+	case Delete:
+		return "-"
+	default:
+		return " "
+	}
+}
+
+// Hunk is a run of lines[Start:End] with changes and their surrounding context.
+type Hunk struct {
+	Start, End         int
+	FromLine, FromSize int
+	ToLine, ToSize     int
+}
+
+// Header returns the hunk's @@ line.
+func (h Hunk) Header() string {
+	return fmt.Sprintf("@@ -%d,%d +%d,%d @@", h.FromLine, h.FromSize, h.ToLine, h.ToSize)
+}
+
+// Hunks groups changed lines with up to context unchanged lines around them,
+// merging groups whose context would overlap.
+func Hunks(lines []Line, context int) []Hunk {
+	var hunks []Hunk
+	for i := 0; i < len(lines); {
+		if lines[i].Kind == Equal {
+			i++
+			continue
+		}
+
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for end < len(lines) {
+			if lines[end].Kind != Equal {
+				end++
+				continue
+			}
+			run := end
+			for run < len(lines) && lines[run].Kind == Equal {
+				run++
+			}
+			if run == len(lines) || run-end > 2*context {
+				end += context
+				if end > len(lines) {
+					end = len(lines)
+				}
+				break
+			}
+			end = run
+		}
+
+		hunks = append(hunks, newHunk(lines, start, end))
+		i = end
+	}
+	return hunks
+}
+
+func newHunk(lines []Line, start, end int) Hunk {
+	h := Hunk{Start: start, End: end}
+	fromBefore, toBefore := 0, 0
+	for _, line := range lines[:start] {
+		if line.Kind != Insert {
+			fromBefore++
+		}
+		if line.Kind != Delete {
+			toBefore++
+		}
+	}
+	for _, line := range lines[start:end] {
+		if line.Kind != Insert {
+			h.FromSize++
+		}
+		if line.Kind != Delete {
+			h.ToSize++
+		}
+	}
+	h.FromLine, h.ToLine = fromBefore, toBefore
+	if h.FromSize > 0 {
+		h.FromLine++
+	}
+	if h.ToSize > 0 {
+		h.ToLine++
+	}
+	return h
+}
@@ -0,0 +1,59 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestLinesProducesMinimalEditScript(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{name: "identical", a: "a\nb\n", b: "a\nb\n", want: " a| b"},
+		{name: "insert", a: "a\nc\n", b: "a\nb\nc\n", want: " a|+b| c"},
+		{name: "delete", a: "a\nb\nc\n", b: "a\nc\n", want: " a|-b| c"},
+		{name: "replace", a: "a\nb\n", b: "a\nx\n", want: " a|-b|+x"},
+		{name: "from empty", a: "", b: "a\n", want: "+a"},
+		{name: "to empty", a: "a\n", b: "", want: "-a"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var parts []string
+			for _, line := range Lines(SplitLines(tt.a), SplitLines(tt.b)) {
+				parts = append(parts, Prefix(line.Kind)+line.Text)
+			}
+			if got := strings.Join(parts, "|"); got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnifiedGroupsHunks(t *testing.T) {
+	var from, to []string
+	for i := 1; i <= 20; i++ {
+		from = append(from, fmt.Sprintf("line %d", i))
+		to = append(to, fmt.Sprintf("line %d", i))
+	}
+	to[1] = "changed near top"
+	to[18] = "changed near bottom"
+
+	got := Unified("a", "b", strings.Join(from, "\n")+"\n", strings.Join(to, "\n")+"\n", 2)
+	if strings.Count(got, "@@ -") != 2 {
+		t.Fatalf("expected two hunks, got:\n%s", got)
+	}
+	if !strings.Contains(got, "@@ -1,4 +1,4 @@") || !strings.Contains(got, "@@ -17,4 +17,4 @@") {
+		t.Fatalf("unexpected hunk headers:\n%s", got)
+	}
+	if !strings.Contains(got, "+changed near bottom") || !strings.Contains(got, "-line 2\n") {
+		t.Fatalf("expected changed lines in diff:\n%s", got)
+	}
+
+	if Unified("a", "b", "same\n", "same\n", 3) != "" {
+		t.Fatalf("expected no diff for identical input")
+	}
+}
@@ -0,0 +1,53 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// MergeFile performs a three-way merge of ours and theirs against their common
+// base using git merge-file. It returns the merged content, with conflict markers
+// labelled by labels (ours, base, theirs) where both sides changed the same lines,
+// and reports whether any conflicts remain.
+func MergeFile(ours, base, theirs []byte, labels [3]string) ([]byte, bool, error) {
+	if err := CheckGitAvailable(); err != nil {
+		return nil, false, err
+	}
+
+	dir, err := os.MkdirTemp("", "incubator-merge-*")
+	if err != nil {
+		return nil, false, fmt.Errorf("creating merge directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	paths := make([]string, 3)
+	for i, content := range [][]byte{ours, base, theirs} {
+		paths[i] = filepath.Join(dir, fmt.Sprintf("%d", i))
+		if err := os.WriteFile(paths[i], content, 0644); err != nil {
+			return nil, false, fmt.Errorf("writing merge input: %w", err)
+		}
+	}
+
+	cmd := exec.Command("git", "merge-file", "-p",
+		"-L", labels[0], "-L", labels[1], "-L", labels[2],
+		paths[0], paths[1], paths[2])
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err = cmd.Run()
+	if err == nil {
+		return stdout.Bytes(), false, nil
+	}
+
+	// A positive exit status is the number of conflicts; git uses 255 for errors.
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() > 0 && exitErr.ExitCode() < 255 {
+		return stdout.Bytes(), true, nil
+	}
+	return nil, false, fmt.Errorf("git merge-file failed: %s: %w", strings.TrimSpace(stderr.String()), err)
+}
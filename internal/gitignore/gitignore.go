@@ -0,0 +1,97 @@
+// Package gitignore composes .gitignore files from embedded fragments.
+package gitignore
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+)
+
+//go:embed fragments/*.gitignore
+var fragments embed.FS
+
+const fragmentExt = ".gitignore"
+
+// Names returns every available fragment, sorted.
+func Names() []string {
+	entries, _ := fs.ReadDir(fragments, "fragments")
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, strings.TrimSuffix(e.Name(), fragmentExt))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Has reports whether a fragment exists.
+func Has(name string) bool {
+	_, err := fs.Stat(fragments, "fragments/"+name+fragmentExt)
+	return err == nil
+}
+
+// Compose appends the named fragments to base, the template's own .gitignore
+// if it has one. Patterns already present are not repeated, and fragments
+// with nothing new to add are left out.
+func Compose(base []byte, names []string) ([]byte, error) {
+	seen := make(map[string]bool)
+	var b strings.Builder
+	if text := strings.TrimRight(string(base), "\n"); text != "" {
+		b.WriteString(text)
+		b.WriteString("\n")
+		for _, line := range strings.Split(text, "\n") {
+			if pattern, ok := patternLine(line); ok {
+				seen[pattern] = true
+			}
+		}
+	}
+
+	used := make(map[string]bool)
+	for _, name := range names {
+		if used[name] {
+			continue
+		}
+		used[name] = true
+
+		data, err := fragments.ReadFile("fragments/" + name + fragmentExt)
+		if err != nil {
+			return nil, fmt.Errorf("unknown gitignore fragment %q (available: %s)", name, strings.Join(Names(), ", "))
+		}
+
+		var kept []string
+		added := false
+		for _, line := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+			pattern, ok := patternLine(line)
+			if !ok {
+				kept = append(kept, line)
+				continue
+			}
+			if seen[pattern] {
+				continue
+			}
+			seen[pattern] = true
+			kept = append(kept, line)
+			added = true
+		}
+		if !added {
+			continue
+		}
+
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(strings.Join(kept, "\n"))
+		b.WriteString("\n")
+	}
+	return []byte(b.String()), nil
+}
+
+// patternLine returns the pattern on a line, ignoring blanks and comments.
+func patternLine(line string) (string, bool) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return "", false
+	}
+	return line, true
+}
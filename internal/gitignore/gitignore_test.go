@@ -0,0 +1,45 @@
+package gitignore
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestComposeMergesAndDedupes(t *testing.T) {
+	base := []byte("# Project\n.DS_Store\n/secrets/\n")
+	out, err := Compose(base, []string{"os", "go", "os", "editors"})
+	if err != nil {
+		t.Fatalf("Compose returned error: %v", err)
+	}
+	text := string(out)
+
+	if !strings.HasPrefix(text, "# Project\n.DS_Store\n/secrets/\n") {
+		t.Fatalf("expected the template's own patterns first, got:\n%s", text)
+	}
+	if strings.Count(text, ".DS_Store") != 1 || strings.Count(text, "# OS files") != 1 {
+		t.Fatalf("expected .DS_Store and the OS fragment once, got:\n%s", text)
+	}
+	for _, want := range []string{"Thumbs.db", "*.test", ".idea/"} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("expected %s in:\n%s", want, text)
+		}
+	}
+
+	if _, err := Compose(nil, []string{"cobol"}); err == nil {
+		t.Fatalf("expected an unknown fragment to be rejected")
+	}
+}
+
+func TestComposeSkipsFragmentsWithNothingNew(t *testing.T) {
+	rust, err := Compose(nil, []string{"rust"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := Compose(rust, []string{"rust"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != string(rust) {
+		t.Fatalf("expected composing twice to change nothing, got:\n%s", out)
+	}
+}
@@ -0,0 +1,132 @@
+// Package license provides embedded license texts and SPDX source file headers.
+package license
+
+import (
+	"embed"
+	"fmt"
+	"path"
+	"strings"
+)
+
+//go:embed texts/*.txt
+var texts embed.FS
+
+// None is the license answer for projects without a license.
+const None = "none"
+
+// License is a license incubator can write.
+type License struct {
+	// ID is the value templates use for the `license` answer.
+	ID string
+	// SPDX is the identifier written in source file headers.
+	SPDX string
+	Name string
+	// yearPlaceholder and holderPlaceholder are replaced in the text.
+	yearPlaceholder   string
+	holderPlaceholder string
+}
+
+var licenses = []License{
+	{ID: "MIT", SPDX: "MIT", Name: "MIT License", yearPlaceholder: "[year]", holderPlaceholder: "[fullname]"},
+	{ID: "Apache-2.0", SPDX: "Apache-2.0", Name: "Apache License 2.0", yearPlaceholder: "[yyyy]", holderPlaceholder: "[name of copyright owner]"},
+	// The GPL's <year> and <name of author> belong to its "How to Apply These
+	// Terms" appendix, not to a copyright line, so the text is used as is.
+	{ID: "GPL-3.0", SPDX: "GPL-3.0-only", Name: "GNU General Public License v3.0"},
+	{ID: "MPL-2.0", SPDX: "MPL-2.0", Name: "Mozilla Public License 2.0"},
+}
+
+// All returns every embedded license.
+func All() []License {
+	return append([]License(nil), licenses...)
+}
+
+// Options returns the license IDs followed by None, for `license` prompts.
+func Options() []string {
+	options := make([]string, 0, len(licenses)+1)
+	for _, l := range licenses {
+		options = append(options, l.ID)
+	}
+	return append(options, None)
+}
+
+// Lookup finds a license by ID or SPDX identifier, ignoring case.
+func Lookup(id string) (License, bool) {
+	for _, l := range licenses {
+		if strings.EqualFold(l.ID, id) || strings.EqualFold(l.SPDX, id) {
+			return l, true
+		}
+	}
+	return License{}, false
+}
+
+// Text returns the license text with the year and copyright holder filled in.
+func (l License) Text(year int, holder string) ([]byte, error) {
+	data, err := texts.ReadFile("texts/" + l.ID + ".txt")
+	if err != nil {
+		return nil, fmt.Errorf("reading %s license text: %w", l.ID, err)
+	}
+	text := string(data)
+	if l.yearPlaceholder != "" {
+		text = strings.ReplaceAll(text, l.yearPlaceholder, fmt.Sprint(year))
+	}
+	if l.holderPlaceholder != "" && holder != "" {
+		text = strings.ReplaceAll(text, l.holderPlaceholder, holder)
+	}
+	return []byte(text), nil
+}
+
+// lineComments maps file extensions to their line comment marker.
+var lineComments = map[string]string{
+	".go": "//", ".js": "//", ".jsx": "//", ".mjs": "//", ".cjs": "//", ".ts": "//", ".tsx": "//",
+	".rs": "//", ".c": "//", ".h": "//", ".cc": "//", ".cpp": "//", ".hpp": "//", ".java": "//",
+	".kt": "//", ".kts": "//", ".swift": "//", ".scala": "//", ".cs": "//", ".dart": "//",
+	".proto": "//", ".zig": "//", ".scss": "//",
+	".py": "#", ".sh": "#", ".bash": "#", ".zsh": "#", ".rb": "#", ".pl": "#", ".r": "#",
+	".ex": "#", ".exs": "#", ".tf": "#", ".ps1": "#", ".yaml": "#", ".yml": "#", ".toml": "#",
+	".sql": "--", ".lua": "--", ".hs": "--",
+}
+
+// fileComments covers files recognised by name rather than extension.
+var fileComments = map[string]string{
+	"Dockerfile":    "#",
+	"Makefile":      "#",
+	"Containerfile": "#",
+}
+
+// Header returns the SPDX comment line for a file, or false if the file type
+// has no known comment syntax.
+func (l License) Header(name string) (string, bool) {
+	base := path.Base(name)
+	if marker, ok := fileComments[base]; ok {
+		return marker + " SPDX-License-Identifier: " + l.SPDX + "\n", true
+	}
+	ext := strings.ToLower(path.Ext(base))
+	if ext == ".css" {
+		return "/* SPDX-License-Identifier: " + l.SPDX + " */\n", true
+	}
+	if marker, ok := lineComments[ext]; ok {
+		return marker + " SPDX-License-Identifier: " + l.SPDX + "\n", true
+	}
+	return "", false
+}
+
+// AddHeader prepends the SPDX header to a source file, after any shebang line.
+// Files that already declare a license identifier, or whose type is unknown,
+// are returned unchanged.
+func (l License) AddHeader(name string, content []byte) []byte {
+	header, ok := l.Header(name)
+	if !ok || strings.Contains(string(content), "SPDX-License-Identifier:") {
+		return content
+	}
+
+	text := string(content)
+	prefix := ""
+	if strings.HasPrefix(text, "#!") {
+		end := strings.IndexByte(text, '\n')
+		if end < 0 {
+			return []byte(text + "\n" + header)
+		}
+		prefix, text = text[:end+1], text[end+1:]
+	}
+	return []byte(prefix + header + text)
+}
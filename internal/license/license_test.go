@@ -0,0 +1,59 @@
+package license
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTextFillsYearAndHolder(t *testing.T) {
+	for _, l := range All() {
+		text, err := l.Text(2031, "Sloth")
+		if err != nil {
+			t.Fatalf("%s: Text returned error: %v", l.ID, err)
+		}
+		if l.yearPlaceholder != "" && (!strings.Contains(string(text), "2031") || !strings.Contains(string(text), "Sloth")) {
+			t.Fatalf("%s: expected year and holder in the text", l.ID)
+		}
+		for _, placeholder := range []string{"[year]", "[fullname]", "[yyyy]"} {
+			if strings.Contains(string(text), placeholder) {
+				t.Fatalf("%s: placeholder %s left in the text", l.ID, placeholder)
+			}
+		}
+	}
+
+	// The GPL's placeholders are part of its appendix and stay as written.
+	gpl, _ := Lookup("GPL-3.0")
+	text, err := gpl.Text(2031, "Sloth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	original, _ := texts.ReadFile("texts/GPL-3.0.txt")
+	if string(text) != string(original) {
+		t.Fatalf("expected the GPL text to be left unchanged")
+	}
+
+	if options := Options(); options[len(options)-1] != None {
+		t.Fatalf("expected %q to be the last option, got %v", None, options)
+	}
+}
+
+func TestAddHeader(t *testing.T) {
+	mit, _ := Lookup("mit")
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"main.go", "package main\n", "// SPDX-License-Identifier: MIT\npackage main\n"},
+		{"run.sh", "#!/bin/sh\necho hi\n", "#!/bin/sh\n# SPDX-License-Identifier: MIT\necho hi\n"},
+		{"styles/site.css", "body {}\n", "/* SPDX-License-Identifier: MIT */\nbody {}\n"},
+		{"Dockerfile", "FROM scratch\n", "# SPDX-License-Identifier: MIT\nFROM scratch\n"},
+		{"README.md", "# hi\n", "# hi\n"},
+		{"lib.rs", "// SPDX-License-Identifier: Apache-2.0\n", "// SPDX-License-Identifier: Apache-2.0\n"},
+	}
+	for _, tt := range tests {
+		if got := string(mit.AddHeader(tt.name, []byte(tt.content))); got != tt.want {
+			t.Fatalf("%s: expected %q, got %q", tt.name, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package scaffold
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/HungSloth/sloth-incubator/internal/template"
+)
+
+// InitAction is what `incubator init` does with a template file that already
+// exists in the project.
+type InitAction string
+
+const (
+	// InitSkip keeps the project's file. This is the default.
+	InitSkip InitAction = "skip"
+	// InitOverwrite replaces the project's file with the rendered template file.
+	InitOverwrite InitAction = "overwrite"
+	// InitWriteNew writes the rendered file next to the existing one with the
+	// IncubatorNewSuffix so it can be merged by hand.
+	InitWriteNew InitAction = "new"
+)
+
+// IncubatorNewSuffix is appended to rendered files written with InitWriteNew.
+const IncubatorNewSuffix = ".incubator-new"
+
+// InitFile is a rendered template file compared with the project's copy.
+type InitFile struct {
+	Path     string
+	Rendered []byte
+	// Mode is the rendered file's permission.
+	Mode fs.FileMode
+	// Existing is nil when the project does not have the file yet.
+	Existing []byte
+}
+
+// Exists reports whether the project already has the file.
+func (f InitFile) Exists() bool {
+	return f.Existing != nil
+}
+
+// Identical reports whether the project's file already matches the template.
+func (f InitFile) Identical() bool {
+	return f.Exists() && bytes.Equal(f.Existing, f.Rendered)
+}
+
+// PlanInit renders the template in memory and pairs every rendered file with
+// the project's existing copy, if any.
+func PlanInit(opts Options) ([]InitFile, error) {
+	templateFS, err := resolveTemplateFS(opts)
+	if err != nil {
+		return nil, err
+	}
+	result, err := newRenderer(opts).Render(templateFS)
+	if err != nil {
+		return nil, fmt.Errorf("rendering templates: %w", err)
+	}
+
+	return compareWithProject(opts.InitDir, result)
+}
+
+// compareWithProject pairs rendered files with their copies in projectDir.
+func compareWithProject(projectDir string, result template.RenderResult) ([]InitFile, error) {
+	files := make([]InitFile, 0, len(result.Files))
+	for _, rendered := range result.Files {
+		file := InitFile{Path: rendered.Path, Rendered: rendered.Content, Mode: rendered.Mode}
+		existing, err := os.ReadFile(filepath.Join(projectDir, filepath.FromSlash(rendered.Path)))
+		if err == nil {
+			file.Existing = existing
+		} else if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("comparing rendered files: %w", err)
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// applyInitActions overwrites existing files, or writes them alongside as
+// .incubator-new, according to the chosen actions. New files are written by the
+// renderer itself.
+func applyInitActions(projectDir string, files []InitFile, actions map[string]InitAction) error {
+	for _, f := range files {
+		if !f.Exists() || f.Identical() {
+			continue
+		}
+		target := filepath.Join(projectDir, filepath.FromSlash(f.Path))
+		switch actions[f.Path] {
+		case InitOverwrite:
+		case InitWriteNew:
+			target += IncubatorNewSuffix
+		default:
+			continue
+		}
+
+		// Overwritten files keep their permissions.
+		mode := f.Mode
+		if info, err := os.Stat(target); err == nil {
+			mode = info.Mode().Perm()
+		}
+		if err := os.WriteFile(target, f.Rendered, mode); err != nil {
+			return fmt.Errorf("writing %s: %w", target, err)
+		}
+	}
+	return nil
+}
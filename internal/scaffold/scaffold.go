@@ -0,0 +1,370 @@
+package scaffold
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/HungSloth/sloth-incubator/internal/config"
+	"github.com/HungSloth/sloth-incubator/internal/git"
+	"github.com/HungSloth/sloth-incubator/internal/template"
+)
+
+// Step names shared by the TUI progress screen and the headless runner.
+const (
+	StepCreateProjectDir = "Creating project directory"
+	StepRenderTemplates  = "Rendering templates"
+	StepRunPostCreate    = "Running post-create hook"
+	StepInitGitRepo      = "Initializing git repo"
+	StepCommitScaffold   = "Committing scaffold files"
+	StepCreateGitHubRepo = "Creating GitHub repo"
+	StepPushToOrigin     = "Pushing to origin"
+)
+
+// Options describes a single scaffolding run.
+type Options struct {
+	Manifest *template.TemplateManifest
+	Answers  map[string]interface{}
+	Config   *config.Config
+	InitMode bool
+	InitDir  string
+	// NoHooks skips the post-create hook even if the template declares one.
+	NoHooks bool
+	// HookOutput receives the post-create hook's output. Defaults to stdout.
+	HookOutput io.Writer
+	// InitActions decides, per project-relative path, what init mode does with
+	// template files that already exist. Files without an entry are skipped.
+	InitActions map[string]InitAction
+}
+
+// StepResult carries values produced by a step that later steps or callers need.
+type StepResult struct {
+	ProjectDir string
+	RepoURL    string
+}
+
+// EventStatus describes the state a step has moved into.
+type EventStatus string
+
+const (
+	EventRunning EventStatus = "running"
+	EventDone    EventStatus = "done"
+	EventFailed  EventStatus = "failed"
+)
+
+// Event is reported by Run as each step starts and finishes.
+type Event struct {
+	Step   string      `json:"step"`
+	Status EventStatus `json:"status"`
+	Error  string      `json:"error,omitempty"`
+}
+
+// Steps returns the ordered list of steps for a run.
+func Steps(opts Options) []string {
+	answers := opts.Answers
+	initMode := opts.InitMode
+
+	steps := []string{StepRenderTemplates}
+	if ShouldRunHooks(opts) {
+		steps = append(steps, StepRunPostCreate)
+	}
+	if initMode {
+		steps = append(steps, StepCommitScaffold)
+	} else {
+		steps = append([]string{StepCreateProjectDir}, steps...)
+		steps = append(steps, StepInitGitRepo)
+	}
+	if !initMode && ShouldCreateGitHubRepo(answers) && !template.Offline() {
+		steps = append(steps, StepCreateGitHubRepo, StepPushToOrigin)
+	}
+	return steps
+}
+
+// OfflineGitHubNote tells the user how to finish a run that skipped GitHub.
+const OfflineGitHubNote = "GitHub repo not created in offline mode; run `gh repo create --source . --push` in the project once online."
+
+// GitHubSkippedOffline reports whether the run asks for a GitHub repository
+// that offline mode leaves out of Steps.
+func GitHubSkippedOffline(opts Options) bool {
+	return !opts.InitMode && ShouldCreateGitHubRepo(opts.Answers) && template.Offline()
+}
+
+// ProjectDir returns the directory the project is scaffolded into.
+func ProjectDir(opts Options) string {
+	if opts.InitMode {
+		return opts.InitDir
+	}
+	projectName := fmt.Sprintf("%v", opts.Answers["project_name"])
+	baseDir := filepath.Join(os.Getenv("HOME"), "projects")
+	if opts.Config != nil {
+		baseDir = opts.Config.GetProjectDir()
+	}
+	return filepath.Join(baseDir, projectName)
+}
+
+// RunStep executes a single named step.
+func RunStep(name string, opts Options) (StepResult, error) {
+	projectDir := ProjectDir(opts)
+	answers := opts.Answers
+
+	switch name {
+	case StepCreateProjectDir:
+		if err := os.MkdirAll(projectDir, 0755); err != nil {
+			return StepResult{}, fmt.Errorf("creating directory: %w", err)
+		}
+		return StepResult{ProjectDir: projectDir}, nil
+
+	case StepRenderTemplates:
+		renderer := newRenderer(opts)
+		renderer.SkipExisting = opts.InitMode
+		templateFS, err := resolveTemplateFS(opts)
+		if err != nil {
+			return StepResult{}, err
+		}
+		result, err := renderer.Render(templateFS)
+		if err != nil {
+			return StepResult{}, fmt.Errorf("rendering templates: %w", err)
+		}
+
+		// Existing files are compared before writing adds the new ones.
+		var initFiles []InitFile
+		if opts.InitMode && len(opts.InitActions) > 0 {
+			if initFiles, err = compareWithProject(projectDir, result); err != nil {
+				return StepResult{}, err
+			}
+		}
+		if err := renderer.Write(projectDir, result); err != nil {
+			return StepResult{}, fmt.Errorf("writing templates: %w", err)
+		}
+		if err := applyInitActions(projectDir, initFiles, opts.InitActions); err != nil {
+			return StepResult{}, err
+		}
+		// Remember where the project came from so `incubator upgrade` can find it.
+		state := template.NewProjectState(opts.Manifest, answers, config.ConfigDir())
+		if err := template.WriteState(projectDir, state); err != nil {
+			return StepResult{}, err
+		}
+		return StepResult{ProjectDir: projectDir}, nil
+
+	case StepRunPostCreate:
+		if template.HookNeedsConsent(opts.Manifest) {
+			script, err := HookScript(opts)
+			if err != nil {
+				return StepResult{}, err
+			}
+			if !template.NewHookTrustStore(config.ConfigDir()).IsTrusted(opts.Manifest, script) {
+				return StepResult{}, fmt.Errorf("post-create hook for %s has not been trusted", opts.Manifest.Name)
+			}
+		}
+		// The hook is rendered again instead of read from the project: in init
+		// mode an existing file at the hook's path is kept, and that file is not
+		// the script the user trusted.
+		templateFS, err := resolveTemplateFS(opts)
+		if err != nil {
+			return StepResult{}, err
+		}
+		result, err := newRenderer(opts).Render(templateFS)
+		if err != nil {
+			return StepResult{}, fmt.Errorf("rendering templates: %w", err)
+		}
+		hook, ok, err := template.PostCreateHookFile(opts.Manifest, result)
+		if err != nil || !ok {
+			return StepResult{}, err
+		}
+		if err := template.RunPostCreateHook(opts.Manifest, hook.Content, projectDir, answers, opts.HookOutput); err != nil {
+			return StepResult{}, err
+		}
+		return StepResult{}, nil
+
+	case StepInitGitRepo:
+		if err := git.InitRepo(projectDir); err != nil {
+			return StepResult{}, err
+		}
+		if err := git.InitialCommit(projectDir); err != nil {
+			return StepResult{}, err
+		}
+		return StepResult{}, nil
+
+	case StepCommitScaffold:
+		if git.HasRepo(projectDir) {
+			if err := git.CommitAll(projectDir, "Add incubator scaffolding"); err != nil {
+				if strings.Contains(err.Error(), "nothing to commit") {
+					return StepResult{}, nil
+				}
+				return StepResult{}, err
+			}
+			return StepResult{}, nil
+		}
+		if err := git.InitRepo(projectDir); err != nil {
+			return StepResult{}, err
+		}
+		if err := git.InitialCommit(projectDir); err != nil {
+			return StepResult{}, err
+		}
+		return StepResult{}, nil
+
+	case StepCreateGitHubRepo:
+		if !ShouldCreateGitHubRepo(answers) {
+			return StepResult{}, nil
+		}
+
+		projectName := fmt.Sprintf("%v", answers["project_name"])
+		visibility := "private"
+		if v, ok := answers["visibility"]; ok {
+			visibility = fmt.Sprintf("%v", v)
+		}
+		isPrivate := visibility == "private"
+
+		repoURL, err := git.CreateRepo(projectName, isPrivate, projectDir)
+		if err != nil {
+			return StepResult{}, err
+		}
+		return StepResult{RepoURL: repoURL}, nil
+
+	case StepPushToOrigin:
+		if !ShouldCreateGitHubRepo(answers) {
+			return StepResult{}, nil
+		}
+
+		if err := git.Push(projectDir); err != nil {
+			return StepResult{}, err
+		}
+		return StepResult{}, nil
+	}
+
+	return StepResult{}, nil
+}
+
+// Run executes every step in order without a TUI, reporting progress through report.
+// GitHub steps are allowed to fail without aborting the run, matching the TUI, but
+// any failure is still returned so callers can exit nonzero.
+func Run(opts Options, report func(Event)) (StepResult, error) {
+	if report == nil {
+		report = func(Event) {}
+	}
+
+	var result StepResult
+	var failures []string
+	for _, step := range Steps(opts) {
+		report(Event{Step: step, Status: EventRunning})
+		stepResult, err := RunStep(step, opts)
+		if err != nil {
+			report(Event{Step: step, Status: EventFailed, Error: err.Error()})
+			if !IsGitHubStep(step) {
+				return result, fmt.Errorf("%s: %w", strings.ToLower(step), err)
+			}
+			failures = append(failures, fmt.Sprintf("%s: %v", strings.ToLower(step), err))
+			continue
+		}
+		if stepResult.ProjectDir != "" {
+			result.ProjectDir = stepResult.ProjectDir
+		}
+		if stepResult.RepoURL != "" {
+			result.RepoURL = stepResult.RepoURL
+		}
+		report(Event{Step: step, Status: EventDone})
+	}
+
+	if len(failures) > 0 {
+		return result, fmt.Errorf("%s", strings.Join(failures, "; "))
+	}
+	return result, nil
+}
+
+// ShouldRunHooks reports whether the run includes the post-create hook step.
+func ShouldRunHooks(opts Options) bool {
+	return !opts.NoHooks && template.HasPostCreateHook(opts.Manifest)
+}
+
+// HookScript returns the source of the template's post-create hook so it can be
+// shown to the user before they agree to run it.
+func HookScript(opts Options) (string, error) {
+	templateFS, err := resolveTemplateFS(opts)
+	if err != nil {
+		return "", err
+	}
+	return template.ReadHookScript(opts.Manifest, templateFS)
+}
+
+// HookTrusted reports whether the hook can run without asking the user.
+func HookTrusted(opts Options) (bool, error) {
+	if !template.HookNeedsConsent(opts.Manifest) {
+		return true, nil
+	}
+	script, err := HookScript(opts)
+	if err != nil {
+		return false, err
+	}
+	return template.NewHookTrustStore(config.ConfigDir()).IsTrusted(opts.Manifest, script), nil
+}
+
+// TrustHook records the user's consent to run the template's current hook script.
+func TrustHook(opts Options) error {
+	script, err := HookScript(opts)
+	if err != nil {
+		return err
+	}
+	return template.NewHookTrustStore(config.ConfigDir()).Trust(opts.Manifest, script)
+}
+
+// ShouldCreateGitHubRepo reports whether the answers ask for a GitHub repository.
+func ShouldCreateGitHubRepo(answers map[string]interface{}) bool {
+	if answers == nil {
+		return true
+	}
+	value, ok := answers["create_github_repo"]
+	if !ok {
+		return true
+	}
+	enabled, ok := value.(bool)
+	if !ok {
+		return true
+	}
+	return enabled
+}
+
+// IsGitHubStep reports whether a step talks to GitHub and may fail without aborting.
+func IsGitHubStep(stepName string) bool {
+	return stepName == StepCreateGitHubRepo || stepName == StepPushToOrigin
+}
+
+// ListFiles renders the template in memory and returns the files it would create.
+func ListFiles(opts Options) ([]string, error) {
+	templateFS, err := resolveTemplateFS(opts)
+	if err != nil {
+		return nil, err
+	}
+	files, err := newRenderer(opts).ListFiles(templateFS)
+	if err != nil {
+		return nil, fmt.Errorf("rendering templates: %w", err)
+	}
+	return files, nil
+}
+
+// newRenderer creates a renderer for the options, crediting the configured
+// GitHub user in generated licenses.
+func newRenderer(opts Options) *template.Renderer {
+	renderer := template.NewRenderer(opts.Manifest, opts.Answers)
+	if opts.Config != nil {
+		renderer.LicenseHolder = opts.Config.GitHubUser
+	}
+	return renderer
+}
+
+func resolveTemplateFS(opts Options) (fs.FS, error) {
+	templateFS, err := template.ResolveTemplateFS(opts.Manifest, config.ConfigDir(), templateRepo(opts.Config))
+	if err != nil {
+		return nil, fmt.Errorf("loading template: %w", err)
+	}
+	return templateFS, nil
+}
+
+func templateRepo(cfg *config.Config) string {
+	if cfg != nil && cfg.TemplateRepo != "" {
+		return cfg.TemplateRepo
+	}
+	return config.DefaultConfig().TemplateRepo
+}
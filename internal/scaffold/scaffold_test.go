@@ -0,0 +1,162 @@
+package scaffold
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/HungSloth/sloth-incubator/internal/template"
+)
+
+func TestStepsForInitModeSkipGitHub(t *testing.T) {
+	steps := Steps(Options{
+		Manifest: template.GetBuiltinManifest(),
+		Answers:  map[string]interface{}{"create_github_repo": true},
+		InitMode: true,
+	})
+	want := []string{StepRenderTemplates, StepCommitScaffold}
+	if len(steps) != len(want) {
+		t.Fatalf("expected %v, got %v", want, steps)
+	}
+	for i := range want {
+		if steps[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, steps)
+		}
+	}
+}
+
+func TestStepsInOfflineModeSkipGitHub(t *testing.T) {
+	t.Setenv("INCUBATOR_OFFLINE", "1")
+	opts := Options{
+		Manifest: template.GetBuiltinManifest(),
+		Answers:  map[string]interface{}{"create_github_repo": true},
+	}
+	for _, step := range Steps(opts) {
+		if step == StepCreateGitHubRepo || step == StepPushToOrigin {
+			t.Fatalf("expected no GitHub steps offline, got %v", Steps(opts))
+		}
+	}
+	if !GitHubSkippedOffline(opts) {
+		t.Fatalf("expected the skipped GitHub repo to be reported")
+	}
+}
+
+func TestRunStopsAtFirstNonGitHubFailure(t *testing.T) {
+	// A regular file where the projects directory should be makes MkdirAll fail.
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	if err := os.WriteFile(filepath.Join(home, "projects"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := Options{
+		Manifest: template.GetBuiltinManifest(),
+		Answers:  map[string]interface{}{"project_name": "blocked", "create_github_repo": false},
+	}
+
+	var events []Event
+	_, err := Run(opts, func(e Event) { events = append(events, e) })
+	if err == nil {
+		t.Fatalf("expected Run to fail")
+	}
+	last := events[len(events)-1]
+	if last.Step != StepCreateProjectDir || last.Status != EventFailed {
+		t.Fatalf("expected the run to stop at %q, got %+v", StepCreateProjectDir, last)
+	}
+}
+
+func TestRenderTemplatesInInitModeAppliesChosenActions(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	projectDir := t.TempDir()
+	writeFile := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(projectDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile("README.md", "my readme\n")
+	writeFile(".gitignore", "my ignores\n")
+
+	opts := Options{
+		Manifest: template.GetBuiltinManifest(),
+		Answers:  map[string]interface{}{"project_name": "demo", "description": "Demo", "license": "MIT"},
+		InitMode: true,
+		InitDir:  projectDir,
+	}
+	files, err := PlanInit(opts)
+	if err != nil {
+		t.Fatalf("PlanInit returned error: %v", err)
+	}
+	existing := map[string]bool{}
+	for _, f := range files {
+		existing[f.Path] = f.Exists()
+	}
+	if !existing["README.md"] || !existing[".gitignore"] || existing["CLAUDE.md"] {
+		t.Fatalf("expected README.md and .gitignore to be reported as existing, got %v", existing)
+	}
+
+	opts.InitActions = map[string]InitAction{"README.md": InitWriteNew, ".gitignore": InitOverwrite}
+	if _, err := RunStep(StepRenderTemplates, opts); err != nil {
+		t.Fatalf("RunStep returned error: %v", err)
+	}
+
+	readme, _ := os.ReadFile(filepath.Join(projectDir, "README.md"))
+	if string(readme) != "my readme\n" {
+		t.Fatalf("expected README.md to be kept, got %q", readme)
+	}
+	if _, err := os.Stat(filepath.Join(projectDir, "README.md"+IncubatorNewSuffix)); err != nil {
+		t.Fatalf("expected README.md%s to be written: %v", IncubatorNewSuffix, err)
+	}
+	gitignore, _ := os.ReadFile(filepath.Join(projectDir, ".gitignore"))
+	if string(gitignore) == "my ignores\n" {
+		t.Fatalf("expected .gitignore to be overwritten")
+	}
+	if _, err := os.Stat(filepath.Join(projectDir, "CLAUDE.md")); err != nil {
+		t.Fatalf("expected new files to be created: %v", err)
+	}
+}
+
+func TestPostCreateHookIgnoresProjectFileInInitMode(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	templateDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(templateDir, "files"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	script := "#!/bin/sh\necho template hook\n"
+	if err := os.WriteFile(filepath.Join(templateDir, "files", "setup.sh"), []byte(script), 0644); err != nil {
+		t.Fatal(err)
+	}
+	projectDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(projectDir, "setup.sh"), []byte("#!/bin/sh\necho project file\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	manifest := &template.TemplateManifest{Name: "hooked", Version: "1.0.0", SourcePath: templateDir, Hooks: template.HooksConfig{PostCreate: "setup.sh"}}
+	var out bytes.Buffer
+	opts := Options{
+		Manifest:   manifest,
+		Answers:    map[string]interface{}{"project_name": "demo"},
+		InitMode:   true,
+		InitDir:    projectDir,
+		HookOutput: &out,
+	}
+	if err := TrustHook(opts); err != nil {
+		t.Fatal(err)
+	}
+	for _, step := range []string{StepRenderTemplates, StepRunPostCreate} {
+		if _, err := RunStep(step, opts); err != nil {
+			t.Fatalf("%s: %v", step, err)
+		}
+	}
+
+	if got := out.String(); got != "template hook\n" {
+		t.Fatalf("expected the trusted template hook to run, got %q", got)
+	}
+	info, err := os.Stat(filepath.Join(projectDir, "setup.sh"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0644 {
+		t.Fatalf("expected the project's setup.sh to keep mode 0644, got %v", info.Mode().Perm())
+	}
+}
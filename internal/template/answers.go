@@ -0,0 +1,281 @@
+package template
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FieldError describes an invalid answer for a single prompt.
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// AnswerErrors collects every invalid answer so callers can report them together.
+type AnswerErrors []*FieldError
+
+func (e AnswerErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, fieldErr := range e {
+		messages = append(messages, fieldErr.Error())
+	}
+	return "invalid answers:\n  " + strings.Join(messages, "\n  ")
+}
+
+// ResolveAnswers validates provided answers against the manifest prompts, coerces
+// them to the prompt's type and fills in defaults for anything left unanswered.
+// It is used by non-interactive flows that have no form to enforce these rules.
+func ResolveAnswers(manifest *TemplateManifest, provided map[string]interface{}) (map[string]interface{}, error) {
+	answers := make(map[string]interface{}, len(manifest.Prompts))
+	var errs AnswerErrors
+
+	known := make(map[string]bool, len(manifest.Prompts))
+	for _, p := range manifest.Prompts {
+		known[p.Name] = true
+	}
+	var unknown []string
+	for key := range provided {
+		if !known[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	for _, key := range unknown {
+		errs = append(errs, &FieldError{Field: key, Message: "no prompt with this name in template " + manifest.Name})
+	}
+
+	for _, p := range manifest.Prompts {
+		// Hidden prompts are left out entirely, even if an answer was provided.
+		if !PromptVisible(p, answers) {
+			continue
+		}
+
+		raw, ok := provided[p.Name]
+		if !ok || raw == nil {
+			raw = p.Default
+		}
+
+		value, err := CoerceAnswer(p, raw)
+		if err != nil {
+			errs = append(errs, &FieldError{Field: p.Name, Message: err.Error()})
+			continue
+		}
+		if err := ValidateAnswer(p, value); err != nil {
+			errs = append(errs, &FieldError{Field: p.Name, Message: err.Error()})
+			continue
+		}
+		answers[p.Name] = value
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return answers, nil
+}
+
+// PromptVisible reports whether a prompt should be asked given the answers to the
+// prompts before it.
+func PromptVisible(p Prompt, answers map[string]interface{}) bool {
+	if strings.TrimSpace(p.When) == "" {
+		return true
+	}
+	return EvaluateCondition(p.When, answers)
+}
+
+// ParseAnswerValue converts a raw string (e.g. from --set key=value) into the
+// value type expected by the prompt with that name, if there is one.
+func ParseAnswerValue(manifest *TemplateManifest, name, raw string) (interface{}, error) {
+	for _, p := range manifest.Prompts {
+		if p.Name == name {
+			return CoerceAnswer(p, raw)
+		}
+	}
+	return raw, nil
+}
+
+// CoerceAnswer converts a raw answer (from YAML, a flag or a default) into the value
+// type the prompt produces: bool, []string, a number or a string.
+func CoerceAnswer(p Prompt, raw interface{}) (interface{}, error) {
+	switch p.Type {
+	case PromptConfirm:
+		return coerceBool(raw)
+
+	case PromptMultiSelect:
+		values, err := coerceStringList(raw)
+		if err != nil {
+			return nil, err
+		}
+		for _, value := range values {
+			if !hasOptionValue(p.Options, value) {
+				return nil, fmt.Errorf("%q is not one of %s", value, optionValues(p.Options))
+			}
+		}
+		return values, nil
+
+	case PromptNumber:
+		return coerceNumber(raw)
+
+	case PromptSelect:
+		if raw == nil {
+			if len(p.Options) > 0 {
+				return p.Options[0].Value, nil
+			}
+			return "", nil
+		}
+		value := fmt.Sprintf("%v", raw)
+		if hasOptionValue(p.Options, value) {
+			return value, nil
+		}
+		return nil, fmt.Errorf("%q is not one of %s", value, optionValues(p.Options))
+
+	default:
+		if raw == nil {
+			return "", nil
+		}
+		return fmt.Sprintf("%v", raw), nil
+	}
+}
+
+// coerceStringList accepts a YAML list or a comma-separated string.
+func coerceStringList(raw interface{}) ([]string, error) {
+	values := []string{}
+	switch v := raw.(type) {
+	case nil:
+	case []string:
+		values = append(values, v...)
+	case []interface{}:
+		for _, item := range v {
+			values = append(values, fmt.Sprintf("%v", item))
+		}
+	case string:
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				values = append(values, item)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("%v is not a list", raw)
+	}
+	return values, nil
+}
+
+// coerceNumber returns an int for whole numbers and a float64 otherwise, so
+// templates print 3 rather than 3.0. Empty input yields nil.
+func coerceNumber(raw interface{}) (interface{}, error) {
+	switch v := raw.(type) {
+	case nil:
+		return nil, nil
+	case int:
+		return v, nil
+	case int64:
+		return int(v), nil
+	case float64:
+		if v == float64(int(v)) {
+			return int(v), nil
+		}
+		return v, nil
+	case string:
+		v = strings.TrimSpace(v)
+		if v == "" {
+			return nil, nil
+		}
+		if i, err := strconv.Atoi(v); err == nil {
+			return i, nil
+		}
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", v)
+		}
+		return f, nil
+	default:
+		return nil, fmt.Errorf("%v is not a number", raw)
+	}
+}
+
+func coerceBool(raw interface{}) (bool, error) {
+	switch v := raw.(type) {
+	case nil:
+		return false, nil
+	case bool:
+		return v, nil
+	case string:
+		switch strings.ToLower(strings.TrimSpace(v)) {
+		case "y", "yes", "on":
+			return true, nil
+		case "n", "no", "off", "":
+			return false, nil
+		}
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return false, fmt.Errorf("%q is not a yes/no value", v)
+		}
+		return b, nil
+	default:
+		return false, fmt.Errorf("%v is not a yes/no value", raw)
+	}
+}
+
+func isEmptyAnswer(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return strings.TrimSpace(v) == ""
+	case []string:
+		return len(v) == 0
+	}
+	return false
+}
+
+func hasOptionValue(options []PromptOption, value string) bool {
+	for _, opt := range options {
+		if opt.Value == value {
+			return true
+		}
+	}
+	return false
+}
+
+// PersistableAnswers returns a copy of the answers without secret prompts such as
+// passwords, for anything that is written to disk or echoed back to the user.
+func PersistableAnswers(manifest *TemplateManifest, answers map[string]interface{}) map[string]interface{} {
+	secret := make(map[string]bool)
+	for _, p := range manifest.Prompts {
+		if p.IsSecret() {
+			secret[p.Name] = true
+		}
+	}
+
+	kept := make(map[string]interface{}, len(answers))
+	for key, value := range answers {
+		if !secret[key] {
+			kept[key] = value
+		}
+	}
+	return kept
+}
+
+// FormatAnswer renders an answer as a plain string, joining lists with commas.
+func FormatAnswer(value interface{}) string {
+	if list, ok := value.([]string); ok {
+		return strings.Join(list, ",")
+	}
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+func optionValues(options []PromptOption) string {
+	values := make([]string, 0, len(options))
+	for _, opt := range options {
+		values = append(values, opt.Value)
+	}
+	return "[" + strings.Join(values, ", ") + "]"
+}
@@ -0,0 +1,118 @@
+package template
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestResolveAnswersAppliesDefaultsAndCoercesTypes(t *testing.T) {
+	manifest := GetBuiltinManifest()
+
+	answers, err := ResolveAnswers(manifest, map[string]interface{}{
+		"project_name":       "demo",
+		"create_github_repo": "no",
+		"license":            "Apache-2.0",
+	})
+	if err != nil {
+		t.Fatalf("ResolveAnswers returned error: %v", err)
+	}
+
+	if answers["description"] != "A new project" {
+		t.Fatalf("expected default description, got %v", answers["description"])
+	}
+	if answers["create_github_repo"] != false {
+		t.Fatalf("expected create_github_repo to be coerced to false, got %v", answers["create_github_repo"])
+	}
+	if answers["visibility"] != "private" {
+		t.Fatalf("expected default visibility private, got %v", answers["visibility"])
+	}
+	if answers["license"] != "Apache-2.0" {
+		t.Fatalf("expected license Apache-2.0, got %v", answers["license"])
+	}
+}
+
+func TestResolveAnswersReportsEveryInvalidField(t *testing.T) {
+	manifest := GetBuiltinManifest()
+
+	_, err := ResolveAnswers(manifest, map[string]interface{}{
+		"license":        "WTFPL",
+		"unknown":        "x",
+		"enable_preview": "maybe",
+	})
+	var answerErrs AnswerErrors
+	if !errors.As(err, &answerErrs) {
+		t.Fatalf("expected AnswerErrors, got %v", err)
+	}
+
+	fields := map[string]bool{}
+	for _, fieldErr := range answerErrs {
+		fields[fieldErr.Field] = true
+	}
+	for _, want := range []string{"project_name", "license", "unknown", "enable_preview"} {
+		if !fields[want] {
+			t.Fatalf("expected an error for %s, got %v", want, err)
+		}
+	}
+}
+
+func TestResolveAnswersSkipsHiddenPrompts(t *testing.T) {
+	manifest := &TemplateManifest{
+		Name: "test",
+		Prompts: []Prompt{
+			{Name: "use_db", Type: PromptConfirm},
+			{Name: "db_name", Type: PromptText, Required: true, When: "{{if .use_db}}true{{end}}"},
+		},
+	}
+
+	answers, err := ResolveAnswers(manifest, map[string]interface{}{"use_db": false, "db_name": "ignored"})
+	if err != nil {
+		t.Fatalf("ResolveAnswers returned error: %v", err)
+	}
+	if _, ok := answers["db_name"]; ok {
+		t.Fatalf("expected hidden db_name to be dropped, got %v", answers)
+	}
+
+	if _, err := ResolveAnswers(manifest, map[string]interface{}{"use_db": true}); err == nil {
+		t.Fatalf("expected visible required db_name to be enforced")
+	}
+}
+
+func TestResolveAnswersCoercesMultiSelectAndNumber(t *testing.T) {
+	min, max := 1.0, 10.0
+	manifest := &TemplateManifest{
+		Name: "test",
+		Prompts: []Prompt{
+			{Name: "languages", Type: PromptMultiSelect, Options: []PromptOption{{Label: "Go", Value: "go"}, {Label: "Rust", Value: "rust"}}},
+			{Name: "replicas", Type: PromptNumber, Min: &min, Max: &max, Default: 3},
+			{Name: "token", Type: PromptPassword},
+		},
+	}
+
+	answers, err := ResolveAnswers(manifest, map[string]interface{}{
+		"languages": "go, rust",
+		"token":     "s3cret",
+	})
+	if err != nil {
+		t.Fatalf("ResolveAnswers returned error: %v", err)
+	}
+	languages, ok := answers["languages"].([]string)
+	if !ok || len(languages) != 2 || languages[0] != "go" || languages[1] != "rust" {
+		t.Fatalf("expected languages [go rust], got %#v", answers["languages"])
+	}
+	if answers["replicas"] != 3 {
+		t.Fatalf("expected default replicas 3, got %#v", answers["replicas"])
+	}
+	if _, ok := PersistableAnswers(manifest, answers)["token"]; ok {
+		t.Fatalf("expected password answer to be left out of persistable answers")
+	}
+
+	for _, provided := range []map[string]interface{}{
+		{"languages": []interface{}{"go", "zig"}},
+		{"replicas": "eleven"},
+		{"replicas": 11},
+	} {
+		if _, err := ResolveAnswers(manifest, provided); err == nil {
+			t.Fatalf("expected error for %v", provided)
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 )
 
@@ -18,6 +19,9 @@ type CacheInfo struct {
 // Cache manages the template cache
 type Cache struct {
 	cacheDir string
+
+	// TTL is how long fetched templates stay fresh; zero means 24 hours.
+	TTL time.Duration
 }
 
 // NewCache creates a new cache manager
@@ -73,7 +77,11 @@ func (c *Cache) IsStale() bool {
 		return true
 	}
 
-	return time.Since(info.LastFetch) > cacheTTL
+	ttl := c.TTL
+	if ttl <= 0 {
+		ttl = cacheTTL
+	}
+	return time.Since(info.LastFetch) > ttl
 }
 
 // MarkFetched updates the last fetch time to now
@@ -85,7 +93,123 @@ func (c *Cache) MarkFetched() error {
 
 // NeedsInitialFetch returns true if there's no cached templates at all
 func (c *Cache) NeedsInitialFetch() bool {
-	templatesDir := filepath.Join(c.cacheDir, "templates")
-	_, err := os.Stat(templatesDir)
-	return os.IsNotExist(err)
+	entries, _ := os.ReadDir(ReposDir(c.cacheDir))
+	for _, entry := range entries {
+		// Dot-directories are downloads in progress, not caches.
+		if !strings.HasPrefix(entry.Name(), ".") {
+			return false
+		}
+	}
+	return true
+}
+
+// VerifyRepoCache checks the cached copy of repo and describes each problem
+// found: a damaged or locally modified git checkout, a checkout at another
+// commit than templates.lock records, or templates that no longer load. A
+// repo that has not been fetched has no problems.
+func VerifyRepoCache(cacheDir, repo string) []string {
+	loader := NewLoader(cacheDir, repo)
+	dir := loader.TemplatesDir()
+	if _, err := os.Stat(dir); err != nil {
+		return nil
+	}
+
+	var problems []string
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+		head, err := gitOutput(dir, "rev-parse", "HEAD")
+		if err != nil {
+			return append(problems, fmt.Sprintf("git checkout is damaged: %v", err))
+		}
+		if _, err := gitOutput(dir, "fsck", "--no-progress", "--connectivity-only"); err != nil {
+			problems = append(problems, fmt.Sprintf("git objects are damaged: %v", err))
+		}
+		if changes, err := gitOutput(dir, "status", "--porcelain"); err == nil && changes != "" {
+			problems = append(problems, fmt.Sprintf("%d file(s) differ from commit %s", len(strings.Split(changes, "\n")), shortCommit(head)))
+		}
+		if lock, err := ReadLock(cacheDir); err == nil {
+			if locked, ok := lock.Repos[repo]; ok && locked.Commit != "" && locked.Commit != head {
+				problems = append(problems, fmt.Sprintf("checked out at %s, but %s records %s", shortCommit(head), LockFileName, shortCommit(locked.Commit)))
+			}
+		}
+	}
+
+	_, invalid, err := loader.LoadTemplates()
+	if err != nil {
+		return append(problems, err.Error())
+	}
+	for _, t := range invalid {
+		problems = append(problems, fmt.Sprintf("template %s: %v", t.Name, t.Err))
+	}
+	return problems
+}
+
+// CacheLeftovers lists what interrupted fetches left in the repos cache: the
+// dot-prefixed directories and files fetches stage their work in.
+func CacheLeftovers(cacheDir string) []string {
+	entries, _ := os.ReadDir(ReposDir(cacheDir))
+	var leftovers []string
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			leftovers = append(leftovers, filepath.Join(ReposDir(cacheDir), entry.Name()))
+		}
+	}
+	return leftovers
+}
+
+// ClearRepoCache removes the cached copy of repo and its lockfile entry. A
+// local directory read in place belongs to the user and is left alone.
+func ClearRepoCache(cacheDir, repo string) error {
+	loader := NewLoader(cacheDir, repo)
+	if !loader.InPlace() {
+		if err := os.RemoveAll(loader.TemplatesDir()); err != nil {
+			return fmt.Errorf("removing cached templates for %s: %w", repo, err)
+		}
+	}
+	return RemoveLock(cacheDir, repo)
+}
+
+// ClearCache removes every cached repo, the lockfile and the last fetch time,
+// so the next launch fetches everything again.
+func ClearCache(cacheDir string) error {
+	if err := os.RemoveAll(ReposDir(cacheDir)); err != nil {
+		return fmt.Errorf("removing cached templates: %w", err)
+	}
+	if err := RemoveLegacyCache(cacheDir); err != nil {
+		return err
+	}
+	for _, path := range []string{LockPath(cacheDir), NewCache(cacheDir).CacheInfoPath()} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// LegacyCacheDir is where the template repo was cached before every repo got
+// its own directory under ReposDir.
+func LegacyCacheDir(cacheDir string) string {
+	return filepath.Join(cacheDir, "templates")
+}
+
+// RemoveLegacyCache deletes the cache left behind by older versions. Only a
+// git checkout with a registry.yaml is removed, so a local template directory
+// that happens to live at the same path is left alone.
+func RemoveLegacyCache(cacheDir string) error {
+	dir := LegacyCacheDir(cacheDir)
+	for _, name := range []string{".git", "registry.yaml"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			return nil
+		}
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("removing old template cache: %w", err)
+	}
+	return nil
+}
+
+func shortCommit(commit string) string {
+	if len(commit) > 12 {
+		return commit[:12]
+	}
+	return commit
 }
@@ -0,0 +1,133 @@
+package template
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newBareRepo commits files to a fresh repository and returns a bare clone
+// of it to fetch from.
+func newBareRepo(t *testing.T, files map[string]string) string {
+	t.Helper()
+	work := writeTemplateDir(t, files)
+	gitRun(t, work, "init", "-q")
+	gitRun(t, work, "add", ".")
+	gitRun(t, work, "commit", "-q", "-m", "templates")
+	bare := filepath.Join(t.TempDir(), "templates.git")
+	gitRun(t, work, "clone", "-q", "--bare", work, bare)
+	return bare
+}
+
+func TestFailedFetchKeepsCacheIntact(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	bare := newBareRepo(t, map[string]string{
+		"registry.yaml":     "templates:\n  - name: svc\n    path: svc\n",
+		"svc/template.yaml": "name: svc\nversion: 1.0.0\n",
+	})
+	cacheDir := t.TempDir()
+	loader := NewLoader(cacheDir, "file://"+bare)
+	if err := loader.FetchTemplates(); err != nil {
+		t.Fatalf("FetchTemplates returned error: %v", err)
+	}
+
+	if err := os.RemoveAll(bare); err != nil {
+		t.Fatal(err)
+	}
+	if err := loader.FetchTemplates(); err == nil {
+		t.Fatal("expected fetching from a missing remote to fail")
+	}
+	if manifests, err := loader.LoadAllManifests(); err != nil || len(manifests) != 1 {
+		t.Fatalf("expected the cache to survive a failed pull, got %v (%v)", err, manifests)
+	}
+	if leftovers := CacheLeftovers(cacheDir); len(leftovers) != 0 {
+		t.Fatalf("expected no leftovers after a failed pull, got %v", leftovers)
+	}
+	if problems := VerifyRepoCache(cacheDir, loader.Repo()); len(problems) != 0 {
+		t.Fatalf("expected a clean cache, got %v", problems)
+	}
+}
+
+func TestOfflineModeNeverFetches(t *testing.T) {
+	t.Cleanup(func() { SetOffline(false) })
+	cacheDir := t.TempDir()
+	loader := NewLoader(cacheDir, "file:///nonexistent/templates.git")
+
+	SetOffline(true)
+	if err := loader.FetchTemplates(); !errors.Is(err, ErrOffline) {
+		t.Fatalf("expected ErrOffline, got %v", err)
+	}
+	SetOffline(false)
+
+	t.Setenv(OfflineEnv, "1")
+	if err := loader.FetchTemplates(); !errors.Is(err, ErrOffline) {
+		t.Fatalf("expected ErrOffline from %s, got %v", OfflineEnv, err)
+	}
+	t.Setenv(OfflineEnv, "false")
+	if Offline() {
+		t.Fatalf("expected %s=false to leave offline mode off", OfflineEnv)
+	}
+	if _, err := os.Stat(ReposDir(cacheDir)); !os.IsNotExist(err) {
+		t.Fatalf("expected nothing written to the cache offline, got %v", err)
+	}
+}
+
+func TestVerifyAndClearRepoCache(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	bare := newBareRepo(t, map[string]string{
+		"registry.yaml":      "templates:\n  - name: svc\n    path: svc\n  - name: gone\n    path: gone\n",
+		"svc/template.yaml":  "name: svc\nversion: 1.0.0\n",
+		"gone/template.yaml": "name: gone\nversion: 1.0.0\n",
+	})
+	cacheDir := t.TempDir()
+	repo := "file://" + bare
+	loader := NewLoader(cacheDir, repo)
+	if err := loader.FetchTemplates(); err != nil {
+		t.Fatalf("FetchTemplates returned error: %v", err)
+	}
+
+	// Wedge the cache: edit a file, delete a template and leave a staging dir.
+	if err := os.WriteFile(filepath.Join(loader.TemplatesDir(), "svc", "template.yaml"), []byte("name: svc\nversion: 9.9.9\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.RemoveAll(filepath.Join(loader.TemplatesDir(), "gone")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(ReposDir(cacheDir), ".pull-123"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	report := strings.Join(VerifyRepoCache(cacheDir, repo), "\n")
+	for _, want := range []string{"2 file(s) differ from commit", "template gone:"} {
+		if !strings.Contains(report, want) {
+			t.Fatalf("expected %q in verify report:\n%s", want, report)
+		}
+	}
+	if leftovers := CacheLeftovers(cacheDir); len(leftovers) != 1 || filepath.Base(leftovers[0]) != ".pull-123" {
+		t.Fatalf("expected the staging dir as a leftover, got %v", leftovers)
+	}
+
+	if err := ClearRepoCache(cacheDir, repo); err != nil {
+		t.Fatalf("ClearRepoCache returned error: %v", err)
+	}
+	if _, err := os.Stat(loader.TemplatesDir()); !os.IsNotExist(err) {
+		t.Fatalf("expected the repo cache to be removed, got %v", err)
+	}
+	if lock, err := ReadLock(cacheDir); err != nil || len(lock.Repos) != 0 {
+		t.Fatalf("expected the lock entry to be removed, got %v (%v)", lock.Repos, err)
+	}
+
+	if err := ClearCache(cacheDir); err != nil {
+		t.Fatalf("ClearCache returned error: %v", err)
+	}
+	if !NewCache(cacheDir).NeedsInitialFetch() || len(CacheLeftovers(cacheDir)) != 0 {
+		t.Fatal("expected an empty cache after ClearCache")
+	}
+}
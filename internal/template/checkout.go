@@ -0,0 +1,149 @@
+package template
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CheckoutTemplateAt extracts the template as it was at commit into destDir and
+// returns that version's manifest and files, so an earlier render can be
+// reproduced. Shallow template caches fetch the commit on demand. Layers of a
+// composed template are taken at their current version.
+func CheckoutTemplateAt(manifest *TemplateManifest, cacheDir, commit, destDir string) (*TemplateManifest, fs.FS, error) {
+	repoDir := templateRepoDir(manifest, cacheDir)
+	if repoDir == "" || commit == "" || (!filepath.IsAbs(manifest.SourcePath) && !isGitTopLevel(repoDir)) {
+		return nil, nil, fmt.Errorf("template %s has no git history to reproduce the original render from", manifest.QualifiedName())
+	}
+
+	subtree := path.Clean(filepath.ToSlash(manifest.SourcePath))
+	local := filepath.IsAbs(manifest.SourcePath)
+	if local {
+		prefix, err := gitOutput(repoDir, "rev-parse", "--show-prefix")
+		if err != nil {
+			return nil, nil, fmt.Errorf("locating template in its repository: %w", err)
+		}
+		subtree = strings.TrimSuffix(prefix, "/")
+	}
+
+	if _, err := gitOutput(repoDir, "cat-file", "-e", commit+"^{commit}"); err != nil {
+		if Offline() {
+			return nil, nil, fmt.Errorf("template commit %s is not cached: %w", commit, ErrOffline)
+		}
+		if _, err := gitOutput(repoDir, "fetch", "--depth=1", "origin", commit); err != nil {
+			return nil, nil, fmt.Errorf("fetching template commit %s: %w", commit, err)
+		}
+	}
+
+	treeish := commit
+	if subtree != "" && subtree != "." {
+		treeish = commit + ":" + subtree
+	}
+	cmd := exec.Command("git", "archive", "--format=tar", treeish)
+	cmd.Dir = repoDir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	archive, err := cmd.Output()
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading template at %s: %s: %w", commit, strings.TrimSpace(stderr.String()), err)
+	}
+	if _, err := extractTar(bytes.NewReader(archive), destDir); err != nil {
+		return nil, nil, err
+	}
+
+	data, err := os.ReadFile(filepath.Join(destDir, "template.yaml"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading template.yaml at %s: %w", commit, err)
+	}
+	var old TemplateManifest
+	if err := yaml.Unmarshal(data, &old); err != nil {
+		return nil, nil, fmt.Errorf("parsing template.yaml at %s: %w", commit, err)
+	}
+	old.SourcePath = manifest.SourcePath
+	old.Repo = manifest.Repo
+	old.IsBuiltin = manifest.IsBuiltin
+	old.ApplyDefaults()
+
+	oldFS := withoutTests(os.DirFS(destDir))
+	if local {
+		oldFS = os.DirFS(filepath.Join(destDir, "files"))
+	}
+	if len(manifest.Layers) == 0 {
+		return &old, oldFS, nil
+	}
+	composed := composeManifest(&old, manifest.Layers)
+	layered, err := layerFS(composed, oldFS, cacheDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	return composed, layered, nil
+}
+
+func gitOutput(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %s: %w", args[0], strings.TrimSpace(string(output)), err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// extractTar writes regular files and directories from a tar stream into
+// destDir. It returns the comment of the archive's global header, where git
+// archive records the commit.
+func extractTar(r io.Reader, destDir string) (string, error) {
+	tr := tar.NewReader(r)
+	comment := ""
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return comment, nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("reading template archive: %w", err)
+		}
+		if header.Typeflag == tar.TypeXGlobalHeader {
+			comment = header.PAXRecords["comment"]
+			continue
+		}
+
+		name := path.Clean(header.Name)
+		if !fs.ValidPath(name) {
+			return "", fmt.Errorf("template archive contains an invalid path %q", header.Name)
+		}
+		target := filepath.Join(destDir, filepath.FromSlash(name))
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return "", err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return "", err
+			}
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, fs.FileMode(header.Mode).Perm())
+			if err != nil {
+				return "", err
+			}
+			if _, err := io.Copy(f, tr); err != nil {
+				f.Close()
+				return "", err
+			}
+			if err := f.Close(); err != nil {
+				return "", err
+			}
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package template
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+)
+
+// ComposeManifests resolves `extends` and `include` for every manifest, looking
+// the referenced templates up among manifests. Templates without either are
+// returned unchanged. Templates whose bases cannot be resolved are left out and
+// returned as invalid.
+func ComposeManifests(manifests []*TemplateManifest) ([]*TemplateManifest, []InvalidTemplate) {
+	composed := make([]*TemplateManifest, 0, len(manifests))
+	var failed []InvalidTemplate
+	for _, m := range manifests {
+		if m.Extends == "" && len(m.Include) == 0 {
+			composed = append(composed, m)
+			continue
+		}
+		layers, err := resolveLayers(m, manifests, nil)
+		if err != nil {
+			failed = append(failed, InvalidTemplate{Name: m.Name, Repo: m.Repo, Err: err, Manifest: m})
+			continue
+		}
+		composed = append(composed, composeManifest(m, layers))
+	}
+	return composed, failed
+}
+
+// resolveLayers returns the templates m is built on, in merge order: the
+// `extends` base and everything under it first, then each `include` in turn.
+// A template reached twice is only layered once, at its first position.
+func resolveLayers(m *TemplateManifest, manifests []*TemplateManifest, stack []*TemplateManifest) ([]*TemplateManifest, error) {
+	for _, seen := range stack {
+		if seen == m {
+			names := make([]string, 0, len(stack)+1)
+			for _, s := range stack {
+				names = append(names, s.QualifiedName())
+			}
+			names = append(names, m.QualifiedName())
+			return nil, fmt.Errorf("template composition cycle: %s", strings.Join(names, " -> "))
+		}
+	}
+	stack = append(stack, m)
+
+	refs := m.Include
+	if m.Extends != "" {
+		refs = append([]string{m.Extends}, m.Include...)
+	}
+	var layers []*TemplateManifest
+	for _, ref := range refs {
+		base, err := findBase(ref, m, manifests)
+		if err != nil {
+			return nil, err
+		}
+		below, err := resolveLayers(base, manifests, stack)
+		if err != nil {
+			return nil, err
+		}
+		for _, layer := range append(below, base) {
+			if !containsManifest(layers, layer) {
+				layers = append(layers, layer)
+			}
+		}
+	}
+	return layers, nil
+}
+
+// findBase looks up a template reference. A qualified owner/repo:name matches
+// exactly; a plain name prefers a template from the same repo (or the local
+// directory), then the built-in template.
+func findBase(ref string, from *TemplateManifest, manifests []*TemplateManifest) (*TemplateManifest, error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return nil, fmt.Errorf("%s: empty template reference", from.QualifiedName())
+	}
+	if strings.Contains(ref, ":") {
+		for _, m := range manifests {
+			if m.QualifiedName() == ref {
+				return m, nil
+			}
+		}
+		return nil, fmt.Errorf("%s: template %q not found", from.QualifiedName(), ref)
+	}
+
+	var builtin *TemplateManifest
+	for _, m := range manifests {
+		if m.Name != ref || m == from {
+			continue
+		}
+		if m.IsBuiltin {
+			builtin = m
+			continue
+		}
+		if m.Repo == from.Repo {
+			return m, nil
+		}
+	}
+	if builtin != nil {
+		return builtin, nil
+	}
+	return nil, fmt.Errorf("%s: template %q not found", from.QualifiedName(), ref)
+}
+
+func containsManifest(list []*TemplateManifest, m *TemplateManifest) bool {
+	for _, l := range list {
+		if l == m {
+			return true
+		}
+	}
+	return false
+}
+
+// composeManifest merges the layers and then m itself into a new manifest:
+//
+//   - prompts are matched by name; a later definition replaces an earlier one
+//     in place and new prompts are appended
+//   - file rules are concatenated, so a later layer's rule wins when several match
+//   - gitignore fragments, devcontainer features, ports and mounts are unioned
+//   - the base image, hook, preview and license settings come from the last
+//     layer that sets them
+//
+// Name, version, description and author always belong to m.
+func composeManifest(m *TemplateManifest, layers []*TemplateManifest) *TemplateManifest {
+	merged := &TemplateManifest{}
+	for _, layer := range append(append([]*TemplateManifest(nil), layers...), m) {
+		mergeManifest(merged, layer)
+	}
+
+	merged.Name = m.Name
+	merged.Version = m.Version
+	merged.Description = m.Description
+	merged.Author = m.Author
+	merged.Extends = m.Extends
+	merged.Include = m.Include
+	merged.SourcePath = m.SourcePath
+	merged.Repo = m.Repo
+	merged.IsBuiltin = m.IsBuiltin
+	merged.Layers = layers
+	merged.ApplyDefaults()
+	return merged
+}
+
+func mergeManifest(dst, src *TemplateManifest) {
+	for _, p := range src.Prompts {
+		replaced := false
+		for i := range dst.Prompts {
+			if dst.Prompts[i].Name == p.Name {
+				dst.Prompts[i] = p
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			dst.Prompts = append(dst.Prompts, p)
+		}
+	}
+
+	dst.Files = append(dst.Files, src.Files...)
+	dst.Gitignore = appendUnique(dst.Gitignore, src.Gitignore...)
+
+	if src.Devcontainer.BaseImage != "" {
+		dst.Devcontainer.BaseImage = src.Devcontainer.BaseImage
+	}
+	dst.Devcontainer.Features.Always = appendUnique(dst.Devcontainer.Features.Always, src.Devcontainer.Features.Always...)
+	dst.Devcontainer.Features.When = append(dst.Devcontainer.Features.When, src.Devcontainer.Features.When...)
+	for _, port := range src.Devcontainer.Ports {
+		if !containsInt(dst.Devcontainer.Ports, port) {
+			dst.Devcontainer.Ports = append(dst.Devcontainer.Ports, port)
+		}
+	}
+	dst.Devcontainer.Mounts = appendUnique(dst.Devcontainer.Mounts, src.Devcontainer.Mounts...)
+
+	if src.Preview.Enabled || src.Preview.AppCommand != "" {
+		dst.Preview = src.Preview
+	}
+	if src.Hooks.PostCreate != "" {
+		dst.Hooks.PostCreate = src.Hooks.PostCreate
+	}
+	if src.License.Skip || src.License.Headers != "" {
+		dst.License = src.License
+	}
+}
+
+func appendUnique(list []string, values ...string) []string {
+	for _, v := range values {
+		if !containsString(list, v) {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+func containsInt(list []int, v int) bool {
+	for _, existing := range list {
+		if existing == v {
+			return true
+		}
+	}
+	return false
+}
+
+// overlayFS stacks template file trees. A path is read from the last layer
+// that has it, and directory listings merge every layer.
+type overlayFS []fs.FS
+
+func (o overlayFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	for i := len(o) - 1; i >= 0; i-- {
+		f, err := o[i].Open(name)
+		if err == nil {
+			return f, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+func (o overlayFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	entries := make(map[string]fs.DirEntry)
+	found := false
+	for i := len(o) - 1; i >= 0; i-- {
+		info, err := fs.Stat(o[i], name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			// A file hides any directories of the same name below it.
+			break
+		}
+		list, err := fs.ReadDir(o[i], name)
+		if err != nil {
+			return nil, err
+		}
+		found = true
+		for _, entry := range list {
+			if _, ok := entries[entry.Name()]; !ok {
+				entries[entry.Name()] = entry
+			}
+		}
+	}
+	if !found {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+
+	result := make([]fs.DirEntry, 0, len(entries))
+	for _, entry := range entries {
+		result = append(result, entry)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name() < result[j].Name() })
+	return result, nil
+}
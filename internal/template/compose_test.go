@@ -0,0 +1,169 @@
+package template
+
+import (
+	"io/fs"
+	"strings"
+	"testing"
+)
+
+func TestComposeManifestsMergesLayersInOrder(t *testing.T) {
+	cacheDir := t.TempDir()
+	writeCachedRepo(t, cacheDir, "acme/templates", map[string]string{
+		"registry.yaml": "templates:\n  - name: base\n    path: base\n  - name: service\n    path: service\n",
+		"base/template.yaml": `name: base
+version: 1.0.0
+extends: empty
+prompts:
+  - name: language
+    label: Language
+    default: go
+files:
+  - src: "ci/**"
+    when: "{{.ci}}"
+devcontainer:
+  base_image: mcr.microsoft.com/devcontainers/base:ubuntu
+  features:
+    always: [ghcr.io/devcontainers/features/github-cli:1]
+`,
+		"base/CLAUDE.md":      "base claude\n",
+		"base/README.md.tmpl": "base readme\n",
+		"base/ci/build.yml":   "base ci\n",
+		"service/template.yaml": `name: service
+version: 2.0.0
+description: A service
+extends: base
+include: [other/mixins:lint]
+prompts:
+  - name: language
+    label: Service language
+    default: rust
+files:
+  - src: "ci/**"
+    always: true
+`,
+		"service/README.md.tmpl": "service readme\n",
+	})
+	writeCachedRepo(t, cacheDir, "other/mixins", map[string]string{
+		"registry.yaml": "templates:\n  - name: lint\n    path: lint\n",
+		"lint/template.yaml": `name: lint
+version: 0.1.0
+gitignore: [go]
+devcontainer:
+  features:
+    always: [ghcr.io/devcontainers/features/github-cli:1, ghcr.io/devcontainers/features/go:1]
+`,
+		"lint/ci/lint.yml": "lint\n",
+		"lint/CLAUDE.md":   "lint claude\n",
+	})
+
+	manifests := append([]*TemplateManifest{GetBuiltinManifest()}, LoadRepoManifests(cacheDir, []string{"acme/templates", "other/mixins"})...)
+	composed, failed := ComposeManifests(manifests)
+	if len(failed) != 0 {
+		t.Fatalf("unexpected composition errors: %v", failed)
+	}
+	var service *TemplateManifest
+	for _, m := range composed {
+		if m.QualifiedName() == "acme/templates:service" {
+			service = m
+		}
+	}
+	if service == nil {
+		t.Fatal("composed service template not found")
+	}
+
+	var layers []string
+	for _, l := range service.Layers {
+		layers = append(layers, l.QualifiedName())
+	}
+	if got := strings.Join(layers, ","); got != "empty,acme/templates:base,other/mixins:lint" {
+		t.Fatalf("unexpected layers: %s", got)
+	}
+	if service.Version != "2.0.0" || service.Description != "A service" {
+		t.Fatalf("expected the template's own metadata, got %s %q", service.Version, service.Description)
+	}
+
+	// Prompts from the built-in template come first; the service's language
+	// prompt replaces the base one in place.
+	if service.Prompts[0].Name != "project_name" {
+		t.Fatalf("expected built-in prompts first, got %s", service.Prompts[0].Name)
+	}
+	last := service.Prompts[len(service.Prompts)-1]
+	if last.Name != "language" || last.Label != "Service language" || last.Default != "rust" {
+		t.Fatalf("expected overridden language prompt last, got %+v", last)
+	}
+	count := 0
+	for _, p := range service.Prompts {
+		if p.Name == "language" {
+			count++
+		}
+	}
+	if count != 1 {
+		t.Fatalf("expected one language prompt, got %d", count)
+	}
+
+	if n := len(service.Files); n < 2 || service.Files[n-1].Always != true || service.Files[n-2].When != "{{.ci}}" {
+		t.Fatalf("expected the service rule after the base rule, got %+v", service.Files)
+	}
+	if got := strings.Join(service.Devcontainer.Features.Always, ","); got != "ghcr.io/devcontainers/features/github-cli:1,ghcr.io/devcontainers/features/go:1" {
+		t.Fatalf("unexpected features: %s", got)
+	}
+	if service.Devcontainer.BaseImage != "mcr.microsoft.com/devcontainers/base:ubuntu" {
+		t.Fatalf("expected base image from base, got %q", service.Devcontainer.BaseImage)
+	}
+	if !containsString(service.Gitignore, "go") || !containsString(service.Gitignore, "os") {
+		t.Fatalf("expected gitignore fragments from every layer, got %v", service.Gitignore)
+	}
+
+	templateFS, err := ResolveTemplateFS(service, cacheDir, "")
+	if err != nil {
+		t.Fatalf("ResolveTemplateFS returned error: %v", err)
+	}
+	for name, want := range map[string]string{
+		"README.md.tmpl": "service readme\n",
+		"CLAUDE.md":      "lint claude\n",
+		"ci/build.yml":   "base ci\n",
+		"ci/lint.yml":    "lint\n",
+	} {
+		got, err := fs.ReadFile(templateFS, name)
+		if err != nil {
+			t.Fatalf("reading %s: %v", name, err)
+		}
+		if string(got) != want {
+			t.Fatalf("%s: expected %q, got %q", name, want, got)
+		}
+	}
+	entries, err := fs.ReadDir(templateFS, "ci")
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("expected merged ci directory with 2 entries, got %v (%v)", entries, err)
+	}
+
+	result, err := NewRenderer(service, map[string]interface{}{"project_name": "svc", "license": "none"}).Render(templateFS)
+	if err != nil {
+		t.Fatalf("Render returned error: %v", err)
+	}
+	if _, ok := result.File("ci/build.yml"); !ok {
+		t.Fatalf("expected the service's always rule to win over the base condition, got %v", result.Paths())
+	}
+}
+
+func TestComposeManifestsReportsCyclesAndMissingBases(t *testing.T) {
+	a := &TemplateManifest{Name: "a", Extends: "b"}
+	b := &TemplateManifest{Name: "b", Include: []string{"a"}}
+	c := &TemplateManifest{Name: "c", Extends: "missing"}
+	d := &TemplateManifest{Name: "d"}
+
+	composed, failed := ComposeManifests([]*TemplateManifest{a, b, c, d})
+	if len(composed) != 1 || composed[0] != d {
+		t.Fatalf("expected only the plain template to survive, got %v", composed)
+	}
+	errs := map[string]error{}
+	for _, f := range failed {
+		errs[f.QualifiedName()] = f.Err
+	}
+	if err := errs["a"]; err == nil || !strings.Contains(err.Error(), "cycle: a -> b -> a") {
+		t.Fatalf("expected cycle error for a, got %v", err)
+	}
+	if err := errs["c"]; err == nil || !strings.Contains(err.Error(), `"missing" not found`) {
+		t.Fatalf("expected missing base error for c, got %v", err)
+	}
+}
@@ -0,0 +1,180 @@
+package template
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// DevcontainerPath is where the manifest's devcontainer section is rendered.
+const DevcontainerPath = ".devcontainer/devcontainer.json"
+
+// RenderDevcontainer builds devcontainer.json from the manifest's devcontainer
+// section. When existing holds a devcontainer.json rendered from the template's
+// files, the manifest settings are merged into it: the image is only set when the
+// file has none, and features, ports and mounts are added next to the file's own.
+func RenderDevcontainer(config DevcontainerConfig, answers map[string]interface{}, existing []byte) ([]byte, error) {
+	doc := map[string]interface{}{}
+	if len(bytes.TrimSpace(existing)) > 0 {
+		if err := json.Unmarshal(stripJSONComments(existing), &doc); err != nil {
+			return nil, fmt.Errorf("parsing existing %s: %w", DevcontainerPath, err)
+		}
+	}
+
+	if _, ok := doc["name"]; !ok {
+		if name := FormatAnswer(answers["project_name"]); name != "" {
+			doc["name"] = name
+		}
+	}
+
+	if config.BaseImage != "" && !hasAnyKey(doc, "image", "build", "dockerComposeFile") {
+		doc["image"] = devcontainerImageRef(config.BaseImage)
+	}
+
+	if refs := DevcontainerFeatureRefs(config, answers); len(refs) > 0 {
+		features, _ := doc["features"].(map[string]interface{})
+		if features == nil {
+			features = map[string]interface{}{}
+		}
+		for _, ref := range refs {
+			if _, ok := features[ref]; !ok {
+				features[ref] = map[string]interface{}{}
+			}
+		}
+		doc["features"] = features
+	}
+
+	if len(config.Ports) > 0 {
+		ports, _ := doc["forwardPorts"].([]interface{})
+		for _, port := range config.Ports {
+			if !containsJSONValue(ports, port) {
+				ports = append(ports, port)
+			}
+		}
+		doc["forwardPorts"] = ports
+	}
+
+	if len(config.Mounts) > 0 {
+		mounts, _ := doc["mounts"].([]interface{})
+		for _, mount := range config.Mounts {
+			if !containsJSONValue(mounts, mount) {
+				mounts = append(mounts, mount)
+			}
+		}
+		doc["mounts"] = mounts
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	// Shell commands such as postCreateCommand are full of &&, keep them readable.
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return nil, fmt.Errorf("encoding %s: %w", DevcontainerPath, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// DevcontainerFeatureRefs returns the feature references enabled for the answers:
+// every `always` feature followed by those whose `when` entry evaluates truthy.
+// Short names such as gh or node are expanded to their ghcr.io references.
+func DevcontainerFeatureRefs(config DevcontainerConfig, answers map[string]interface{}) []string {
+	names := append([]string{}, config.Features.Always...)
+	for _, conditional := range config.Features.When {
+		if EvaluateCondition(conditional.If, answers) {
+			names = append(names, conditional.Features...)
+		}
+	}
+
+	seen := make(map[string]bool, len(names))
+	refs := make([]string, 0, len(names))
+	for _, name := range names {
+		ref := featureToDevcontainerRef(strings.TrimSpace(name))
+		if ref == "" || seen[ref] {
+			continue
+		}
+		seen[ref] = true
+		refs = append(refs, ref)
+	}
+	return refs
+}
+
+// devcontainerImageRef expands a bare variant such as "ubuntu" to the matching
+// devcontainers base image. Anything with a registry path or tag is used as is.
+func devcontainerImageRef(baseImage string) string {
+	if strings.ContainsAny(baseImage, "/:") {
+		return baseImage
+	}
+	return "mcr.microsoft.com/devcontainers/base:" + baseImage
+}
+
+func hasAnyKey(doc map[string]interface{}, keys ...string) bool {
+	for _, key := range keys {
+		if _, ok := doc[key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// containsJSONValue compares by string form so a decoded 3000 (float64) matches
+// the manifest's int 3000.
+func containsJSONValue(values []interface{}, value interface{}) bool {
+	want := fmt.Sprintf("%v", value)
+	for _, v := range values {
+		if fmt.Sprintf("%v", v) == want {
+			return true
+		}
+	}
+	return false
+}
+
+// stripJSONComments turns JSONC, as used by devcontainer.json, into plain JSON by
+// removing // and /* */ comments and trailing commas outside of strings.
+func stripJSONComments(data []byte) []byte {
+	var out []byte
+	inString := false
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		if inString {
+			out = append(out, c)
+			if c == '\\' && i+1 < len(data) {
+				i++
+				out = append(out, data[i])
+			} else if c == '"' {
+				inString = false
+			}
+			continue
+		}
+
+		switch {
+		case c == '"':
+			inString = true
+			out = append(out, c)
+		case c == '/' && i+1 < len(data) && data[i+1] == '/':
+			for i < len(data) && data[i] != '\n' {
+				i++
+			}
+			if i < len(data) {
+				out = append(out, '\n')
+			}
+		case c == '/' && i+1 < len(data) && data[i+1] == '*':
+			end := bytes.Index(data[i+2:], []byte("*/"))
+			if end < 0 {
+				return out
+			}
+			i += end + 3
+		case c == '}' || c == ']':
+			// Drop a trailing comma before the closing bracket.
+			trimmed := bytes.TrimRight(out, " \t\r\n")
+			if len(trimmed) > 0 && trimmed[len(trimmed)-1] == ',' {
+				out = append(trimmed[:len(trimmed)-1], out[len(trimmed):]...)
+			}
+			out = append(out, c)
+		default:
+			out = append(out, c)
+		}
+	}
+	return out
+}
@@ -0,0 +1,137 @@
+package template
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func TestRenderDevcontainerSynthesizesFromManifest(t *testing.T) {
+	config := DevcontainerConfig{
+		BaseImage: "ubuntu",
+		Features: DevcontainerFeatures{
+			Always: []string{"gh"},
+			When: []ConditionalFeatures{
+				{If: "{{if .use_node}}true{{end}}", Features: []string{"node"}},
+				{If: "{{if .use_rust}}true{{end}}", Features: []string{"rust"}},
+			},
+		},
+		Ports:  []int{3000},
+		Mounts: []string{"source=cache,target=/cache,type=volume"},
+	}
+
+	data, err := RenderDevcontainer(config, map[string]interface{}{"project_name": "demo", "use_node": true}, nil)
+	if err != nil {
+		t.Fatalf("RenderDevcontainer returned error: %v", err)
+	}
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("rendered devcontainer.json is not valid JSON: %v\n%s", err, data)
+	}
+	if doc["name"] != "demo" {
+		t.Fatalf("expected name demo, got %v", doc["name"])
+	}
+	if doc["image"] != "mcr.microsoft.com/devcontainers/base:ubuntu" {
+		t.Fatalf("expected expanded base image, got %v", doc["image"])
+	}
+	features := doc["features"].(map[string]interface{})
+	for _, want := range []string{"ghcr.io/devcontainers/features/github-cli:1", "ghcr.io/devcontainers/features/node:1"} {
+		if _, ok := features[want]; !ok {
+			t.Fatalf("expected feature %s, got %v", want, features)
+		}
+	}
+	if _, ok := features["ghcr.io/devcontainers/features/rust:1"]; ok {
+		t.Fatalf("expected rust feature to be left out, got %v", features)
+	}
+	if ports := doc["forwardPorts"].([]interface{}); len(ports) != 1 || ports[0] != float64(3000) {
+		t.Fatalf("expected forwardPorts [3000], got %v", doc["forwardPorts"])
+	}
+	if mounts := doc["mounts"].([]interface{}); len(mounts) != 1 {
+		t.Fatalf("expected one mount, got %v", doc["mounts"])
+	}
+}
+
+func TestRenderDevcontainerMergesIntoTemplateFile(t *testing.T) {
+	existing := []byte(`{
+  // written by the template author
+  "name": "custom",
+  "image": "golang:1.22",
+  "forwardPorts": [3000,],
+  "features": {
+    "ghcr.io/devcontainers/features/github-cli:1": {"version": "2"}
+  },
+  "postCreateCommand": "make setup && echo ready"
+}`)
+	config := DevcontainerConfig{
+		BaseImage: "ubuntu",
+		Features:  DevcontainerFeatures{Always: []string{"gh", "go"}},
+		Ports:     []int{3000, 8080},
+	}
+
+	data, err := RenderDevcontainer(config, map[string]interface{}{"project_name": "demo"}, existing)
+	if err != nil {
+		t.Fatalf("RenderDevcontainer returned error: %v", err)
+	}
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("merged devcontainer.json is not valid JSON: %v\n%s", err, data)
+	}
+	if doc["name"] != "custom" || doc["image"] != "golang:1.22" {
+		t.Fatalf("expected template name and image to be kept, got %v / %v", doc["name"], doc["image"])
+	}
+	if doc["postCreateCommand"] != "make setup && echo ready" {
+		t.Fatalf("expected postCreateCommand to be kept, got %v", doc["postCreateCommand"])
+	}
+	features := doc["features"].(map[string]interface{})
+	if gh := features["ghcr.io/devcontainers/features/github-cli:1"].(map[string]interface{}); gh["version"] != "2" {
+		t.Fatalf("expected existing feature options to be kept, got %v", gh)
+	}
+	if _, ok := features["ghcr.io/devcontainers/features/go:1"]; !ok {
+		t.Fatalf("expected go feature to be added, got %v", features)
+	}
+	if ports := doc["forwardPorts"].([]interface{}); len(ports) != 2 {
+		t.Fatalf("expected ports 3000 and 8080 without duplicates, got %v", ports)
+	}
+}
+
+func TestRendererWritesDevcontainerFromManifest(t *testing.T) {
+	manifest := &TemplateManifest{
+		Name: "test",
+		Devcontainer: DevcontainerConfig{
+			BaseImage: "debian",
+			Features:  DevcontainerFeatures{Always: []string{"node"}},
+		},
+	}
+	sourceFS := fstest.MapFS{
+		"README.md.tmpl": {Data: []byte("# {{.project_name}}\n")},
+	}
+	renderer := NewRenderer(manifest, map[string]interface{}{"project_name": "demo"})
+
+	files, err := renderer.ListFiles(sourceFS)
+	if err != nil {
+		t.Fatalf("ListFiles returned error: %v", err)
+	}
+	if !containsString(files, DevcontainerPath) {
+		t.Fatalf("expected %s in file list, got %v", DevcontainerPath, files)
+	}
+
+	targetDir := t.TempDir()
+	if err := renderer.RenderTo(targetDir, sourceFS); err != nil {
+		t.Fatalf("RenderTo returned error: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(targetDir, DevcontainerPath))
+	if err != nil {
+		t.Fatalf("expected devcontainer.json to be written: %v", err)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("invalid devcontainer.json: %v", err)
+	}
+	if doc["image"] != "mcr.microsoft.com/devcontainers/base:debian" {
+		t.Fatalf("unexpected image %v", doc["image"])
+	}
+}
@@ -10,7 +10,48 @@ var embeddedEmpty embed.FS
 
 // GetEmbeddedEmptyTemplate returns the filesystem for the embedded "empty" template
 func GetEmbeddedEmptyTemplate() (fs.FS, error) {
-	return fs.Sub(embeddedEmpty, "embedded/empty")
+	sub, err := fs.Sub(embeddedEmpty, "embedded/empty")
+	if err != nil {
+		return nil, err
+	}
+	return embeddedFS{sub}, nil
+}
+
+// embeddedFS reports embedded files as 0644. embed.FS keeps no permissions and
+// marks every file read-only, which rendered files would otherwise inherit.
+type embeddedFS struct {
+	fs.FS
+}
+
+func (f embeddedFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	entries, err := fs.ReadDir(f.FS, name)
+	for i, entry := range entries {
+		entries[i] = embeddedEntry{entry}
+	}
+	return entries, err
+}
+
+type embeddedEntry struct {
+	fs.DirEntry
+}
+
+func (e embeddedEntry) Info() (fs.FileInfo, error) {
+	info, err := e.DirEntry.Info()
+	if err != nil {
+		return nil, err
+	}
+	return embeddedInfo{info}, nil
+}
+
+type embeddedInfo struct {
+	fs.FileInfo
+}
+
+func (i embeddedInfo) Mode() fs.FileMode {
+	if i.IsDir() {
+		return i.FileInfo.Mode()
+	}
+	return defaultFileMode
 }
 
 // GetBuiltinManifest returns the manifest for the built-in "empty" template
@@ -45,15 +86,10 @@ func GetBuiltinManifest() *TemplateManifest {
 				Default: "private",
 			},
 			{
-				Name:  "license",
-				Label: "License",
-				Type:  PromptSelect,
-				Options: []PromptOption{
-					{Label: "MIT", Value: "MIT"},
-					{Label: "Apache-2.0", Value: "Apache-2.0"},
-					{Label: "GPL-3.0", Value: "GPL-3.0"},
-					{Label: "none", Value: "none"},
-				},
+				Name:    "license",
+				Label:   "License",
+				Type:    PromptSelect,
+				Options: LicenseOptions(),
 				Default: "MIT",
 			},
 			{
@@ -69,11 +105,18 @@ func GetBuiltinManifest() *TemplateManifest {
 				Default: false,
 			},
 		},
+		Gitignore: []string{"os", "editors", "env"},
 		Files: []FileRule{
 			{
 				Src:  ".incubator/preview/**",
 				When: "{{if .enable_preview}}true{{end}}",
 			},
+			{
+				// Embedded files carry no execute bit.
+				Src:  ".incubator/preview/entrypoint.sh",
+				When: "{{if .enable_preview}}true{{end}}",
+				Mode: 0755,
+			},
 		},
 		Preview: PreviewConfig{
 			Enabled:    true,
@@ -0,0 +1,442 @@
+package template
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Fetcher downloads a template repo into its cache directory.
+type Fetcher interface {
+	// Fetch brings dir up to date, creating it if needed, and reports what
+	// the repo resolved to.
+	Fetch(dir string) (FetchResult, error)
+}
+
+// FetchResult describes the download a Fetcher made.
+type FetchResult struct {
+	// Commit is the git commit fetched, when known.
+	Commit string
+	// Checksum is "sha256:<hex>" of a downloaded archive.
+	Checksum string
+}
+
+// GitFetcher clones the repo with git: a shallow clone of the default branch,
+// or a detached checkout of Ref when the repo is pinned.
+type GitFetcher struct {
+	URL string
+	Ref string
+}
+
+// Fetch clones the repo into dir, or pulls it when dir is already a clone.
+func (f *GitFetcher) Fetch(dir string) (FetchResult, error) {
+	var err error
+	if _, statErr := os.Stat(filepath.Join(dir, ".git")); statErr == nil {
+		// Already cloned, pull
+		err = f.pull(dir)
+	} else {
+		// Fresh clone
+		err = f.clone(dir)
+	}
+	if err != nil {
+		return FetchResult{}, err
+	}
+	commit, err := gitOutput(dir, "rev-parse", "HEAD")
+	if err != nil {
+		return FetchResult{}, fmt.Errorf("resolving templates commit: %w", err)
+	}
+	return FetchResult{Commit: commit}, nil
+}
+
+// clone performs a shallow clone of the templates repo next to dir and moves
+// it into place, so a failed clone leaves nothing behind.
+func (f *GitFetcher) clone(dir string) error {
+	staging, err := os.MkdirTemp(filepath.Dir(dir), ".clone-*")
+	if err != nil {
+		return fmt.Errorf("creating cache directory: %w", err)
+	}
+	defer os.RemoveAll(staging)
+
+	if f.Ref != "" {
+		// `git clone --branch` cannot take a commit, so start empty and
+		// check the ref out.
+		if _, err := gitOutput(staging, "init", "-q"); err != nil {
+			return fmt.Errorf("cloning templates repo: %w", err)
+		}
+		if _, err := gitOutput(staging, "remote", "add", "origin", f.URL); err != nil {
+			return fmt.Errorf("cloning templates repo: %w", err)
+		}
+		if err := f.checkoutRef(staging); err != nil {
+			return err
+		}
+	} else {
+		cmd := exec.Command("git", "clone", "-q", "--depth=1", f.URL, staging)
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("cloning templates repo: %s: %w", strings.TrimSpace(string(output)), err)
+		}
+	}
+	return replaceDir(dir, staging)
+}
+
+// pull pulls the latest changes, or moves a pinned repo to its ref. It works
+// on a copy of dir that replaces dir only once the pull succeeded, so a
+// failed or interrupted pull never leaves a half-updated tree.
+func (f *GitFetcher) pull(dir string) error {
+	staging, err := os.MkdirTemp(filepath.Dir(dir), ".pull-*")
+	if err != nil {
+		return fmt.Errorf("creating cache directory: %w", err)
+	}
+	defer os.RemoveAll(staging)
+	if err := copyDir(dir, staging); err != nil {
+		return fmt.Errorf("copying templates cache: %w", err)
+	}
+
+	if f.Ref != "" {
+		if err := f.checkoutRef(staging); err != nil {
+			return err
+		}
+	} else {
+		cmd := exec.Command("git", "pull", "-q", "--ff-only")
+		cmd.Dir = staging
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("pulling templates: %s: %w", strings.TrimSpace(string(output)), err)
+		}
+	}
+	if head, err := gitOutput(staging, "rev-parse", "HEAD"); err == nil {
+		if current, err := gitOutput(dir, "rev-parse", "HEAD"); err == nil && current == head {
+			// Nothing changed; keep the tree that is already in place.
+			return nil
+		}
+	}
+	return replaceDir(dir, staging)
+}
+
+// copyDir copies the tree at src, which must exist, into the empty directory dst.
+func copyDir(src, dst string) error {
+	return filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm()|0700)
+		case d.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(p)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case d.Type().IsRegular():
+			data, err := os.ReadFile(p)
+			if err != nil {
+				return err
+			}
+			return os.WriteFile(target, data, info.Mode().Perm())
+		}
+		return nil
+	})
+}
+
+// checkoutRef detaches the checkout at the pinned ref. A full commit that is
+// already checked out never changes, so it is not fetched again; a branch is
+// fetched each time and so follows its tip.
+func (f *GitFetcher) checkoutRef(dir string) error {
+	if isCommitID(f.Ref) {
+		if head, err := gitOutput(dir, "rev-parse", "HEAD"); err == nil && head == f.Ref {
+			return nil
+		}
+	}
+
+	if _, err := gitOutput(dir, "fetch", "-q", "--depth=1", "origin", f.Ref); err == nil {
+		if _, err := gitOutput(dir, "checkout", "-q", "--detach", "FETCH_HEAD"); err != nil {
+			return fmt.Errorf("checking out %s: %w", f.Ref, err)
+		}
+		return nil
+	}
+
+	// Servers only hand out refs and full commits by name; an abbreviated
+	// commit has to be found in the full history.
+	if err := fetchHistory(dir); err != nil {
+		return fmt.Errorf("fetching %s: %w", f.URL, err)
+	}
+	if _, err := gitOutput(dir, "rev-parse", "--verify", "-q", f.Ref+"^{commit}"); err != nil {
+		return fmt.Errorf("ref %s not found in %s", f.Ref, f.URL)
+	}
+	if _, err := gitOutput(dir, "checkout", "-q", "--detach", f.Ref); err != nil {
+		return fmt.Errorf("checking out %s: %w", f.Ref, err)
+	}
+	return nil
+}
+
+// fetchHistory fetches every branch and tag of origin, deepening a shallow
+// clone to its full history.
+func fetchHistory(dir string) error {
+	args := []string{"fetch", "-q", "--tags"}
+	if shallow, err := gitOutput(dir, "rev-parse", "--is-shallow-repository"); err == nil && shallow == "true" {
+		args = append(args, "--unshallow")
+	}
+	_, err := gitOutput(dir, append(args, "origin")...)
+	return err
+}
+
+// isCommitID reports whether ref is a full hexadecimal commit ID.
+func isCommitID(ref string) bool {
+	if len(ref) != 40 && len(ref) != 64 {
+		return false
+	}
+	for _, r := range ref {
+		if !(r >= '0' && r <= '9' || r >= 'a' && r <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// ArchiveFetcher downloads a .tar.gz or .zip of the repo over HTTP and
+// extracts it, without needing git. A single top-level directory in the
+// archive, as GitHub and GitLab add, is stripped.
+type ArchiveFetcher struct {
+	URL string
+	// Checksum is the SHA-256 the archive must have, as hex with an optional
+	// "sha256:" prefix. Empty accepts any archive.
+	Checksum string
+	// Client defaults to http.DefaultClient.
+	Client *http.Client
+}
+
+// Fetch downloads and verifies the archive, then replaces dir with its
+// contents. dir is left untouched if anything fails.
+func (f *ArchiveFetcher) Fetch(dir string) (FetchResult, error) {
+	client := f.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Get(f.URL)
+	if err != nil {
+		return FetchResult{}, fmt.Errorf("downloading templates archive: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return FetchResult{}, fmt.Errorf("downloading templates archive: %s returned status %d", f.URL, resp.StatusCode)
+	}
+
+	download, err := os.CreateTemp(filepath.Dir(dir), ".download-*")
+	if err != nil {
+		return FetchResult{}, fmt.Errorf("creating cache directory: %w", err)
+	}
+	defer os.Remove(download.Name())
+	defer download.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(download, hash), resp.Body)
+	if err != nil {
+		return FetchResult{}, fmt.Errorf("downloading templates archive: %w", err)
+	}
+	checksum := "sha256:" + hex.EncodeToString(hash.Sum(nil))
+	if want := f.Checksum; want != "" {
+		if !strings.HasPrefix(want, "sha256:") {
+			want = "sha256:" + want
+		}
+		if !strings.EqualFold(want, checksum) {
+			return FetchResult{}, fmt.Errorf("templates archive %s has checksum %s, expected %s", f.URL, checksum, want)
+		}
+	}
+
+	staging, err := os.MkdirTemp(filepath.Dir(dir), ".extract-*")
+	if err != nil {
+		return FetchResult{}, fmt.Errorf("creating cache directory: %w", err)
+	}
+	defer os.RemoveAll(staging)
+
+	var comment string
+	magic := make([]byte, 4)
+	if _, err := download.ReadAt(magic, 0); err != nil && err != io.EOF {
+		return FetchResult{}, err
+	}
+	if _, err := download.Seek(0, io.SeekStart); err != nil {
+		return FetchResult{}, err
+	}
+	switch {
+	case bytes.HasPrefix(magic, []byte("PK\x03\x04")):
+		comment, err = extractZip(download, size, staging)
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		var gz *gzip.Reader
+		if gz, err = gzip.NewReader(download); err == nil {
+			comment, err = extractTar(gz, staging)
+		}
+	default:
+		err = fmt.Errorf("%s is not a .tar.gz or .zip archive", f.URL)
+	}
+	if err != nil {
+		return FetchResult{}, err
+	}
+
+	root, err := archiveRoot(staging)
+	if err != nil {
+		return FetchResult{}, err
+	}
+	if err := replaceDir(dir, root); err != nil {
+		return FetchResult{}, err
+	}
+
+	result := FetchResult{Checksum: checksum}
+	// git archive records the commit it was made from as the archive comment.
+	if comment = strings.TrimSpace(comment); isCommitID(comment) {
+		result.Commit = comment
+	}
+	return result, nil
+}
+
+// extractZip writes the regular files and directories of a zip archive into
+// destDir and returns the archive comment.
+func extractZip(r io.ReaderAt, size int64, destDir string) (string, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return "", fmt.Errorf("reading templates archive: %w", err)
+	}
+	for _, file := range zr.File {
+		name := path.Clean(file.Name)
+		if !fs.ValidPath(name) {
+			return "", fmt.Errorf("templates archive contains an invalid path %q", file.Name)
+		}
+		target := filepath.Join(destDir, filepath.FromSlash(name))
+
+		mode := file.Mode()
+		switch {
+		case mode.IsDir():
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return "", err
+			}
+		case mode.IsRegular():
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return "", err
+			}
+			perm := mode.Perm()
+			if perm == 0 {
+				perm = 0644
+			}
+			if err := writeZipFile(file, target, perm); err != nil {
+				return "", err
+			}
+		}
+	}
+	return zr.Comment, nil
+}
+
+func writeZipFile(file *zip.File, target string, perm fs.FileMode) error {
+	src, err := file.Open()
+	if err != nil {
+		return fmt.Errorf("reading %s from templates archive: %w", file.Name, err)
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+// archiveRoot returns the single directory an extracted archive is wrapped
+// in, or dir itself when the archive has several top-level entries.
+func archiveRoot(dir string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	if len(entries) == 1 && entries[0].IsDir() {
+		return filepath.Join(dir, entries[0].Name()), nil
+	}
+	return dir, nil
+}
+
+// replaceDir moves newDir to dir, keeping the previous dir until the new one
+// is in place so a failure never leaves dir half-written.
+func replaceDir(dir, newDir string) error {
+	old := ""
+	if _, err := os.Lstat(dir); err == nil {
+		old = newDir + ".old"
+		if err := os.Rename(dir, old); err != nil {
+			return fmt.Errorf("replacing %s: %w", dir, err)
+		}
+	}
+	if err := os.Rename(newDir, dir); err != nil {
+		if old != "" {
+			os.Rename(old, dir)
+		}
+		return fmt.Errorf("replacing %s: %w", dir, err)
+	}
+	if old != "" {
+		os.RemoveAll(old)
+	}
+	return nil
+}
+
+// IsArchiveURL reports whether a repo reference is an HTTP(S) URL of a
+// .tar.gz, .tgz or .zip archive, optionally with a #sha256=<hex> fragment.
+func IsArchiveURL(repo string) bool {
+	u, err := url.Parse(repo)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+	p := strings.ToLower(u.Path)
+	return strings.HasSuffix(p, ".tar.gz") || strings.HasSuffix(p, ".tgz") || strings.HasSuffix(p, ".zip")
+}
+
+// splitChecksum splits a #sha256=<hex> fragment off an archive URL.
+func splitChecksum(archiveURL string) (string, string) {
+	base, fragment, found := strings.Cut(archiveURL, "#")
+	if !found {
+		return archiveURL, ""
+	}
+	if sum, ok := strings.CutPrefix(fragment, "sha256="); ok {
+		return base, sum
+	}
+	return base, ""
+}
+
+// GitHubArchiveURL returns the codeload URL of a GitHub repo's tarball at
+// ref, or at the default branch when ref is empty.
+func GitHubArchiveURL(repo, ref string) string {
+	if ref == "" {
+		ref = "HEAD"
+	}
+	return fmt.Sprintf("https://codeload.github.com/%s/tar.gz/%s", repo, ref)
+}
+
+// isGitHubRepo reports whether a repo reference is the owner/repo shorthand
+// for a GitHub repository.
+func isGitHubRepo(repo string) bool {
+	return !strings.Contains(repo, ":") && !IsLocalRepo(repo) && strings.Count(repo, "/") == 1
+}
+
+func hasGit() bool {
+	_, err := exec.LookPath("git")
+	return err == nil
+}
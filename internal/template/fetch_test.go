@@ -0,0 +1,208 @@
+package template
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+var archiveFiles = map[string]string{
+	"registry.yaml":     "templates:\n  - name: svc\n    path: svc\n",
+	"svc/template.yaml": "name: svc\nversion: 1.0.0\n",
+}
+
+const archiveCommit = "0123456789abcdef0123456789abcdef01234567"
+
+// tarGz builds an archive laid out like GitHub's: one top-level directory and
+// the commit in the global header.
+func tarGz(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	if err := tw.WriteHeader(&tar.Header{Typeflag: tar.TypeXGlobalHeader, Name: "pax_global_header", PAXRecords: map[string]string{"comment": archiveCommit}}); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: "templates-main/" + name, Mode: 0644, Size: int64(len(content))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func zipArchive(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestFetchTemplatesFromArchive(t *testing.T) {
+	tarball := tarGz(t, archiveFiles)
+	zipped := zipArchive(t, archiveFiles)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/templates.tar.gz":
+			w.Write(tarball)
+		case "/templates.zip":
+			w.Write(zipped)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cacheDir := t.TempDir()
+	for _, repo := range []string{
+		server.URL + "/templates.tar.gz",
+		server.URL + "/templates.tar.gz#sha256=" + sha256Hex(tarball),
+		server.URL + "/templates.zip",
+	} {
+		loader := NewLoader(cacheDir, repo)
+		if err := loader.FetchTemplates(); err != nil {
+			t.Fatalf("fetching %s: %v", repo, err)
+		}
+		manifests, err := loader.LoadAllManifests()
+		if err != nil || len(manifests) != 1 || manifests[0].Version != "1.0.0" {
+			t.Fatalf("loading %s: %v (%v)", repo, err, manifests)
+		}
+	}
+
+	lock, err := ReadLock(cacheDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	locked := lock.Repos[server.URL+"/templates.tar.gz"]
+	if locked.Commit != archiveCommit || locked.Checksum != "sha256:"+sha256Hex(tarball) {
+		t.Fatalf("expected the archive commit and checksum in the lockfile, got %+v", locked)
+	}
+
+	// A checksum mismatch fails without touching the cache.
+	loader := NewLoader(cacheDir, server.URL+"/templates.tar.gz")
+	loader.Fetcher = &ArchiveFetcher{URL: server.URL + "/templates.zip", Checksum: sha256Hex(tarball)}
+	if err := loader.FetchTemplates(); err == nil || !strings.Contains(err.Error(), "checksum") {
+		t.Fatalf("expected a checksum error, got %v", err)
+	}
+	if manifests, err := loader.LoadAllManifests(); err != nil || len(manifests) != 1 {
+		t.Fatalf("expected the previous cache to survive, got %v (%v)", err, manifests)
+	}
+
+	if err := NewLoader(cacheDir, server.URL+"/missing.tar.gz").FetchTemplates(); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Fatalf("expected a 404 error, got %v", err)
+	}
+}
+
+func TestDefaultFetcher(t *testing.T) {
+	cacheDir := t.TempDir()
+	archive, ok := NewLoader(cacheDir, "https://example.com/t.tar.gz#sha256=abc").defaultFetcher().(*ArchiveFetcher)
+	if !ok || archive.URL != "https://example.com/t.tar.gz" || archive.Checksum != "abc" {
+		t.Fatalf("expected an archive fetcher with the URL's checksum, got %+v", archive)
+	}
+	if _, ok := NewLoader(cacheDir, "https://example.com/group/templates.git").defaultFetcher().(*GitFetcher); !ok {
+		t.Fatal("expected git URLs to be cloned")
+	}
+	if got := GitHubArchiveURL("owner/repo", "v1.4.0"); got != "https://codeload.github.com/owner/repo/tar.gz/v1.4.0" {
+		t.Fatalf("unexpected GitHub archive URL %s", got)
+	}
+	if got := GitHubArchiveURL("owner/repo", ""); got != "https://codeload.github.com/owner/repo/tar.gz/HEAD" {
+		t.Fatalf("unexpected GitHub archive URL %s", got)
+	}
+}
+
+func TestPinnedArchiveChecksums(t *testing.T) {
+	content := tarGz(t, archiveFiles)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(content)
+	}))
+	defer server.Close()
+
+	cacheDir := t.TempDir()
+	branch := NewLoader(cacheDir, server.URL+"/templates.tar.gz@main")
+	commit := NewLoader(cacheDir, server.URL+"/templates.tar.gz@"+archiveCommit)
+	for _, loader := range []*Loader{branch, commit} {
+		if err := loader.FetchTemplates(); err != nil {
+			t.Fatalf("first fetch: %v", err)
+		}
+	}
+
+	// Upstream moves on: the branch follows, the commit pin refuses.
+	content = tarGz(t, map[string]string{
+		"registry.yaml":     archiveFiles["registry.yaml"],
+		"svc/template.yaml": "name: svc\nversion: 1.1.0\n",
+	})
+	if err := branch.FetchTemplates(); err != nil {
+		t.Fatalf("expected a pinned branch to move to its new archive, got %v", err)
+	}
+	if manifests, err := branch.LoadAllManifests(); err != nil || len(manifests) != 1 || manifests[0].Version != "1.1.0" {
+		t.Fatalf("expected the updated branch archive, got %v (%v)", manifests, err)
+	}
+	if err := commit.FetchTemplates(); err == nil || !strings.Contains(err.Error(), "checksum") {
+		t.Fatalf("expected a changed archive for a pinned commit to fail, got %v", err)
+	}
+}
+
+func TestTemplateCommitOfArchiveCacheInsideGitRepo(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	tarball := tarGz(t, archiveFiles)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(tarball)
+	}))
+	defer server.Close()
+
+	// The cache lives in a repository of its own, like a dotfiles checkout.
+	home := t.TempDir()
+	gitRun(t, home, "init", "-q")
+	gitRun(t, home, "commit", "-q", "--allow-empty", "-m", "dotfiles")
+
+	repo := server.URL + "/templates.tar.gz"
+	if err := NewLoader(home, repo).FetchTemplates(); err != nil {
+		t.Fatal(err)
+	}
+	manifests := LoadRepoManifests(home, []string{repo})
+	if len(manifests) != 1 {
+		t.Fatalf("expected one manifest, got %d", len(manifests))
+	}
+	if got := TemplateCommit(manifests[0], home); got != archiveCommit {
+		t.Fatalf("expected the archive's commit %s, got %q", archiveCommit, got)
+	}
+	if _, _, err := CheckoutTemplateAt(manifests[0], home, archiveCommit, t.TempDir()); err == nil || !strings.Contains(err.Error(), "no git history") {
+		t.Fatalf("expected archive caches to have no history, got %v", err)
+	}
+}
@@ -0,0 +1,219 @@
+package template
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// envAllowlist names the environment variables templates may read with `env`.
+// Variables starting with envAllowPrefix are allowed too, so users can pass
+// values to templates on purpose. Everything else reads as "" so templates
+// cannot copy tokens, home paths or login names into generated files.
+var envAllowlist = map[string]bool{
+	"GIT_AUTHOR_NAME":     true,
+	"GIT_AUTHOR_EMAIL":    true,
+	"GIT_COMMITTER_NAME":  true,
+	"GIT_COMMITTER_EMAIL": true,
+}
+
+const envAllowPrefix = "INCUBATOR_"
+
+// templateFuncs is the function map shared by .tmpl files, `when` conditions
+// and path names.
+func templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		// Comparisons are by string value so answers of any type compare cleanly.
+		"ne": func(a, b interface{}) bool {
+			return fmt.Sprintf("%v", a) != fmt.Sprintf("%v", b)
+		},
+		"eq": eq,
+
+		"snake":  func(v interface{}) string { return strings.Join(lowerWords(v), "_") },
+		"kebab":  func(v interface{}) string { return strings.Join(lowerWords(v), "-") },
+		"camel":  camelCase,
+		"pascal": pascalCase,
+		"upper":  func(v interface{}) string { return strings.ToUpper(fmt.Sprint(v)) },
+		"lower":  func(v interface{}) string { return strings.ToLower(fmt.Sprint(v)) },
+		"title":  titleCase,
+
+		"replace": func(old, new string, v interface{}) string {
+			return strings.ReplaceAll(fmt.Sprint(v), old, new)
+		},
+		"trim":    func(v interface{}) string { return strings.TrimSpace(fmt.Sprint(v)) },
+		"default": defaultValue,
+		"join":    join,
+
+		"now":  time.Now,
+		"year": func() int { return time.Now().Year() },
+		"uuid": newUUID,
+		"env":  allowedEnv,
+
+		"toJson": toJSON,
+		"toYaml": toYAML,
+	}
+}
+
+// eq reports whether a equals any of the other arguments, like the built-in
+// eq, comparing by string value.
+func eq(a, b interface{}, rest ...interface{}) bool {
+	want := fmt.Sprintf("%v", a)
+	for _, other := range append([]interface{}{b}, rest...) {
+		if fmt.Sprintf("%v", other) == want {
+			return true
+		}
+	}
+	return false
+}
+
+// words splits a value into words at separators and case changes, so
+// "myHTTPServer", "my-http-server" and "My HTTP server" all give my/HTTP/server.
+func words(v interface{}) []string {
+	runes := []rune(fmt.Sprint(v))
+	var result []string
+	var current []rune
+	flush := func() {
+		if len(current) > 0 {
+			result = append(result, string(current))
+			current = nil
+		}
+	}
+
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			flush()
+			continue
+		}
+		if unicode.IsUpper(r) && len(current) > 0 {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				flush()
+			}
+		}
+		current = append(current, r)
+	}
+	flush()
+	return result
+}
+
+func lowerWords(v interface{}) []string {
+	parts := words(v)
+	for i, w := range parts {
+		parts[i] = strings.ToLower(w)
+	}
+	return parts
+}
+
+func capitalize(word string) string {
+	runes := []rune(strings.ToLower(word))
+	if len(runes) == 0 {
+		return ""
+	}
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
+
+func pascalCase(v interface{}) string {
+	var b strings.Builder
+	for _, w := range words(v) {
+		b.WriteString(capitalize(w))
+	}
+	return b.String()
+}
+
+func camelCase(v interface{}) string {
+	parts := words(v)
+	if len(parts) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString(strings.ToLower(parts[0]))
+	for _, w := range parts[1:] {
+		b.WriteString(capitalize(w))
+	}
+	return b.String()
+}
+
+func titleCase(v interface{}) string {
+	parts := words(v)
+	for i, w := range parts {
+		parts[i] = capitalize(w)
+	}
+	return strings.Join(parts, " ")
+}
+
+// defaultValue returns value unless it is missing, an empty string or an empty
+// list. A false answer is kept.
+func defaultValue(def, value interface{}) interface{} {
+	if value == nil {
+		return def
+	}
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map:
+		if rv.Len() == 0 {
+			return def
+		}
+	}
+	return value
+}
+
+// join joins a list answer, such as a multiselect, with sep.
+func join(sep string, list interface{}) string {
+	rv := reflect.ValueOf(list)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		if list == nil {
+			return ""
+		}
+		return fmt.Sprint(list)
+	}
+	parts := make([]string, rv.Len())
+	for i := range parts {
+		parts[i] = fmt.Sprint(rv.Index(i).Interface())
+	}
+	return strings.Join(parts, sep)
+}
+
+// newUUID returns a random version 4 UUID.
+func newUUID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+// allowedEnv reads an environment variable if templates are allowed to see it.
+func allowedEnv(name string) string {
+	if !envAllowlist[name] && !strings.HasPrefix(name, envAllowPrefix) {
+		return ""
+	}
+	return os.Getenv(name)
+}
+
+func toJSON(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func toYAML(v interface{}) (string, error) {
+	data, err := yaml.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(string(data), "\n"), nil
+}
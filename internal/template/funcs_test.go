@@ -0,0 +1,92 @@
+package template
+
+import (
+	"regexp"
+	"strconv"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestTemplateFuncs(t *testing.T) {
+	t.Setenv("GIT_AUTHOR_NAME", "Sloth")
+	t.Setenv("GH_TOKEN", "secret")
+	t.Setenv("HOME", "/home/sloth")
+
+	answers := map[string]interface{}{
+		"name":      "myHTTP-server v2",
+		"languages": []interface{}{"go", "rust"},
+		"empty":     "",
+		"enabled":   false,
+		"port":      8080,
+	}
+	tests := []struct {
+		tmpl string
+		want string
+	}{
+		{`{{snake .name}}`, "my_http_server_v2"},
+		{`{{kebab .name}}`, "my-http-server-v2"},
+		{`{{camel .name}}`, "myHttpServerV2"},
+		{`{{pascal .name}}`, "MyHttpServerV2"},
+		{`{{upper "abc"}}`, "ABC"},
+		{`{{title "hello_world"}}`, "Hello World"},
+		{`{{.name | replace "-" "."}}`, "myHTTP.server v2"},
+		{`{{trim "  x  "}}`, "x"},
+		{`{{.empty | default "fallback"}}`, "fallback"},
+		{`{{.missing | default "fallback"}}`, "fallback"},
+		{`{{.enabled | default true}}`, "false"},
+		{`{{join ", " .languages}}`, "go, rust"},
+		{`{{.languages | toJson}}`, `["go","rust"]`},
+		{`{{.languages | toYaml}}`, "- go\n- rust"},
+		{`{{env "GIT_AUTHOR_NAME"}}`, "Sloth"},
+		{`{{env "GH_TOKEN"}}`, ""},
+		{`{{env "HOME"}}`, ""},
+		{`{{if eq .port "8080"}}yes{{end}}`, "yes"},
+		{`{{year}}`, strconv.Itoa(time.Now().Year())},
+	}
+
+	renderer := NewRenderer(&TemplateManifest{Name: "test"}, answers)
+	for _, tt := range tests {
+		got, err := renderer.processTemplate("test", tt.tmpl)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.tmpl, err)
+		}
+		if got != tt.want {
+			t.Fatalf("%s: expected %q, got %q", tt.tmpl, tt.want, got)
+		}
+	}
+
+	id, err := renderer.processTemplate("test", "{{uuid}}")
+	if err != nil || !regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(id) {
+		t.Fatalf("expected a v4 uuid, got %q (%v)", id, err)
+	}
+}
+
+func TestTemplateFuncsInConditionsAndPaths(t *testing.T) {
+	answers := map[string]interface{}{"project_name": "My Service"}
+	if !EvaluateCondition(`{{if eq (kebab .project_name) "my-service"}}true{{end}}`, answers) {
+		t.Fatalf("expected conditions to use the template functions")
+	}
+	// eq takes several values like the built-in one and matches any of them.
+	licensed := map[string]interface{}{"license": "MIT"}
+	if !EvaluateCondition(`{{if eq .license "MIT" "Apache-2.0"}}true{{end}}`, licensed) {
+		t.Fatalf("expected eq to match any of its arguments")
+	}
+	if EvaluateCondition(`{{if eq .license "GPL-3.0" "Apache-2.0"}}true{{end}}`, licensed) {
+		t.Fatalf("expected eq without a matching argument to be false")
+	}
+
+	sourceFS := fstest.MapFS{
+		"cmd/{{.project_name | kebab}}/main.go": {Data: []byte("package main\n")},
+		"{{project_name}}.txt":                  {Data: []byte("legacy\n")},
+	}
+	result, err := NewRenderer(&TemplateManifest{Name: "test"}, answers).Render(sourceFS)
+	if err != nil {
+		t.Fatalf("Render returned error: %v", err)
+	}
+	for _, path := range []string{"cmd/my-service/main.go", "My Service.txt"} {
+		if _, ok := result.File(path); !ok {
+			t.Fatalf("expected %s in %v", path, result.Paths())
+		}
+	}
+}
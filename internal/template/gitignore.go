@@ -0,0 +1,61 @@
+package template
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/HungSloth/sloth-incubator/internal/gitignore"
+)
+
+// GitignorePath is where the manifest's gitignore fragments are written.
+const GitignorePath = ".gitignore"
+
+// renderGitignore merges the manifest's gitignore fragments into the
+// template's own .gitignore, creating it if needed.
+func (r *Renderer) renderGitignore(result *RenderResult, add func(RenderedFile)) error {
+	names, err := r.gitignoreFragments()
+	if err != nil || len(names) == 0 {
+		return err
+	}
+
+	file := RenderedFile{Path: GitignorePath, Mode: defaultFileMode}
+	for _, f := range result.Files {
+		if f.Path == GitignorePath {
+			file = f
+		}
+	}
+	content, err := gitignore.Compose(file.Content, names)
+	if err != nil {
+		return err
+	}
+	file.Content = content
+	add(file)
+	return nil
+}
+
+// gitignoreFragments resolves the manifest's `gitignore` entries. Entries may
+// be templates, e.g. "{{.language}}" or `{{join "," .languages}}`, that render
+// to one or more names; names from templates without a fragment are skipped.
+func (r *Renderer) gitignoreFragments() ([]string, error) {
+	var names []string
+	for _, entry := range r.manifest.Gitignore {
+		if !strings.Contains(entry, "{{") {
+			if !gitignore.Has(entry) {
+				return nil, fmt.Errorf("unknown gitignore fragment %q (available: %s)", entry, strings.Join(gitignore.Names(), ", "))
+			}
+			names = append(names, entry)
+			continue
+		}
+
+		rendered, err := r.processTemplate("gitignore", entry)
+		if err != nil {
+			return nil, fmt.Errorf("rendering gitignore entry %q: %w", entry, err)
+		}
+		for _, name := range strings.FieldsFunc(rendered, func(c rune) bool { return c == ',' || c == ' ' || c == '\n' }) {
+			if name = strings.ToLower(name); gitignore.Has(name) {
+				names = append(names, name)
+			}
+		}
+	}
+	return names, nil
+}
@@ -0,0 +1,157 @@
+package template
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// matchGlob reports whether a slash-separated path matches a doublestar glob:
+//
+//   - `*` matches any run of characters within a path segment
+//   - `?` matches a single character within a segment
+//   - `[abc]`, `[a-z]` and `[!a]` (or `[^a]`) match character classes
+//   - `**` as a whole segment matches zero or more segments
+//   - `{a,b}` matches either alternative and may nest
+//
+// `{{ ... }}` is taken literally so patterns can name templated paths such as
+// `{{project_name}}/**`. An error is returned for malformed patterns.
+func matchGlob(pattern, name string) (bool, error) {
+	alternatives, err := expandBraces(pattern)
+	if err != nil {
+		return false, err
+	}
+	nameSegments := strings.Split(name, "/")
+	for _, alt := range alternatives {
+		matched, err := matchSegments(strings.Split(alt, "/"), nameSegments)
+		if err != nil {
+			return false, fmt.Errorf("invalid glob %q: %w", pattern, err)
+		}
+		if matched {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func matchSegments(pattern, name []string) (bool, error) {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			// Collapse repeated ** and try every possible number of segments.
+			for len(pattern) > 0 && pattern[0] == "**" {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 0 {
+				return true, nil
+			}
+			for i := 0; i <= len(name); i++ {
+				matched, err := matchSegments(pattern, name[i:])
+				if err != nil || matched {
+					return matched, err
+				}
+			}
+			return false, nil
+		}
+
+		if len(name) == 0 {
+			return false, nil
+		}
+		matched, err := path.Match(segmentPattern(pattern[0]), name[0])
+		if err != nil || !matched {
+			return false, err
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0, nil
+}
+
+// segmentPattern converts one glob segment to path.Match syntax: `[!` becomes
+// `[^` and template actions are escaped so they only match themselves.
+func segmentPattern(segment string) string {
+	var b strings.Builder
+	for i := 0; i < len(segment); i++ {
+		if strings.HasPrefix(segment[i:], "{{") {
+			end := strings.Index(segment[i:], "}}")
+			if end >= 0 {
+				for _, c := range segment[i : i+end+2] {
+					if strings.ContainsRune(`*?[]\`, c) {
+						b.WriteByte('\\')
+					}
+					b.WriteRune(c)
+				}
+				i += end + 1
+				continue
+			}
+		}
+		if strings.HasPrefix(segment[i:], "[!") {
+			b.WriteString("[^")
+			i++
+			continue
+		}
+		b.WriteByte(segment[i])
+	}
+	return b.String()
+}
+
+// expandBraces turns `{a,b}` alternatives into separate patterns.
+func expandBraces(pattern string) ([]string, error) {
+	start := -1
+	for i := 0; i < len(pattern); i++ {
+		if strings.HasPrefix(pattern[i:], "{{") {
+			end := strings.Index(pattern[i:], "}}")
+			if end < 0 {
+				return nil, fmt.Errorf("invalid glob %q: unclosed {{", pattern)
+			}
+			i += end + 1
+			continue
+		}
+		if pattern[i] == '\\' {
+			i++
+			continue
+		}
+		if pattern[i] == '{' {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		return []string{pattern}, nil
+	}
+
+	// Find the matching close brace and the top-level commas inside it.
+	depth := 0
+	commas := []int{}
+	end := -1
+	for i := start; i < len(pattern) && end < 0; i++ {
+		switch pattern[i] {
+		case '\\':
+			i++
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				end = i
+			}
+		case ',':
+			if depth == 1 {
+				commas = append(commas, i)
+			}
+		}
+	}
+	if end < 0 {
+		return nil, fmt.Errorf("invalid glob %q: unclosed {", pattern)
+	}
+
+	prefix, suffix := pattern[:start], pattern[end+1:]
+	bounds := append(append([]int{start}, commas...), end)
+	var result []string
+	for i := 0; i+1 < len(bounds); i++ {
+		expanded, err := expandBraces(prefix + pattern[bounds[i]+1:bounds[i+1]] + suffix)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, expanded...)
+	}
+	return result, nil
+}
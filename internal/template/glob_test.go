@@ -0,0 +1,127 @@
+package template
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"README.md", "README.md", true},
+		{"README.md", "docs/README.md", false},
+		{"docs/*.md", "docs/index.md", true},
+		{"docs/*.md", "docs/guide/intro.md", false},
+		{"docs/*.md", "docs/logo.png", false},
+		{"docs/**", "docs", true},
+		{"docs/**", "docs/guide/intro.md", true},
+		{"docs/**", "documents/x.md", false},
+		{"**/*.go", "main.go", true},
+		{"**/*.go", "cmd/app/main.go", true},
+		{"src/**/test/*.go", "src/test/a.go", true},
+		{"src/**/test/*.go", "src/a/b/test/a.go", true},
+		{"src/**/test/*.go", "src/a/b/a.go", false},
+		{"**/node_modules/**", "web/node_modules/x/index.js", true},
+		{"*.{yml,yaml}", "ci.yaml", true},
+		{"*.{yml,yaml}", "ci.yml", true},
+		{"*.{yml,yaml}", "ci.json", false},
+		{"{cmd,internal/{a,b}}/**", "internal/b/x.go", true},
+		{"{cmd,internal/{a,b}}/**", "internal/c/x.go", false},
+		{"file?.txt", "file1.txt", true},
+		{"file?.txt", "file10.txt", false},
+		{"[a-c]*.txt", "b.txt", true},
+		{"[!a-c]*.txt", "b.txt", false},
+		{"[!a-c]*.txt", "d.txt", true},
+		{"[^a-c]*.txt", "d.txt", true},
+		{"{{project_name}}/**", "{{project_name}}/main.go", true},
+		{"{{.name | kebab}}/*", "{{.name | kebab}}/a", true},
+		{".incubator/preview/**", ".incubator/preview", true},
+		{".incubator/preview/**", ".incubator", false},
+	}
+	for _, tt := range tests {
+		got, err := matchGlob(tt.pattern, tt.path)
+		if err != nil {
+			t.Fatalf("matchGlob(%q, %q) returned error: %v", tt.pattern, tt.path, err)
+		}
+		if got != tt.want {
+			t.Fatalf("matchGlob(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"{a,b", "[a-", "x/{{y"} {
+		if _, err := matchGlob(bad, "x/y"); err == nil {
+			t.Fatalf("expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestFileRulesLaterRuleWins(t *testing.T) {
+	manifest := &TemplateManifest{
+		Name: "test",
+		Files: []FileRule{
+			{Src: "docs/**", When: "{{if .with_docs}}true{{end}}"},
+			{Src: "docs/README.md", Always: true},
+			{Src: "!**/*.bak"},
+			{Src: "!ci/**", When: "{{if .no_ci}}true{{end}}"},
+			{Src: "scripts/*.sh", Mode: 0755},
+		},
+	}
+	tests := []struct {
+		answers map[string]interface{}
+		path    string
+		want    bool
+	}{
+		{map[string]interface{}{"with_docs": false}, "docs/guide.md", false},
+		{map[string]interface{}{"with_docs": false}, "docs/README.md", true},
+		{map[string]interface{}{"with_docs": true}, "docs/guide.md", true},
+		{map[string]interface{}{"with_docs": true}, "docs/old.bak", false},
+		{map[string]interface{}{}, "ci/build.yaml", true},
+		{map[string]interface{}{"no_ci": true}, "ci/build.yaml", false},
+		{map[string]interface{}{}, "main.go", true},
+		{map[string]interface{}{}, "scripts/setup.sh", true},
+	}
+	for _, tt := range tests {
+		_, got, err := NewRenderer(manifest, tt.answers).includeRule(tt.path)
+		if err != nil {
+			t.Fatalf("%s with %v: unexpected error: %v", tt.path, tt.answers, err)
+		}
+		if got != tt.want {
+			t.Fatalf("%s with %v: expected include=%v, got %v", tt.path, tt.answers, tt.want, got)
+		}
+	}
+
+	rule, _, _ := NewRenderer(manifest, nil).includeRule("scripts/setup.sh")
+	if rule == nil || rule.Mode != 0755 {
+		t.Fatalf("expected scripts/setup.sh to be included by the mode rule, got %+v", rule)
+	}
+}
+
+func TestBuiltinTemplateSkipsPreviewDirectoryWhenDisabled(t *testing.T) {
+	sourceFS, err := GetEmbeddedEmptyTemplate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	answers := map[string]interface{}{"project_name": "demo", "enable_preview": false}
+	result, err := NewRenderer(GetBuiltinManifest(), answers).Render(sourceFS)
+	if err != nil {
+		t.Fatalf("Render returned error: %v", err)
+	}
+	for _, p := range append(result.Paths(), result.Dirs...) {
+		if p == ".incubator" || strings.HasPrefix(p, ".incubator/") {
+			t.Fatalf("expected nothing under .incubator when preview is disabled, got %s", p)
+		}
+	}
+
+	answers["enable_preview"] = true
+	result, err = NewRenderer(GetBuiltinManifest(), answers).Render(sourceFS)
+	if err != nil {
+		t.Fatalf("Render returned error: %v", err)
+	}
+	entrypoint, ok := result.File(".incubator/preview/entrypoint.sh")
+	if !ok || entrypoint.Mode != 0755 {
+		t.Fatalf("expected an executable entrypoint.sh, got %+v", entrypoint)
+	}
+}
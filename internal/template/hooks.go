@@ -1,43 +1,107 @@
 package template
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
 	"strings"
+
+	"gopkg.in/yaml.v3"
 )
 
-// RunPostCreateHook executes the post-create hook if one is defined
-func RunPostCreateHook(manifest *TemplateManifest, projectDir string, answers map[string]interface{}) error {
-	if manifest.Hooks.PostCreate == "" {
-		return nil
+// HasPostCreateHook reports whether the manifest declares a post-create hook.
+func HasPostCreateHook(manifest *TemplateManifest) bool {
+	return manifest != nil && strings.TrimSpace(manifest.Hooks.PostCreate) != ""
+}
+
+// HookNeedsConsent reports whether running the manifest's hook requires the user to
+// trust it first. Built-in templates ship with the binary and are always trusted.
+func HookNeedsConsent(manifest *TemplateManifest) bool {
+	return HasPostCreateHook(manifest) && !manifest.IsBuiltin
+}
+
+// ReadHookScript returns the source of the post-create hook from the template
+// filesystem, looking for both the plain file and a .tmpl variant.
+func ReadHookScript(manifest *TemplateManifest, sourceFS fs.FS) (string, error) {
+	if !HasPostCreateHook(manifest) {
+		return "", nil
 	}
+	hookPath, err := cleanHookPath(manifest.Hooks.PostCreate)
+	if err != nil {
+		return "", err
+	}
+	for _, candidate := range []string{hookPath, hookPath + ".tmpl"} {
+		data, err := fs.ReadFile(sourceFS, candidate)
+		if err == nil {
+			return string(data), nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", fmt.Errorf("reading hook %s: %w", candidate, err)
+		}
+	}
+	return "", fmt.Errorf("hook script %s not found in template", hookPath)
+}
 
-	hookPath := filepath.Join(projectDir, manifest.Hooks.PostCreate)
+// PostCreateHookFile returns the post-create hook as rendered in result. It is
+// false when the template's rules leave the hook out.
+func PostCreateHookFile(manifest *TemplateManifest, result RenderResult) (RenderedFile, bool, error) {
+	if !HasPostCreateHook(manifest) {
+		return RenderedFile{}, false, nil
+	}
+	hookPath, err := cleanHookPath(manifest.Hooks.PostCreate)
+	if err != nil {
+		return RenderedFile{}, false, err
+	}
+	file, ok := result.File(hookPath)
+	return file, ok, nil
+}
 
-	// Check if hook file exists
-	if _, err := os.Stat(hookPath); os.IsNotExist(err) {
-		return nil // No hook file, skip silently
+// RunPostCreateHook executes the rendered hook script in projectDir, streaming
+// its combined output to out. The script is run from a temporary file rather
+// than from the project, so a file the user already had at the hook's path is
+// never made executable or run.
+func RunPostCreateHook(manifest *TemplateManifest, script []byte, projectDir string, answers map[string]interface{}, out io.Writer) error {
+	if !HasPostCreateHook(manifest) {
+		return nil
 	}
 
-	// Make the hook executable
-	if err := os.Chmod(hookPath, 0755); err != nil {
-		return fmt.Errorf("making hook executable: %w", err)
+	relPath, err := cleanHookPath(manifest.Hooks.PostCreate)
+	if err != nil {
+		return err
+	}
+	tmpDir, err := os.MkdirTemp("", "incubator-hook-*")
+	if err != nil {
+		return fmt.Errorf("creating hook directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	hookPath := filepath.Join(tmpDir, path.Base(relPath))
+	if err := os.WriteFile(hookPath, script, 0700); err != nil {
+		return fmt.Errorf("writing hook: %w", err)
 	}
 
 	// Build environment variables from answers
 	env := os.Environ()
 	for key, val := range answers {
 		envKey := "INCUBATOR_" + strings.ToUpper(key)
-		env = append(env, fmt.Sprintf("%s=%v", envKey, val))
+		env = append(env, fmt.Sprintf("%s=%s", envKey, FormatAnswer(val)))
+	}
+
+	if out == nil {
+		out = os.Stdout
 	}
 
 	cmd := exec.Command(hookPath)
 	cmd.Dir = projectDir
 	cmd.Env = env
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	cmd.Stdout = out
+	cmd.Stderr = out
 
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("post-create hook failed: %w", err)
@@ -45,3 +109,102 @@ func RunPostCreateHook(manifest *TemplateManifest, projectDir string, answers ma
 
 	return nil
 }
+
+// cleanHookPath rejects hook paths that would escape the project directory.
+func cleanHookPath(hookPath string) (string, error) {
+	cleaned := path.Clean(filepath.ToSlash(strings.TrimSpace(hookPath)))
+	if !fs.ValidPath(cleaned) || cleaned == "." {
+		return "", fmt.Errorf("invalid hook path %q: must be relative to the project", hookPath)
+	}
+	return cleaned, nil
+}
+
+// HookHash fingerprints a hook by template identity and script contents.
+func HookHash(manifest *TemplateManifest, script string) string {
+	sum := sha256.Sum256([]byte(manifest.QualifiedName() + "\x00" + manifest.Version + "\x00" + script))
+	return hex.EncodeToString(sum[:])
+}
+
+// TrustedHook records a hook the user has agreed to run.
+type TrustedHook struct {
+	Template string `yaml:"template"`
+	Version  string `yaml:"version"`
+	SHA256   string `yaml:"sha256"`
+}
+
+// HookTrustStore persists trusted hook hashes in the incubator config directory.
+type HookTrustStore struct {
+	dir string
+}
+
+// NewHookTrustStore creates a trust store rooted at dir.
+func NewHookTrustStore(dir string) *HookTrustStore {
+	return &HookTrustStore{dir: dir}
+}
+
+// Path returns the path to trusted_hooks.yaml.
+func (s *HookTrustStore) Path() string {
+	return filepath.Join(s.dir, "trusted_hooks.yaml")
+}
+
+func (s *HookTrustStore) load() ([]TrustedHook, error) {
+	data, err := os.ReadFile(s.Path())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading trusted hooks: %w", err)
+	}
+
+	var hooks []TrustedHook
+	if err := yaml.Unmarshal(data, &hooks); err != nil {
+		return nil, fmt.Errorf("parsing trusted hooks: %w", err)
+	}
+	return hooks, nil
+}
+
+// IsTrusted reports whether this exact hook script was previously trusted.
+func (s *HookTrustStore) IsTrusted(manifest *TemplateManifest, script string) bool {
+	hooks, err := s.load()
+	if err != nil {
+		return false
+	}
+	hash := HookHash(manifest, script)
+	for _, h := range hooks {
+		if h.Template == manifest.QualifiedName() && h.Version == manifest.Version && h.SHA256 == hash {
+			return true
+		}
+	}
+	return false
+}
+
+// Trust records the hook script as trusted, replacing any earlier entry for the
+// same template and version.
+func (s *HookTrustStore) Trust(manifest *TemplateManifest, script string) error {
+	hooks, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	filtered := make([]TrustedHook, 0, len(hooks)+1)
+	for _, h := range hooks {
+		if h.Template == manifest.QualifiedName() && h.Version == manifest.Version {
+			continue
+		}
+		filtered = append(filtered, h)
+	}
+	filtered = append(filtered, TrustedHook{
+		Template: manifest.QualifiedName(),
+		Version:  manifest.Version,
+		SHA256:   HookHash(manifest, script),
+	})
+
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return fmt.Errorf("creating config directory: %w", err)
+	}
+	data, err := yaml.Marshal(filtered)
+	if err != nil {
+		return fmt.Errorf("marshaling trusted hooks: %w", err)
+	}
+	return os.WriteFile(s.Path(), data, 0644)
+}
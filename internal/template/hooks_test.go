@@ -0,0 +1,67 @@
+package template
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestHookTrustStoreRemembersExactScript(t *testing.T) {
+	store := NewHookTrustStore(t.TempDir())
+	manifest := &TemplateManifest{Name: "demo", Version: "1.0.0"}
+
+	if store.IsTrusted(manifest, "echo hi") {
+		t.Fatalf("expected hook to be untrusted before Trust")
+	}
+	if err := store.Trust(manifest, "echo hi"); err != nil {
+		t.Fatalf("Trust returned error: %v", err)
+	}
+	if !store.IsTrusted(manifest, "echo hi") {
+		t.Fatalf("expected hook to be trusted after Trust")
+	}
+	if store.IsTrusted(manifest, "echo changed") {
+		t.Fatalf("expected a changed script to need consent again")
+	}
+
+	bumped := &TemplateManifest{Name: "demo", Version: "1.1.0"}
+	if store.IsTrusted(bumped, "echo hi") {
+		t.Fatalf("expected a new template version to need consent again")
+	}
+}
+
+func TestReadHookScriptFindsTmplVariant(t *testing.T) {
+	manifest := &TemplateManifest{Hooks: HooksConfig{PostCreate: "scripts/setup.sh"}}
+	sourceFS := fstest.MapFS{
+		"scripts/setup.sh.tmpl": {Data: []byte("echo {{.project_name}}\n")},
+	}
+
+	script, err := ReadHookScript(manifest, sourceFS)
+	if err != nil {
+		t.Fatalf("ReadHookScript returned error: %v", err)
+	}
+	if script != "echo {{.project_name}}\n" {
+		t.Fatalf("unexpected script %q", script)
+	}
+
+	manifest.Hooks.PostCreate = "../outside.sh"
+	if _, err := ReadHookScript(manifest, sourceFS); err == nil {
+		t.Fatalf("expected hook paths outside the project to be rejected")
+	}
+}
+
+func TestRunPostCreateHookStreamsOutput(t *testing.T) {
+	projectDir := t.TempDir()
+	script := "#!/bin/sh\necho \"hello $INCUBATOR_PROJECT_NAME from $(basename \"$PWD\")\"\n"
+
+	manifest := &TemplateManifest{Hooks: HooksConfig{PostCreate: "setup.sh"}}
+	var out bytes.Buffer
+	answers := map[string]interface{}{"project_name": "demo"}
+	if err := RunPostCreateHook(manifest, []byte(script), projectDir, answers, &out); err != nil {
+		t.Fatalf("RunPostCreateHook returned error: %v", err)
+	}
+	if !strings.Contains(out.String(), "hello demo from "+filepath.Base(projectDir)) {
+		t.Fatalf("expected hook output to be streamed, got %q", out.String())
+	}
+}
@@ -0,0 +1,78 @@
+package template
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/HungSloth/sloth-incubator/internal/license"
+)
+
+// LicensePath is where the license chosen in the `license` answer is written.
+const LicensePath = "LICENSE"
+
+// LicenseOptions lists the values a `license` prompt can offer.
+func LicenseOptions() []PromptOption {
+	var options []PromptOption
+	for _, id := range license.Options() {
+		options = append(options, PromptOption{Label: id, Value: id})
+	}
+	return options
+}
+
+// renderLicense writes LICENSE for the `license` answer unless the template
+// ships its own, and adds SPDX headers to source files if the manifest asks.
+func (r *Renderer) renderLicense(result *RenderResult, add func(RenderedFile)) error {
+	id, _ := r.answers["license"].(string)
+	lic, ok := license.Lookup(id)
+	if !ok {
+		return nil
+	}
+
+	if !r.manifest.License.Skip && !hasLicenseFile(result.Files) {
+		text, err := lic.Text(r.now().Year(), r.licenseHolder())
+		if err != nil {
+			return err
+		}
+		add(RenderedFile{Path: LicensePath, Content: text, Mode: defaultFileMode})
+	}
+
+	if r.manifest.License.Headers != "" && r.evaluateCondition(r.manifest.License.Headers) {
+		for i, f := range result.Files {
+			if f.Source != "" && !f.Binary {
+				result.Files[i].Content = lic.AddHeader(f.Path, f.Content)
+			}
+		}
+	}
+	return nil
+}
+
+// licenseHolder names the copyright holder: the `author` answer, then the
+// renderer's LicenseHolder, then the project's authors.
+func (r *Renderer) licenseHolder() string {
+	if author, _ := r.answers["author"].(string); strings.TrimSpace(author) != "" {
+		return author
+	}
+	if r.LicenseHolder != "" {
+		return r.LicenseHolder
+	}
+	if name, _ := r.answers["project_name"].(string); name != "" {
+		return fmt.Sprintf("The %s Authors", name)
+	}
+	return "The Authors"
+}
+
+// hasLicenseFile reports whether the template already rendered a license at the
+// project root, such as LICENSE.md or COPYING.
+func hasLicenseFile(files []RenderedFile) bool {
+	for _, f := range files {
+		if strings.Contains(f.Path, "/") {
+			continue
+		}
+		name := strings.ToUpper(strings.TrimSuffix(f.Path, path.Ext(f.Path)))
+		if name == "LICENSE" || name == "LICENCE" || name == "COPYING" {
+			return true
+		}
+	}
+	return false
+}
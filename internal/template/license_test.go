@@ -0,0 +1,50 @@
+package template
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestRenderWritesLicenseAndHeaders(t *testing.T) {
+	manifest := &TemplateManifest{Name: "test", License: LicenseConfig{Headers: "{{.spdx_headers}}"}}
+	sourceFS := fstest.MapFS{
+		"main.go":   {Data: []byte("package main\n")},
+		"README.md": {Data: []byte("# demo\n")},
+	}
+	answers := map[string]interface{}{"project_name": "demo", "license": "Apache-2.0", "spdx_headers": true}
+	renderer := NewRenderer(manifest, answers)
+	renderer.LicenseHolder = "octocat"
+
+	result, err := renderer.Render(sourceFS)
+	if err != nil {
+		t.Fatalf("Render returned error: %v", err)
+	}
+	file, ok := result.File(LicensePath)
+	if !ok {
+		t.Fatalf("expected a LICENSE file, got %v", result.Paths())
+	}
+	want := "Copyright " + strconv.Itoa(time.Now().Year()) + " octocat"
+	if !strings.Contains(string(file.Content), want) {
+		t.Fatalf("expected LICENSE to contain %q", want)
+	}
+	main, _ := result.File("main.go")
+	if !strings.HasPrefix(string(main.Content), "// SPDX-License-Identifier: Apache-2.0\n") {
+		t.Fatalf("expected an SPDX header in main.go, got %q", main.Content)
+	}
+
+	answers["license"] = "none"
+	result, _ = NewRenderer(manifest, answers).Render(sourceFS)
+	if _, ok := result.File(LicensePath); ok {
+		t.Fatalf("expected no LICENSE for license none")
+	}
+
+	answers["license"] = "MIT"
+	sourceFS["LICENSE.md"] = &fstest.MapFile{Data: []byte("custom\n")}
+	result, _ = NewRenderer(manifest, answers).Render(sourceFS)
+	if _, ok := result.File(LicensePath); ok {
+		t.Fatalf("expected the template's own LICENSE.md to be kept instead")
+	}
+}
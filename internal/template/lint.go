@@ -0,0 +1,467 @@
+package template
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/HungSloth/sloth-incubator/internal/gitignore"
+	"gopkg.in/yaml.v3"
+)
+
+// LintSeverity says whether a lint issue breaks the template or only looks wrong.
+type LintSeverity string
+
+const (
+	LintError   LintSeverity = "error"
+	LintWarning LintSeverity = "warning"
+)
+
+// LintIssue is a single problem found in a template.
+type LintIssue struct {
+	Severity LintSeverity `json:"severity"`
+	// File is relative to the template directory.
+	File    string `json:"file"`
+	Line    int    `json:"line,omitempty"`
+	Message string `json:"message"`
+}
+
+func (i LintIssue) String() string {
+	location := i.File
+	if i.Line > 0 {
+		location += ":" + strconv.Itoa(i.Line)
+	}
+	return fmt.Sprintf("%s: %s: %s", location, i.Severity, i.Message)
+}
+
+// HasLintErrors reports whether any issue is an error rather than a warning.
+func HasLintErrors(issues []LintIssue) bool {
+	for _, issue := range issues {
+		if issue.Severity == LintError {
+			return true
+		}
+	}
+	return false
+}
+
+// TemplateFilesDir returns the directory holding a template's files: files/ for
+// local templates, the template directory itself for templates in a repo.
+func TemplateFilesDir(templateDir string) string {
+	filesDir := filepath.Join(templateDir, "files")
+	if stat, err := os.Stat(filesDir); err == nil && stat.IsDir() {
+		return filesDir
+	}
+	return templateDir
+}
+
+// LintTemplate checks the template in dir without rendering it. known holds the
+// templates `extends` and `include` may refer to, and cacheDir is where remote
+// ones are cached. The returned error is only set when dir has no readable
+// template.yaml; everything else is reported as an issue.
+func LintTemplate(dir string, known []*TemplateManifest, cacheDir string) ([]LintIssue, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "template.yaml"))
+	if err != nil {
+		return nil, fmt.Errorf("reading template.yaml: %w", err)
+	}
+	filesDir := TemplateFilesDir(dir)
+	l := &linter{filesPrefix: relativePrefix(dir, filesDir)}
+
+	manifest := l.parseManifest(data)
+	if manifest == nil {
+		return l.sorted(), nil
+	}
+	l.checkPrompts(manifest)
+
+	// Prompts and files from extended templates count as this template's own.
+	ownFS := TemplateFilesFS(dir)
+	composed, sourceFS, err := composeTemplateDir(manifest, ownFS, known, cacheDir)
+	if err != nil {
+		l.add(LintError, "template.yaml", 0, "%v", err)
+		composed, sourceFS = manifest, ownFS
+	}
+	for _, p := range composed.Prompts {
+		l.defined = append(l.defined, p.Name)
+	}
+
+	l.checkExpressions(manifest)
+	paths, err := l.checkFiles(ownFS)
+	if err != nil {
+		return nil, err
+	}
+	if composed != manifest {
+		if paths, err = templatePaths(sourceFS); err != nil {
+			return nil, err
+		}
+	}
+	l.checkRules(manifest, paths)
+	l.checkHook(manifest, sourceFS)
+	return l.sorted(), nil
+}
+
+type linter struct {
+	issues []LintIssue
+	// defined are the prompt names templates may reference.
+	defined []string
+	// filesPrefix is prepended to template file paths in issues.
+	filesPrefix string
+}
+
+func (l *linter) add(severity LintSeverity, file string, line int, format string, args ...interface{}) {
+	l.issues = append(l.issues, LintIssue{Severity: severity, File: file, Line: line, Message: fmt.Sprintf(format, args...)})
+}
+
+func (l *linter) sorted() []LintIssue {
+	sort.SliceStable(l.issues, func(i, j int) bool {
+		if l.issues[i].File != l.issues[j].File {
+			return l.issues[i].File < l.issues[j].File
+		}
+		return l.issues[i].Line < l.issues[j].Line
+	})
+	return l.issues
+}
+
+var (
+	yamlLinePattern  = regexp.MustCompile(`^line (\d+): (.*)$`)
+	yamlErrorPattern = regexp.MustCompile(`line (\d+)`)
+)
+
+// parseManifest decodes template.yaml strictly, reporting unknown keys. If the
+// strict decode fails, a lenient decode is used so other checks still run.
+func (l *linter) parseManifest(data []byte) *TemplateManifest {
+	var manifest TemplateManifest
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	err := dec.Decode(&manifest)
+	if err == nil || errors.Is(err, io.EOF) {
+		manifest.ApplyDefaults()
+		return &manifest
+	}
+
+	var typeErr *yaml.TypeError
+	if !errors.As(err, &typeErr) {
+		l.add(LintError, "template.yaml", yamlErrorLine(err), "%v", err)
+		return nil
+	}
+	for _, msg := range typeErr.Errors {
+		line := 0
+		if m := yamlLinePattern.FindStringSubmatch(msg); m != nil {
+			line, _ = strconv.Atoi(m[1])
+			msg = m[2]
+		}
+		l.add(LintError, "template.yaml", line, "%s", msg)
+	}
+
+	// Values of the wrong type are left zero; everything else is still checked.
+	manifest = TemplateManifest{}
+	_ = yaml.Unmarshal(data, &manifest)
+	manifest.ApplyDefaults()
+	return &manifest
+}
+
+// yamlErrorLine pulls the line number out of a YAML syntax error.
+func yamlErrorLine(err error) int {
+	if m := yamlErrorPattern.FindStringSubmatch(err.Error()); m != nil {
+		line, _ := strconv.Atoi(m[1])
+		return line
+	}
+	return 0
+}
+
+func (l *linter) checkPrompts(m *TemplateManifest) {
+	seen := make(map[string]bool)
+	for i, p := range m.Prompts {
+		label := p.Name
+		if label == "" {
+			label = fmt.Sprintf("#%d", i+1)
+			l.add(LintError, "template.yaml", 0, "prompt %s has no name", label)
+		} else if seen[p.Name] {
+			l.add(LintError, "template.yaml", 0, "prompt %q is defined more than once", p.Name)
+		}
+		seen[p.Name] = true
+
+		if !p.Type.Valid() {
+			l.add(LintError, "template.yaml", 0, "prompt %s has unknown type %q", label, p.Type)
+			continue
+		}
+		if p.Type.HasOptions() && len(p.Options) == 0 {
+			l.add(LintError, "template.yaml", 0, "prompt %s is a %s without options", label, p.Type)
+		}
+		if p.Default != nil && p.Type.HasOptions() {
+			values, err := coerceStringList(p.Default)
+			if p.Type == PromptSelect {
+				values, err = []string{FormatAnswer(p.Default)}, nil
+			}
+			if err != nil {
+				l.add(LintError, "template.yaml", 0, "prompt %s default: %v", label, err)
+			}
+			for _, v := range values {
+				if !hasOptionValue(p.Options, v) {
+					l.add(LintError, "template.yaml", 0, "prompt %s default %q is not one of its options", label, v)
+				}
+			}
+		}
+		if rule := strings.TrimSpace(p.Validate); rule != "" {
+			if _, named := namedValidators[rule]; !named {
+				if _, err := regexp.Compile(rule); err != nil {
+					l.add(LintError, "template.yaml", 0, "prompt %s has an invalid validate pattern: %v", label, err)
+				}
+			}
+		}
+		if p.Min != nil && p.Max != nil && *p.Min > *p.Max {
+			l.add(LintError, "template.yaml", 0, "prompt %s has min greater than max", label)
+		}
+	}
+}
+
+// checkExpressions compiles every template expression in the manifest.
+func (l *linter) checkExpressions(m *TemplateManifest) {
+	for _, p := range m.Prompts {
+		l.checkTemplate("template.yaml", "prompt "+p.Name+" when", p.When)
+	}
+	for i, rule := range m.Files {
+		pattern, _ := rule.Pattern()
+		if _, err := expandBraces(pattern); err != nil {
+			l.add(LintError, "template.yaml", 0, "file rule %d: %v", i+1, err)
+		}
+		l.checkTemplate("template.yaml", fmt.Sprintf("file rule %d when", i+1), rule.When)
+	}
+	for i, cond := range m.Devcontainer.Features.When {
+		l.checkTemplate("template.yaml", fmt.Sprintf("devcontainer feature condition %d", i+1), cond.If)
+	}
+	l.checkTemplate("template.yaml", "license headers", m.License.Headers)
+	for _, entry := range m.Gitignore {
+		if strings.Contains(entry, "{{") {
+			l.checkTemplate("template.yaml", "gitignore entry", entry)
+		} else if !gitignore.Has(strings.TrimSpace(entry)) {
+			l.add(LintError, "template.yaml", 0, "unknown gitignore fragment %q", entry)
+		}
+	}
+}
+
+// checkFiles compiles every .tmpl file and templated path in the template's own
+// files and returns their paths.
+func (l *linter) checkFiles(sourceFS fs.FS) ([]string, error) {
+	var paths []string
+	// Paths are read the way the renderer reads them, with every prompt answered.
+	answers := make(map[string]interface{}, len(l.defined))
+	for _, name := range l.defined {
+		answers[name] = nil
+	}
+	renderer := NewRenderer(&TemplateManifest{}, answers)
+	err := fs.WalkDir(sourceFS, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == "." {
+			return nil
+		}
+		file := l.filesPrefix + p
+		if strings.Contains(p, "{{") {
+			for _, segment := range splitPathSegments(p) {
+				if strings.Contains(segment, "{{") {
+					l.checkTemplate(file, "path", renderer.legacyPathSegment(segment))
+				}
+			}
+		}
+		if d.IsDir() {
+			return nil
+		}
+		paths = append(paths, p)
+		if !strings.HasSuffix(p, ".tmpl") {
+			return nil
+		}
+		content, err := fs.ReadFile(sourceFS, p)
+		if err != nil {
+			return err
+		}
+		if !IsBinary(content) {
+			l.checkTemplate(file, "", string(content))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading template files: %w", err)
+	}
+	return paths, nil
+}
+
+// checkRules warns about file rules that match none of the template's files.
+func (l *linter) checkRules(m *TemplateManifest, paths []string) {
+	for i, rule := range m.Files {
+		pattern, _ := rule.Pattern()
+		matched := false
+		for _, p := range paths {
+			ok, err := matchGlob(pattern, p)
+			if err == nil && !ok && strings.HasSuffix(p, ".tmpl") {
+				ok, err = matchGlob(pattern, strings.TrimSuffix(p, ".tmpl"))
+			}
+			if err != nil {
+				break
+			}
+			if ok {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			l.add(LintWarning, "template.yaml", 0, "file rule %d (%s) matches no files", i+1, rule.Src)
+		}
+	}
+}
+
+func (l *linter) checkHook(m *TemplateManifest, sourceFS fs.FS) {
+	if !HasPostCreateHook(m) {
+		return
+	}
+	if _, err := ReadHookScript(m, sourceFS); err != nil {
+		l.add(LintError, "template.yaml", 0, "hooks.post_create: %v", err)
+	}
+}
+
+// checkTemplate parses a template expression with the renderer's functions and
+// warns about answers it reads that no prompt defines.
+func (l *linter) checkTemplate(file, what, text string) {
+	if strings.TrimSpace(text) == "" {
+		return
+	}
+	prefix := ""
+	if what != "" {
+		prefix = what + ": "
+	}
+	tmpl, err := template.New(file).Funcs(templateFuncs()).Parse(text)
+	if err != nil {
+		l.add(LintError, file, templateErrorLine(err), "%s%v", prefix, err)
+		return
+	}
+
+	refs := make(map[string]int)
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil && t.Tree.Root != nil {
+			collectFieldRefs(t.Tree, t.Tree.Root, true, refs)
+		}
+	}
+	names := make([]string, 0, len(refs))
+	for name := range refs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !containsString(l.defined, name) {
+			l.add(LintWarning, file, refs[name], "%sreferences .%s, which no prompt defines", prefix, name)
+		}
+	}
+}
+
+// collectFieldRefs records the top-level answer names a template reads, with
+// the line of their first use. rootDot is false inside range and with bodies,
+// where dot no longer holds the answers.
+func collectFieldRefs(tree *parse.Tree, node parse.Node, rootDot bool, refs map[string]int) {
+	record := func(n parse.Node, name string) {
+		if _, ok := refs[name]; !ok {
+			line, _ := tree.ErrorContext(n)
+			refs[name] = lineFromContext(line)
+		}
+	}
+
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			collectFieldRefs(tree, child, rootDot, refs)
+		}
+	case *parse.ActionNode:
+		collectFieldRefs(tree, n.Pipe, rootDot, refs)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			collectFieldRefs(tree, cmd, rootDot, refs)
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			collectFieldRefs(tree, arg, rootDot, refs)
+		}
+	case *parse.ChainNode:
+		collectFieldRefs(tree, n.Node, rootDot, refs)
+	case *parse.FieldNode:
+		if rootDot && len(n.Ident) > 0 {
+			record(n, n.Ident[0])
+		}
+	case *parse.VariableNode:
+		if len(n.Ident) > 1 && n.Ident[0] == "$" {
+			record(n, n.Ident[1])
+		}
+	case *parse.IfNode:
+		collectFieldRefs(tree, n.Pipe, rootDot, refs)
+		collectFieldRefs(tree, n.List, rootDot, refs)
+		collectFieldRefs(tree, n.ElseList, rootDot, refs)
+	case *parse.RangeNode:
+		collectFieldRefs(tree, n.Pipe, rootDot, refs)
+		collectFieldRefs(tree, n.List, false, refs)
+		collectFieldRefs(tree, n.ElseList, rootDot, refs)
+	case *parse.WithNode:
+		collectFieldRefs(tree, n.Pipe, rootDot, refs)
+		collectFieldRefs(tree, n.List, false, refs)
+		collectFieldRefs(tree, n.ElseList, rootDot, refs)
+	case *parse.TemplateNode:
+		collectFieldRefs(tree, n.Pipe, rootDot, refs)
+	}
+}
+
+var templateErrorPattern = regexp.MustCompile(`:(\d+):`)
+
+// templateErrorLine pulls the line number out of a text/template parse error.
+func templateErrorLine(err error) int {
+	if m := templateErrorPattern.FindStringSubmatch(err.Error()); m != nil {
+		line, _ := strconv.Atoi(m[1])
+		return line
+	}
+	return 0
+}
+
+// lineFromContext extracts the line from a parse.Tree location like "name:3:10".
+func lineFromContext(location string) int {
+	parts := strings.Split(location, ":")
+	if len(parts) < 2 {
+		return 0
+	}
+	line, _ := strconv.Atoi(parts[len(parts)-2])
+	return line
+}
+
+// templatePaths lists every file in a template filesystem.
+func templatePaths(sourceFS fs.FS) ([]string, error) {
+	var paths []string
+	err := fs.WalkDir(sourceFS, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			paths = append(paths, p)
+		}
+		return nil
+	})
+	return paths, err
+}
+
+// relativePrefix returns filesDir relative to dir as a path prefix, e.g. "files/".
+func relativePrefix(dir, filesDir string) string {
+	rel, err := filepath.Rel(dir, filesDir)
+	if err != nil || rel == "." {
+		return ""
+	}
+	return filepath.ToSlash(rel) + "/"
+}
@@ -0,0 +1,141 @@
+package template
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTemplateDir(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for relPath, content := range files {
+		fullPath := filepath.Join(dir, relPath)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLintTemplateReportsProblems(t *testing.T) {
+	dir := writeTemplateDir(t, map[string]string{
+		"template.yaml": `name: broken
+version: 1.0.0
+colour: blue
+prompts:
+  - name: project_name
+  - name: project_name
+  - name: kind
+    type: dropdown
+  - name: language
+    type: select
+    options: [go, rust]
+    default: python
+  - name: tools
+    type: multiselect
+    options: [make, just]
+    default: [make, bazel]
+files:
+  - src: "docs/**"
+    when: "{{if .with_docs}}true"
+  - src: "*.py"
+hooks:
+  post_create: scripts/setup.sh
+`,
+		"files/README.md.tmpl":              "# {{.project_name}} by {{.author}}\n{{range .tools}}- {{.}}{{end}}\n",
+		"files/main.go.tmpl":                "package main\n\n{{if .language}\n",
+		"files/{{.language | pascal}}/x.go": "package x\n",
+		"files/docs/index.md":               "docs\n",
+	})
+
+	issues, err := LintTemplate(dir, nil, t.TempDir())
+	if err != nil {
+		t.Fatalf("LintTemplate returned error: %v", err)
+	}
+	var lines []string
+	for _, issue := range issues {
+		lines = append(lines, issue.String())
+	}
+	report := strings.Join(lines, "\n")
+
+	for _, want := range []string{
+		"template.yaml:3: error: field colour not found",
+		`prompt "project_name" is defined more than once`,
+		`prompt kind has unknown type "dropdown"`,
+		`prompt language default "python" is not one of its options`,
+		`prompt tools default "bazel" is not one of its options`,
+		"error: file rule 1 when:",
+		"warning: file rule 2 (*.py) matches no files",
+		"hooks.post_create: hook script scripts/setup.sh not found",
+		"files/README.md.tmpl:1: warning: references .author, which no prompt defines",
+		"files/main.go.tmpl:3: error:",
+	} {
+		if !strings.Contains(report, want) {
+			t.Fatalf("expected %q in lint report:\n%s", want, report)
+		}
+	}
+	// Inside range, dot is the element rather than the answers.
+	if strings.Contains(report, "references .tools") || strings.Contains(report, ".language, which") {
+		t.Fatalf("unexpected undefined variable warning:\n%s", report)
+	}
+	if !HasLintErrors(issues) {
+		t.Fatal("expected lint errors")
+	}
+}
+
+func TestLintTemplateAcceptsCleanTemplate(t *testing.T) {
+	dir := writeTemplateDir(t, map[string]string{
+		"template.yaml": `name: clean
+version: 1.0.0
+extends: empty
+prompts:
+  - name: with_ci
+    type: confirm
+files:
+  - src: ".github/**"
+    when: "{{.with_ci}}"
+hooks:
+  post_create: setup.sh
+`,
+		"files/.github/workflows/ci.yml.tmpl": "name: {{.project_name}}\n",
+		"files/setup.sh.tmpl":                 "echo {{$.project_name}}\n",
+	})
+
+	issues, err := LintTemplate(dir, []*TemplateManifest{GetBuiltinManifest()}, t.TempDir())
+	if err != nil {
+		t.Fatalf("LintTemplate returned error: %v", err)
+	}
+	if len(issues) != 0 {
+		t.Fatalf("expected no issues, got %v", issues)
+	}
+}
+
+func TestScaffoldedTemplatesLintClean(t *testing.T) {
+	root := t.TempDir()
+	dirs := []string{}
+	dir, err := CreateLocalTemplate(root, "plain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dirs = append(dirs, dir)
+	dir, err = CreateLocalTemplateFromWizard(root, LocalTemplateWizardOptions{Name: "wizard", Software: "node", Tools: []string{"git", "devcontainer", "preview"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dirs = append(dirs, dir)
+
+	for _, dir := range dirs {
+		issues, err := LintTemplate(dir, nil, t.TempDir())
+		if err != nil {
+			t.Fatalf("LintTemplate returned error: %v", err)
+		}
+		if len(issues) != 0 {
+			t.Fatalf("%s: expected no issues, got %v", filepath.Base(dir), issues)
+		}
+	}
+}
@@ -4,9 +4,9 @@ import (
 	"fmt"
 	"io/fs"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -27,9 +27,14 @@ type Registry struct {
 type Loader struct {
 	cacheDir     string
 	templateRepo string
+
+	// Fetcher downloads the repo in FetchTemplates. When nil, one is chosen
+	// from the repo reference.
+	Fetcher Fetcher
 }
 
-// NewLoader creates a new template loader
+// NewLoader creates a new template loader. templateRepo may pin a tag, branch
+// or commit with an @ suffix, e.g. owner/repo@v1.4.0.
 func NewLoader(cacheDir, templateRepo string) *Loader {
 	return &Loader{
 		cacheDir:     cacheDir,
@@ -37,50 +42,220 @@ func NewLoader(cacheDir, templateRepo string) *Loader {
 	}
 }
 
-// TemplatesDir returns the path to the cached templates directory
-func (l *Loader) TemplatesDir() string {
-	return filepath.Join(l.cacheDir, "templates")
+// Repo returns the repository this loader reads from, including any pinned ref.
+func (l *Loader) Repo() string {
+	return l.templateRepo
 }
 
-// FetchTemplates clones or pulls the templates repository
-func (l *Loader) FetchTemplates() error {
-	templatesDir := l.TemplatesDir()
+// Ref returns the tag, branch or commit the repo is pinned to, or "" to
+// follow the default branch.
+func (l *Loader) Ref() string {
+	_, ref := SplitRef(l.templateRepo)
+	return ref
+}
 
-	if _, err := os.Stat(filepath.Join(templatesDir, ".git")); err == nil {
-		// Already cloned, pull
-		return l.pullTemplates(templatesDir)
+// URL returns the git URL the repo is cloned from.
+func (l *Loader) URL() string {
+	repo, _ := SplitRef(l.templateRepo)
+	return RepoURL(repo)
+}
+
+// RepoURL returns the git URL for a repo reference. owner/repo is a GitHub
+// repository; full URLs (https, ssh, file://), scp-style addresses such as
+// git@host:group/repo.git and local paths are handed to git unchanged, so
+// git's own credential helpers and ssh agent take care of authentication.
+func RepoURL(repo string) string {
+	switch {
+	case strings.Contains(repo, "://"):
+		return repo
+	case IsLocalRepo(repo):
+		return expandHome(repo)
+	case strings.Contains(repo, ":"):
+		return repo
+	default:
+		return fmt.Sprintf("https://github.com/%s.git", repo)
 	}
+}
 
-	// Fresh clone
-	return l.cloneTemplates(templatesDir)
+// IsLocalRepo reports whether a repo reference is a path on this machine.
+func IsLocalRepo(repo string) bool {
+	repo, _ = SplitRef(repo)
+	return filepath.IsAbs(repo) || repo == "~" || repo == "." || repo == ".." ||
+		strings.HasPrefix(repo, "~/") || strings.HasPrefix(repo, "./") || strings.HasPrefix(repo, "../")
 }
 
-// cloneTemplates performs a shallow clone of the templates repo
-func (l *Loader) cloneTemplates(dir string) error {
-	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
-		return fmt.Errorf("creating cache directory: %w", err)
+// NormalizeRepo makes a local repo path absolute so the config entry works
+// from any directory. Other references are returned unchanged.
+func NormalizeRepo(repo string) (string, error) {
+	path, ref := SplitRef(strings.TrimSpace(repo))
+	if !IsLocalRepo(path) || strings.HasPrefix(path, "~") {
+		return strings.TrimSpace(repo), nil
 	}
-
-	repoURL := fmt.Sprintf("https://github.com/%s.git", l.templateRepo)
-	cmd := exec.Command("git", "clone", "--depth=1", repoURL, dir)
-	output, err := cmd.CombinedOutput()
+	abs, err := filepath.Abs(path)
 	if err != nil {
-		return fmt.Errorf("cloning templates repo: %s: %w", strings.TrimSpace(string(output)), err)
+		return "", fmt.Errorf("resolving %s: %w", path, err)
+	}
+	if ref != "" {
+		abs += "@" + ref
 	}
+	return abs, nil
+}
+
+// InPlace reports whether the repo is a local directory that is not a git
+// repository. Its templates are read where they are instead of from a clone,
+// and there is nothing to fetch or lock.
+func (l *Loader) InPlace() bool {
+	_, ok := l.inPlaceDir()
+	return ok
+}
 
-	return nil
+func (l *Loader) inPlaceDir() (string, bool) {
+	if !IsLocalRepo(l.templateRepo) || l.Ref() != "" {
+		return "", false
+	}
+	dir := expandHome(l.templateRepo)
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return "", false
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+		return "", false
+	}
+	// A bare repository has HEAD and objects/ at its top level.
+	if _, err := os.Stat(filepath.Join(dir, "HEAD")); err == nil {
+		if _, err := os.Stat(filepath.Join(dir, "objects")); err == nil {
+			return "", false
+		}
+	}
+	return dir, true
 }
 
-// pullTemplates pulls the latest changes
-func (l *Loader) pullTemplates(dir string) error {
-	cmd := exec.Command("git", "pull", "--ff-only")
-	cmd.Dir = dir
-	output, err := cmd.CombinedOutput()
+func expandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		homeDir, _ := os.UserHomeDir()
+		return filepath.Join(homeDir, path[1:])
+	}
+	return path
+}
+
+// SplitRef splits a trailing @ref off a repo or template reference:
+// "owner/repo@v1.4.0" gives "owner/repo" and "v1.4.0". An @ before the last
+// "/" or ":" is part of the name, so "owner/repo@v1:go-cli" has no ref.
+func SplitRef(s string) (name, ref string) {
+	at := strings.LastIndex(s, "@")
+	if at < 0 || at < strings.LastIndexAny(s, "/:") {
+		return s, ""
+	}
+	return s[:at], s[at+1:]
+}
+
+// TemplatesDir returns the path to the cached templates directory for this repo.
+// Every repo gets its own directory under <cacheDir>/repos, except a local
+// directory outside git, which is used in place.
+func (l *Loader) TemplatesDir() string {
+	if dir, ok := l.inPlaceDir(); ok {
+		return dir
+	}
+	return filepath.Join(ReposDir(l.cacheDir), RepoCacheKey(l.templateRepo))
+}
+
+// ReposDir returns the directory holding one cache directory per template repo.
+func ReposDir(cacheDir string) string {
+	return filepath.Join(cacheDir, "repos")
+}
+
+// RepoCacheKey turns a repo reference into a safe directory name. Letters,
+// digits, '-' and '.' are kept, '/' becomes "__" and every other byte is
+// written as '_' and two hex digits, so different references never share a
+// directory.
+func RepoCacheKey(repo string) string {
+	var b strings.Builder
+	repo = strings.TrimSpace(repo)
+	for i := 0; i < len(repo); i++ {
+		c := repo[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-':
+			b.WriteByte(c)
+		case c == '.' && i > 0:
+			// A leading dot would hide the directory among staging leftovers.
+			b.WriteByte(c)
+		case c == '/':
+			b.WriteString("__")
+		default:
+			fmt.Fprintf(&b, "_%02x", c)
+		}
+	}
+	return b.String()
+}
+
+// FetchTemplates brings the repo's cache up to date with its Fetcher and
+// records what it resolved to in the lockfile. It returns ErrOffline in
+// offline mode.
+func (l *Loader) FetchTemplates() error {
+	if l.InPlace() {
+		return nil
+	}
+	if Offline() {
+		return ErrOffline
+	}
+	fetcher := l.Fetcher
+	if fetcher == nil {
+		fetcher = l.defaultFetcher()
+	}
+
+	templatesDir := l.TemplatesDir()
+	if err := os.MkdirAll(filepath.Dir(templatesDir), 0755); err != nil {
+		return fmt.Errorf("creating cache directory: %w", err)
+	}
+	result, err := fetcher.Fetch(templatesDir)
 	if err != nil {
-		return fmt.Errorf("pulling templates: %s: %w", strings.TrimSpace(string(output)), err)
+		return err
+	}
+	return UpdateLock(l.cacheDir, l.templateRepo, LockedRepo{
+		URL:       l.URL(),
+		Ref:       l.Ref(),
+		Commit:    result.Commit,
+		Checksum:  result.Checksum,
+		FetchedAt: time.Now().UTC(),
+	})
+}
+
+// defaultFetcher picks how to download the repo: archive URLs are downloaded
+// as archives, GitHub repos fall back to GitHub's archive downloads when git
+// is not installed, and everything else is cloned with git. A pinned archive
+// must match the checksum it had when it was first locked.
+func (l *Loader) defaultFetcher() Fetcher {
+	repo, ref := SplitRef(l.templateRepo)
+	archiveURL, checksum := "", ""
+	switch {
+	case IsArchiveURL(repo):
+		archiveURL, checksum = splitChecksum(repo)
+	case isGitHubRepo(repo) && !hasGit():
+		archiveURL = GitHubArchiveURL(repo, ref)
+	default:
+		return &GitFetcher{URL: l.URL(), Ref: ref}
 	}
+	// Only an archive pinned to a commit must stay the same; a branch moves
+	// and its archive with it.
+	if checksum == "" && isCommitID(ref) {
+		if lock, err := ReadLock(l.cacheDir); err == nil {
+			checksum = lock.Repos[l.templateRepo].Checksum
+		}
+	}
+	return &ArchiveFetcher{URL: archiveURL, Checksum: checksum}
+}
 
-	return nil
+// lockCommit records the commit checked out in dir in the lockfile.
+func (l *Loader) lockCommit(dir string) error {
+	commit, err := gitOutput(dir, "rev-parse", "HEAD")
+	if err != nil {
+		return fmt.Errorf("resolving templates commit: %w", err)
+	}
+	return UpdateLock(l.cacheDir, l.templateRepo, LockedRepo{
+		URL:       l.URL(),
+		Ref:       l.Ref(),
+		Commit:    commit,
+		FetchedAt: time.Now().UTC(),
+	})
 }
 
 // LoadRegistry loads and parses the registry.yaml file
@@ -112,37 +287,137 @@ func (l *Loader) LoadManifest(templatePath string) (*TemplateManifest, error) {
 		return nil, fmt.Errorf("parsing template.yaml: %w", err)
 	}
 	manifest.SourcePath = templatePath
+	manifest.Repo = l.templateRepo
 	manifest.IsBuiltin = false
 	manifest.ApplyDefaults()
 
 	return &manifest, nil
 }
 
-// LoadAllManifests loads all template manifests from the registry
+// LoadAllManifests loads all template manifests from the registry, skipping
+// templates that fail to load.
 func (l *Loader) LoadAllManifests() ([]*TemplateManifest, error) {
+	manifests, _, err := l.LoadTemplates()
+	return manifests, err
+}
+
+// LoadTemplates loads every template in the registry, returning the ones that
+// failed to load alongside the valid manifests.
+func (l *Loader) LoadTemplates() ([]*TemplateManifest, []InvalidTemplate, error) {
 	reg, err := l.LoadRegistry()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	var manifests []*TemplateManifest
+	var invalid []InvalidTemplate
 	for _, entry := range reg.Templates {
 		manifest, err := l.LoadManifest(entry.Path)
 		if err != nil {
-			// Skip templates that fail to load
+			invalid = append(invalid, InvalidTemplate{
+				Name: entry.Name,
+				Repo: l.templateRepo,
+				Path: filepath.Join(l.TemplatesDir(), entry.Path),
+				Err:  err,
+			})
 			continue
 		}
 		manifests = append(manifests, manifest)
 	}
 
-	return manifests, nil
+	return manifests, invalid, nil
+}
+
+// InvalidTemplate is a template that could not be loaded, kept so it can be
+// reported instead of silently skipped.
+type InvalidTemplate struct {
+	Name string
+	// Repo is empty for local templates.
+	Repo string
+	// Path is the template's directory on disk.
+	Path string
+	Err  error
+	// Manifest is set when template.yaml parsed but the template could not be
+	// composed with its bases.
+	Manifest *TemplateManifest
+}
+
+// QualifiedName returns the name in the same form as TemplateManifest.QualifiedName.
+func (t InvalidTemplate) QualifiedName() string {
+	if t.Repo == "" {
+		return t.Name
+	}
+	return t.Repo + ":" + t.Name
+}
+
+// Source reports where the template comes from, like TemplateManifest.Source.
+func (t InvalidTemplate) Source() string {
+	if t.Repo == "" {
+		return SourceLocal
+	}
+	return SourceRemote
 }
 
-// GetTemplateFS returns an os.DirFS for the template directory.
+// GetTemplateFS returns an os.DirFS for the template directory, without the
+// template's tests.
 func (l *Loader) GetTemplateFS(templatePath string) (fs.FS, error) {
 	dir := filepath.Join(l.TemplatesDir(), templatePath)
 	if _, err := os.Stat(dir); err != nil {
 		return nil, fmt.Errorf("template directory not found: %s", dir)
 	}
-	return os.DirFS(dir), nil
+	return withoutTests(os.DirFS(dir)), nil
+}
+
+// FetchAllTemplates clones or pulls every repo, returning one error per repo that failed.
+func FetchAllTemplates(cacheDir string, repos []string) map[string]error {
+	failed := make(map[string]error)
+	if err := RemoveLegacyCache(cacheDir); err != nil {
+		failed[LegacyCacheDir(cacheDir)] = err
+	}
+	for _, repo := range repos {
+		if strings.TrimSpace(repo) == "" {
+			continue
+		}
+		if err := NewLoader(cacheDir, repo).FetchTemplates(); err != nil {
+			failed[repo] = err
+		}
+	}
+	return failed
+}
+
+// LoadRepoManifests loads the manifests of every cached repo, in repo order.
+// Repos that have not been fetched yet are skipped.
+func LoadRepoManifests(cacheDir string, repos []string) []*TemplateManifest {
+	manifests, _ := LoadRepoTemplates(cacheDir, repos)
+	return manifests
+}
+
+// LoadRepoTemplates is LoadRepoManifests that also returns the templates that
+// failed to load. A fetched repo without a readable registry is reported as a
+// single invalid entry named after registry.yaml.
+func LoadRepoTemplates(cacheDir string, repos []string) ([]*TemplateManifest, []InvalidTemplate) {
+	var manifests []*TemplateManifest
+	var invalid []InvalidTemplate
+	for _, repo := range repos {
+		if strings.TrimSpace(repo) == "" {
+			continue
+		}
+		loader := NewLoader(cacheDir, repo)
+		if _, err := os.Stat(loader.TemplatesDir()); err != nil {
+			continue
+		}
+		remote, broken, err := loader.LoadTemplates()
+		if err != nil {
+			invalid = append(invalid, InvalidTemplate{
+				Name: "registry.yaml",
+				Repo: repo,
+				Path: loader.TemplatesDir(),
+				Err:  err,
+			})
+			continue
+		}
+		manifests = append(manifests, remote...)
+		invalid = append(invalid, broken...)
+	}
+	return manifests, invalid
 }
@@ -0,0 +1,300 @@
+package template
+
+import (
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeCachedRepo lays out a fetched repo in the cache the way FetchTemplates would.
+func writeCachedRepo(t *testing.T, cacheDir, repo string, files map[string]string) {
+	t.Helper()
+	root := NewLoader(cacheDir, repo).TemplatesDir()
+	for relPath, content := range files {
+		fullPath := filepath.Join(root, relPath)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLoadRepoManifestsNamespacesByRepo(t *testing.T) {
+	cacheDir := t.TempDir()
+	for _, repo := range []string{"acme/templates", "other/templates"} {
+		writeCachedRepo(t, cacheDir, repo, map[string]string{
+			"registry.yaml":         "templates:\n  - name: go-cli\n    path: go-cli\n",
+			"go-cli/template.yaml":  "name: go-cli\nversion: 1.0.0\ndescription: from " + repo + "\n",
+			"go-cli/README.md.tmpl": repo,
+		})
+	}
+
+	manifests := LoadRepoManifests(cacheDir, []string{"acme/templates", "missing/repo", "other/templates"})
+	if len(manifests) != 2 {
+		t.Fatalf("expected 2 manifests, got %d", len(manifests))
+	}
+	if got := manifests[0].QualifiedName(); got != "acme/templates:go-cli" {
+		t.Fatalf("expected acme/templates:go-cli, got %s", got)
+	}
+	if got := manifests[1].QualifiedName(); got != "other/templates:go-cli" {
+		t.Fatalf("expected other/templates:go-cli, got %s", got)
+	}
+
+	// Each manifest resolves files from its own repo cache, not the fallback repo.
+	templateFS, err := ResolveTemplateFS(manifests[1], cacheDir, "acme/templates")
+	if err != nil {
+		t.Fatalf("ResolveTemplateFS returned error: %v", err)
+	}
+	content, err := fs.ReadFile(templateFS, "README.md.tmpl")
+	if err != nil {
+		t.Fatalf("reading README.md.tmpl: %v", err)
+	}
+	if string(content) != "other/templates" {
+		t.Fatalf("expected file from other/templates, got %q", content)
+	}
+}
+
+func TestRepoCacheKeyIsFilesystemSafe(t *testing.T) {
+	if got := RepoCacheKey("owner/repo"); got != "owner__repo" {
+		t.Fatalf("expected owner__repo, got %s", got)
+	}
+	if got := RepoCacheKey("https://example.com/a b"); got != "https_3a____example.com__a_20b" {
+		t.Fatalf("unexpected cache key %s", got)
+	}
+	// Every reference gets a directory of its own.
+	seen := map[string]string{}
+	for _, repo := range []string{"owner/repo@v1", "owner/repo_v1", "owner/repo v1", "owner__repo", "owner/repo", ".hidden", "_2ehidden"} {
+		key := RepoCacheKey(repo)
+		if other, ok := seen[key]; ok {
+			t.Fatalf("%s and %s share the cache key %s", repo, other, key)
+		}
+		if strings.HasPrefix(key, ".") {
+			t.Fatalf("cache key %s for %s is hidden", key, repo)
+		}
+		seen[key] = repo
+	}
+}
+
+func TestFetchAllTemplatesRemovesLegacyCache(t *testing.T) {
+	cacheDir := t.TempDir()
+	legacy := LegacyCacheDir(cacheDir)
+	for _, name := range []string{".git/HEAD", "registry.yaml"} {
+		path := filepath.Join(legacy, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if failed := FetchAllTemplates(cacheDir, nil); len(failed) != 0 {
+		t.Fatalf("unexpected failures %v", failed)
+	}
+	if _, err := os.Stat(legacy); !os.IsNotExist(err) {
+		t.Fatalf("expected the old cache to be removed, got %v", err)
+	}
+
+	// A directory that is not the old cache is kept.
+	if err := os.MkdirAll(filepath.Join(legacy, "mine"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	FetchAllTemplates(cacheDir, nil)
+	if _, err := os.Stat(filepath.Join(legacy, "mine")); err != nil {
+		t.Fatalf("expected an unrelated directory to be kept: %v", err)
+	}
+}
+
+func TestLoadTemplatesReportsInvalidTemplates(t *testing.T) {
+	cacheDir := t.TempDir()
+	writeCachedRepo(t, cacheDir, "acme/templates", map[string]string{
+		"registry.yaml":      "templates:\n  - name: good\n    path: good\n  - name: bad\n    path: bad\n  - name: gone\n    path: gone\n",
+		"good/template.yaml": "name: good\nversion: 1.0.0\n",
+		"bad/template.yaml":  "name: bad\nprompts: [oops\n",
+	})
+	writeCachedRepo(t, cacheDir, "acme/broken", map[string]string{
+		"registry.yaml": "templates: {",
+	})
+
+	manifests, invalid := LoadRepoTemplates(cacheDir, []string{"acme/templates", "acme/broken", "never/fetched"})
+	if len(manifests) != 1 || manifests[0].Name != "good" {
+		t.Fatalf("expected only the good template, got %v", manifests)
+	}
+	var names []string
+	for _, inv := range invalid {
+		if inv.Err == nil || inv.Path == "" || inv.Source() != SourceRemote {
+			t.Fatalf("expected an error, path and remote source for %s, got %+v", inv.QualifiedName(), inv)
+		}
+		names = append(names, inv.QualifiedName())
+	}
+	if got := strings.Join(names, ","); got != "acme/templates:bad,acme/templates:gone,acme/broken:registry.yaml" {
+		t.Fatalf("unexpected invalid templates: %s", got)
+	}
+
+	localDir := t.TempDir()
+	if _, err := CreateLocalTemplate(localDir, "ok"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(localDir, "nofiles"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(localDir, "nofiles", "template.yaml"), []byte("name: nofiles\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	local, brokenLocal, err := LoadLocalTemplates(localDir)
+	if err != nil {
+		t.Fatalf("LoadLocalTemplates returned error: %v", err)
+	}
+	if len(local) != 1 || len(brokenLocal) != 1 || brokenLocal[0].Name != "nofiles" || brokenLocal[0].Source() != SourceLocal {
+		t.Fatalf("expected one valid and one invalid local template, got %v and %+v", local, brokenLocal)
+	}
+	if local[0].Source() != SourceLocal || GetBuiltinManifest().Source() != SourceBuiltin {
+		t.Fatal("unexpected template sources")
+	}
+}
+
+func TestRepoURL(t *testing.T) {
+	home, _ := os.UserHomeDir()
+	tests := []struct {
+		repo, url string
+	}{
+		{"owner/repo", "https://github.com/owner/repo.git"},
+		{"https://gitlab.example.com/group/repo.git", "https://gitlab.example.com/group/repo.git"},
+		{"ssh://git@gitea.example.com:2222/team/templates.git", "ssh://git@gitea.example.com:2222/team/templates.git"},
+		{"git@gitlab.example.com:group/repo.git", "git@gitlab.example.com:group/repo.git"},
+		{"file:///srv/git/templates.git", "file:///srv/git/templates.git"},
+		{"/srv/git/templates.git", "/srv/git/templates.git"},
+		{"~/templates", filepath.Join(home, "templates")},
+	}
+	for _, tt := range tests {
+		if got := RepoURL(tt.repo); got != tt.url {
+			t.Fatalf("RepoURL(%q) = %q, want %q", tt.repo, got, tt.url)
+		}
+	}
+	if got := NewLoader("", "git@gitlab.example.com:group/repo.git@v2").URL(); got != "git@gitlab.example.com:group/repo.git" {
+		t.Fatalf("expected the ref to be split off the URL, got %q", got)
+	}
+}
+
+func TestFetchTemplatesFromLocalBareRepo(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	work := t.TempDir()
+	commit := func(version string) string {
+		for name, content := range map[string]string{
+			"registry.yaml":      "templates:\n  - name: svc\n    path: svc\n",
+			"svc/template.yaml":  "name: svc\nversion: " + version + "\n",
+			"svc/README.md.tmpl": version + "\n",
+		} {
+			if err := os.MkdirAll(filepath.Join(work, filepath.Dir(name)), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(work, name), []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		gitRun(t, work, "add", ".")
+		gitRun(t, work, "commit", "-q", "-m", version)
+		return strings.TrimSpace(gitRun(t, work, "rev-parse", "HEAD"))
+	}
+	gitRun(t, work, "init", "-q")
+	v1 := commit("1.0.0")
+	gitRun(t, work, "tag", "v1.0.0")
+	commit("2.0.0")
+	bare := filepath.Join(t.TempDir(), "templates.git")
+	gitRun(t, work, "clone", "-q", "--bare", work, bare)
+	gitRun(t, work, "remote", "add", "origin", bare)
+
+	cacheDir := t.TempDir()
+	versionOf := func(repo string) string {
+		t.Helper()
+		loader := NewLoader(cacheDir, repo)
+		if err := loader.FetchTemplates(); err != nil {
+			t.Fatalf("fetching %s: %v", repo, err)
+		}
+		manifests, err := loader.LoadAllManifests()
+		if err != nil || len(manifests) != 1 {
+			t.Fatalf("loading %s: %v (%d manifests)", repo, err, len(manifests))
+		}
+		return manifests[0].Version
+	}
+
+	for repo, want := range map[string]string{
+		"file://" + bare:     "2.0.0",
+		bare:                 "2.0.0",
+		bare + "@v1.0.0":     "1.0.0",
+		bare + "@" + v1:      "1.0.0",
+		bare + "@" + v1[:10]: "1.0.0",
+	} {
+		if got := versionOf(repo); got != want {
+			t.Fatalf("%s: expected version %s, got %s", repo, want, got)
+		}
+	}
+
+	// Refreshing follows the default branch but leaves pinned repos alone.
+	commit("3.0.0")
+	gitRun(t, work, "push", "-q", "origin", "HEAD:refs/heads/"+strings.TrimSpace(gitRun(t, bare, "symbolic-ref", "--short", "HEAD")))
+	if got := versionOf(bare); got != "3.0.0" {
+		t.Fatalf("expected the refreshed repo at 3.0.0, got %s", got)
+	}
+	if got := versionOf(bare + "@v1.0.0"); got != "1.0.0" {
+		t.Fatalf("expected the pinned repo to stay at 1.0.0, got %s", got)
+	}
+
+	lock, err := ReadLock(cacheDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if locked := lock.Repos[bare+"@v1.0.0"]; locked.Commit != v1 || locked.Ref != "v1.0.0" || locked.URL != bare {
+		t.Fatalf("unexpected lock entry for the pinned repo: %+v", locked)
+	}
+
+	// A plain directory is read in place, with nothing to fetch.
+	plain := writeTemplateDir(t, map[string]string{
+		"registry.yaml":     "templates:\n  - name: svc\n    path: svc\n",
+		"svc/template.yaml": "name: svc\nversion: 0.1.0\n",
+	})
+	if loader := NewLoader(cacheDir, plain); !loader.InPlace() || loader.TemplatesDir() != plain {
+		t.Fatalf("expected %s to be read in place, got %s", plain, loader.TemplatesDir())
+	}
+	if got := versionOf(plain); got != "0.1.0" {
+		t.Fatalf("expected the plain directory's template, got version %s", got)
+	}
+	if NewLoader(cacheDir, bare).InPlace() {
+		t.Fatal("expected a bare repository to be cloned, not read in place")
+	}
+}
+
+func TestRepoTemplatesLeaveOutTheirTests(t *testing.T) {
+	cacheDir := t.TempDir()
+	writeCachedRepo(t, cacheDir, "acme/templates", map[string]string{
+		"registry.yaml":                      "templates:\n  - name: svc\n    path: svc\n",
+		"svc/template.yaml":                  "name: svc\nversion: 1.0.0\n",
+		"svc/README.md.tmpl":                 "# {{.project_name}}\n",
+		"svc/tests/basic/answers.yaml":       "project_name: demo\n",
+		"svc/tests/basic/expected/README.md": "# demo\n",
+		"svc/docs/tests/keep.md":             "nested tests/ directories are ordinary files\n",
+	})
+	manifests := LoadRepoManifests(cacheDir, []string{"acme/templates"})
+	if len(manifests) != 1 {
+		t.Fatalf("expected one manifest, got %d", len(manifests))
+	}
+	templateFS, err := ResolveTemplateFS(manifests[0], cacheDir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := NewRenderer(manifests[0], map[string]interface{}{"project_name": "demo"}).Render(templateFS)
+	if err != nil {
+		t.Fatalf("Render returned error: %v", err)
+	}
+	paths := strings.Join(result.Paths(), " ")
+	if strings.Contains(paths, "tests/basic") || !strings.Contains(paths, "docs/tests/keep.md") {
+		t.Fatalf("expected tests/ to be left out of the render, got %v", result.Paths())
+	}
+}
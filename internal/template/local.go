@@ -8,6 +8,7 @@ import (
 	"regexp"
 	"strings"
 
+	"github.com/HungSloth/sloth-incubator/internal/license"
 	"gopkg.in/yaml.v3"
 )
 
@@ -40,7 +41,7 @@ func CreateLocalTemplate(localTemplatesDir, name string) (string, error) {
 		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
 			return "", fmt.Errorf("creating directory for %s: %w", relPath, err)
 		}
-		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+		if err := os.WriteFile(fullPath, []byte(content), localTemplateFileMode(relPath)); err != nil {
 			return "", fmt.Errorf("writing %s: %w", relPath, err)
 		}
 	}
@@ -78,7 +79,7 @@ func CreateLocalTemplateFromWizard(localTemplatesDir string, opts LocalTemplateW
 		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
 			return "", fmt.Errorf("creating directory for %s: %w", relPath, err)
 		}
-		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+		if err := os.WriteFile(fullPath, []byte(content), localTemplateFileMode(relPath)); err != nil {
 			return "", fmt.Errorf("writing %s: %w", relPath, err)
 		}
 	}
@@ -86,17 +87,34 @@ func CreateLocalTemplateFromWizard(localTemplatesDir string, opts LocalTemplateW
 	return templateDir, nil
 }
 
+// localTemplateFileMode makes scaffolded scripts executable so rendered projects
+// get them with the execute bit set.
+func localTemplateFileMode(relPath string) os.FileMode {
+	if strings.HasSuffix(relPath, ".sh") {
+		return 0755
+	}
+	return 0644
+}
+
 // LoadLocalManifests loads template manifests from a local template directory.
 func LoadLocalManifests(localTemplatesDir string) ([]*TemplateManifest, error) {
+	manifests, _, err := LoadLocalTemplates(localTemplatesDir)
+	return manifests, err
+}
+
+// LoadLocalTemplates loads every local template, returning the ones that failed
+// to load alongside the valid manifests.
+func LoadLocalTemplates(localTemplatesDir string) ([]*TemplateManifest, []InvalidTemplate, error) {
 	entries, err := os.ReadDir(localTemplatesDir)
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
-			return nil, nil
+			return nil, nil, nil
 		}
-		return nil, fmt.Errorf("reading local templates directory: %w", err)
+		return nil, nil, fmt.Errorf("reading local templates directory: %w", err)
 	}
 
 	manifests := make([]*TemplateManifest, 0, len(entries))
+	var invalid []InvalidTemplate
 	for _, entry := range entries {
 		if !entry.IsDir() {
 			continue
@@ -104,13 +122,13 @@ func LoadLocalManifests(localTemplatesDir string) ([]*TemplateManifest, error) {
 		templateDir := filepath.Join(localTemplatesDir, entry.Name())
 		manifest, err := loadLocalManifest(templateDir)
 		if err != nil {
-			// Skip invalid local templates and continue loading others.
+			invalid = append(invalid, InvalidTemplate{Name: entry.Name(), Path: templateDir, Err: err})
 			continue
 		}
 		manifests = append(manifests, manifest)
 	}
 
-	return manifests, nil
+	return manifests, invalid, nil
 }
 
 func loadLocalManifest(templateDir string) (*TemplateManifest, error) {
@@ -128,7 +146,7 @@ func loadLocalManifest(templateDir string) (*TemplateManifest, error) {
 
 	var manifest TemplateManifest
 	if err := yaml.Unmarshal(data, &manifest); err != nil {
-		return nil, err
+		return nil, fmt.Errorf("parsing template.yaml: %w", err)
 	}
 	if manifest.Name == "" {
 		manifest.Name = filepath.Base(templateDir)
@@ -150,9 +168,6 @@ func localTemplateScaffold(name string) map[string]string {
 		"files/README.md.tmpl": `# {{.project_name}}
 
 {{.description}}
-`,
-		"files/.gitignore": `.DS_Store
-.env
 `,
 		"files/.devcontainer/devcontainer.json.tmpl": `{
   "name": "{{.project_name}}",
@@ -191,13 +206,6 @@ func localTemplateScaffoldWithOptions(opts LocalTemplateWizardOptions) map[strin
 	files := map[string]string{
 		"template.yaml":        localTemplateYAMLWithOptions(opts),
 		"files/README.md.tmpl": readmeTemplateForOptions(opts),
-		"files/.gitignore": `.DS_Store
-.env
-`,
-	}
-
-	if !hasTool(opts.Tools, "git") {
-		delete(files, "files/.gitignore")
 	}
 
 	if hasTool(opts.Tools, "devcontainer") {
@@ -237,10 +245,7 @@ prompts:
     label: License
     type: select
     options:
-      - MIT
-      - Apache-2.0
-      - GPL-3.0
-      - none
+%s
     default: MIT
   - name: create_github_repo
     label: Create GitHub repository?
@@ -250,6 +255,7 @@ prompts:
     label: Enable headless preview tooling?
     type: confirm
     default: true
+gitignore: [os, editors, env]
 files:
   - src: .incubator/preview/**
     when: '{{if .enable_preview}}true{{end}}'
@@ -265,8 +271,16 @@ devcontainer:
       - python
       - go
       - rust
-      - incubator-preview
-`, name, name)
+`, name, name, licenseOptionsYAML("      "))
+}
+
+// licenseOptionsYAML lists the embedded licenses as YAML sequence items.
+func licenseOptionsYAML(indent string) string {
+	lines := make([]string, 0, len(license.Options()))
+	for _, id := range license.Options() {
+		lines = append(lines, indent+"- "+id)
+	}
+	return strings.Join(lines, "\n")
 }
 
 func localTemplateYAMLWithOptions(opts LocalTemplateWizardOptions) string {
@@ -303,6 +317,10 @@ func localTemplateYAMLWithOptions(opts LocalTemplateWizardOptions) string {
 		b.WriteString("  app_command: \"echo \\\"Set preview app_command in .incubator/preview/config.yaml\\\" && sleep infinity\"\n")
 	}
 
+	if hasTool(opts.Tools, "git") {
+		fmt.Fprintf(&b, "gitignore: [os, editors, env, %s]\n", opts.Software)
+	}
+
 	if hasTool(opts.Tools, "devcontainer") {
 		b.WriteString("devcontainer:\n")
 		b.WriteString("  base_image: ubuntu\n")
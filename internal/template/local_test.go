@@ -4,6 +4,7 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -105,3 +106,33 @@ func TestRenderLocalTemplate(t *testing.T) {
 		t.Fatalf("expected rendered README.md to have content")
 	}
 }
+
+func TestWizardTemplateComposesGitignore(t *testing.T) {
+	root := t.TempDir()
+	_, err := CreateLocalTemplateFromWizard(root, LocalTemplateWizardOptions{Name: "svc", Software: "python", Tools: []string{"git"}})
+	if err != nil {
+		t.Fatalf("CreateLocalTemplateFromWizard returned error: %v", err)
+	}
+	manifests, err := LoadLocalManifests(root)
+	if err != nil || len(manifests) != 1 {
+		t.Fatalf("LoadLocalManifests returned %d manifests, %v", len(manifests), err)
+	}
+	templateFS, err := ResolveTemplateFS(manifests[0], t.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := NewRenderer(manifests[0], map[string]interface{}{"project_name": "svc"}).Render(templateFS)
+	if err != nil {
+		t.Fatalf("Render returned error: %v", err)
+	}
+	file, ok := result.File(GitignorePath)
+	if !ok {
+		t.Fatalf("expected a .gitignore, got %v", result.Paths())
+	}
+	for _, want := range []string{".DS_Store", ".env", "__pycache__/"} {
+		if !strings.Contains(string(file.Content), want) {
+			t.Fatalf("expected %s in .gitignore:\n%s", want, file.Content)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package template
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LockFileName is the lockfile in the cache directory recording the commit
+// every template repo was last fetched at.
+const LockFileName = "templates.lock"
+
+// LockFile maps each configured repo, as written in config, to the commit it
+// resolved to.
+type LockFile struct {
+	Repos map[string]LockedRepo `yaml:"repos"`
+}
+
+// LockedRepo is the resolved state of one template repo.
+type LockedRepo struct {
+	URL string `yaml:"url"`
+	// Ref is the pinned tag, branch or commit, empty for the default branch.
+	Ref    string `yaml:"ref,omitempty"`
+	Commit string `yaml:"commit,omitempty"`
+	// Checksum is the sha256 of the archive a repo was downloaded as. Pinned
+	// archives must keep matching it.
+	Checksum  string    `yaml:"checksum,omitempty"`
+	FetchedAt time.Time `yaml:"fetched_at"`
+}
+
+// LockPath returns the path to the lockfile.
+func LockPath(cacheDir string) string {
+	return filepath.Join(cacheDir, LockFileName)
+}
+
+// ReadLock reads the lockfile. A missing lockfile reads as empty.
+func ReadLock(cacheDir string) (*LockFile, error) {
+	lock := &LockFile{Repos: map[string]LockedRepo{}}
+	data, err := os.ReadFile(LockPath(cacheDir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return lock, nil
+		}
+		return nil, fmt.Errorf("reading %s: %w", LockFileName, err)
+	}
+	if err := yaml.Unmarshal(data, lock); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", LockFileName, err)
+	}
+	if lock.Repos == nil {
+		lock.Repos = map[string]LockedRepo{}
+	}
+	return lock, nil
+}
+
+// Save writes the lockfile.
+func (l *LockFile) Save(cacheDir string) error {
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return fmt.Errorf("creating cache directory: %w", err)
+	}
+	data, err := yaml.Marshal(l)
+	if err != nil {
+		return fmt.Errorf("marshaling %s: %w", LockFileName, err)
+	}
+	header := []byte("# Written by incubator: the commit each template repo was last fetched at.\n")
+	if err := os.WriteFile(LockPath(cacheDir), append(header, data...), 0644); err != nil {
+		return fmt.Errorf("writing %s: %w", LockFileName, err)
+	}
+	return nil
+}
+
+// UpdateLock records the state of repo in the lockfile.
+func UpdateLock(cacheDir, repo string, locked LockedRepo) error {
+	lock, err := ReadLock(cacheDir)
+	if err != nil {
+		return err
+	}
+	lock.Repos[repo] = locked
+	return lock.Save(cacheDir)
+}
+
+// RemoveLock drops repo from the lockfile.
+func RemoveLock(cacheDir, repo string) error {
+	lock, err := ReadLock(cacheDir)
+	if err != nil {
+		return err
+	}
+	if _, ok := lock.Repos[repo]; !ok {
+		return nil
+	}
+	delete(lock.Repos, repo)
+	return lock.Save(cacheDir)
+}
@@ -1,14 +1,48 @@
 package template
 
+import (
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
 // PromptType represents the type of a template prompt
 type PromptType string
 
 const (
-	PromptText    PromptType = "text"
-	PromptSelect  PromptType = "select"
-	PromptConfirm PromptType = "confirm"
+	PromptText        PromptType = "text"
+	PromptSelect      PromptType = "select"
+	PromptConfirm     PromptType = "confirm"
+	PromptMultiSelect PromptType = "multiselect"
+	PromptNumber      PromptType = "number"
+	PromptPath        PromptType = "path"
+	PromptPassword    PromptType = "password"
+	PromptMultiline   PromptType = "multiline"
 )
 
+// PromptTypes lists every supported prompt type.
+func PromptTypes() []PromptType {
+	return []PromptType{PromptText, PromptSelect, PromptConfirm, PromptMultiSelect, PromptNumber, PromptPath, PromptPassword, PromptMultiline}
+}
+
+// Valid reports whether the prompt type is supported.
+func (t PromptType) Valid() bool {
+	for _, known := range PromptTypes() {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// HasOptions reports whether answers for this type are picked from Options.
+func (t PromptType) HasOptions() bool {
+	return t == PromptSelect || t == PromptMultiSelect
+}
+
 // PromptOption represents a selectable option in a prompt
 type PromptOption struct {
 	Label string `yaml:"label"`
@@ -43,24 +77,78 @@ type Prompt struct {
 	Options  []PromptOption `yaml:"options"`
 	Required bool           `yaml:"required"`
 	Validate string         `yaml:"validate"`
+	// When hides the prompt unless the expression evaluates truthy against the
+	// answers to the prompts before it, using the same rules as file `when`.
+	When string `yaml:"when"`
+	// Min and Max bound number prompts.
+	Min *float64 `yaml:"min"`
+	Max *float64 `yaml:"max"`
+}
+
+// IsSecret reports whether the answer must never be written to disk.
+func (p Prompt) IsSecret() bool {
+	return p.Type == PromptPassword
 }
 
-// FileRule defines a conditional file inclusion rule
+// FileRule defines a conditional file inclusion rule. Src is a doublestar glob
+// matched against paths in the template's files; a leading "!" makes the rule
+// exclude what it matches.
 type FileRule struct {
 	Src    string `yaml:"src"`
 	Always bool   `yaml:"always"`
 	When   string `yaml:"when"`
+	// Mode overrides the permissions of the files the rule includes.
+	Mode FileMode `yaml:"mode"`
+}
+
+// Pattern returns the rule's glob and whether it is negated with a leading "!".
+func (r FileRule) Pattern() (pattern string, negated bool) {
+	if strings.HasPrefix(r.Src, "!") {
+		return strings.TrimPrefix(r.Src, "!"), true
+	}
+	return r.Src, false
+}
+
+// FileMode is a file permission written in octal in template.yaml, e.g. 0755.
+// Zero keeps the source file's own mode.
+type FileMode fs.FileMode
+
+// UnmarshalYAML reads the mode as octal whether or not it is quoted.
+func (m *FileMode) UnmarshalYAML(node *yaml.Node) error {
+	value := strings.TrimPrefix(strings.TrimPrefix(node.Value, "0o"), "0O")
+	mode, err := strconv.ParseUint(value, 8, 32)
+	if err != nil || mode == 0 || mode > 0777 {
+		return fmt.Errorf("line %d: invalid file mode %q: use octal permissions like 0755", node.Line, node.Value)
+	}
+	*m = FileMode(mode)
+	return nil
 }
 
 // DevcontainerFeatures holds conditional devcontainer feature lists
 type DevcontainerFeatures struct {
-	Always []string `yaml:"always"`
+	Always []string              `yaml:"always"`
+	When   []ConditionalFeatures `yaml:"when"`
+}
+
+// ConditionalFeatures enables features only when the `if` expression is truthy.
+type ConditionalFeatures struct {
+	If       string   `yaml:"if"`
+	Features []string `yaml:"features"`
 }
 
 // DevcontainerConfig holds devcontainer configuration
 type DevcontainerConfig struct {
 	BaseImage string               `yaml:"base_image"`
 	Features  DevcontainerFeatures `yaml:"features"`
+	Ports     []int                `yaml:"ports"`
+	Mounts    []string             `yaml:"mounts"`
+}
+
+// IsEmpty reports whether the manifest leaves devcontainer.json entirely to the
+// template files.
+func (c DevcontainerConfig) IsEmpty() bool {
+	return c.BaseImage == "" && len(c.Features.Always) == 0 && len(c.Features.When) == 0 &&
+		len(c.Ports) == 0 && len(c.Mounts) == 0
 }
 
 // PreviewConfig holds optional headless preview configuration.
@@ -71,6 +159,15 @@ type PreviewConfig struct {
 	VNCPort    int    `yaml:"vnc_port"`
 }
 
+// LicenseConfig controls the LICENSE file written from the `license` answer.
+type LicenseConfig struct {
+	// Skip leaves the license entirely to the template's own files.
+	Skip bool `yaml:"skip"`
+	// Headers adds SPDX-License-Identifier comments to source files when the
+	// condition renders truthy, e.g. "true" or "{{.spdx_headers}}".
+	Headers string `yaml:"headers"`
+}
+
 // HooksConfig holds hook configuration
 type HooksConfig struct {
 	PostCreate string `yaml:"post_create"`
@@ -78,23 +175,66 @@ type HooksConfig struct {
 
 // TemplateManifest represents a template.yaml file
 type TemplateManifest struct {
-	Name         string             `yaml:"name"`
-	Version      string             `yaml:"version"`
-	Description  string             `yaml:"description"`
-	Author       string             `yaml:"author"`
+	Name        string `yaml:"name"`
+	Version     string `yaml:"version"`
+	Description string `yaml:"description"`
+	Author      string `yaml:"author"`
+	// Extends names the template this one is built on, and Include lists
+	// mixins layered on top of it, e.g. "go-service" or "owner/repo:ci".
+	Extends      string             `yaml:"extends"`
+	Include      []string           `yaml:"include"`
 	Prompts      []Prompt           `yaml:"prompts"`
 	Files        []FileRule         `yaml:"files"`
+	Gitignore    []string           `yaml:"gitignore"`
 	Devcontainer DevcontainerConfig `yaml:"devcontainer"`
 	Preview      PreviewConfig      `yaml:"preview"`
 	Hooks        HooksConfig        `yaml:"hooks"`
+	License      LicenseConfig      `yaml:"license"`
 
 	// Runtime-only metadata, not part of template.yaml schema.
 	SourcePath string `yaml:"-"`
+	Repo       string `yaml:"-"`
 	IsBuiltin  bool   `yaml:"-"`
+	// Layers are the resolved extends and include templates, in merge order.
+	// Their files sit underneath this template's own files.
+	Layers []*TemplateManifest `yaml:"-"`
+}
+
+// Template sources, as reported by TemplateManifest.Source.
+const (
+	SourceBuiltin = "builtin"
+	SourceLocal   = "local"
+	SourceRemote  = "remote"
+)
+
+// Source reports whether the template is built in, local or from a remote repo.
+func (m *TemplateManifest) Source() string {
+	switch {
+	case m.IsBuiltin:
+		return SourceBuiltin
+	case m.Repo != "":
+		return SourceRemote
+	default:
+		return SourceLocal
+	}
 }
 
-// ApplyDefaults applies safe defaults so templates can omit optional preview fields.
+// QualifiedName returns the template name namespaced by its repo, e.g.
+// owner/repo:template. Built-in and local templates use their plain name.
+func (m *TemplateManifest) QualifiedName() string {
+	if m.Repo == "" {
+		return m.Name
+	}
+	return m.Repo + ":" + m.Name
+}
+
+// ApplyDefaults applies safe defaults so templates can omit optional fields.
 func (m *TemplateManifest) ApplyDefaults() {
+	for i := range m.Prompts {
+		if m.Prompts[i].Type == "" {
+			m.Prompts[i].Type = PromptText
+		}
+	}
 	if m.Preview.NoVNCPort == 0 {
 		m.Preview.NoVNCPort = 6080
 	}
@@ -1,6 +1,10 @@
 package template
 
-import "testing"
+import (
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
 
 func TestApplyDefaultsSetsPreviewPorts(t *testing.T) {
 	manifest := &TemplateManifest{}
@@ -13,3 +17,18 @@ func TestApplyDefaultsSetsPreviewPorts(t *testing.T) {
 		t.Fatalf("expected default vnc port 5900, got %d", manifest.Preview.VNCPort)
 	}
 }
+
+func TestFileRuleModeParsesOctal(t *testing.T) {
+	var rules []FileRule
+	data := "- src: bin/*\n  mode: 0755\n- src: secrets/*\n  mode: \"0600\"\n"
+	if err := yaml.Unmarshal([]byte(data), &rules); err != nil {
+		t.Fatalf("Unmarshal returned error: %v", err)
+	}
+	if rules[0].Mode != 0755 || rules[1].Mode != 0600 {
+		t.Fatalf("expected modes 0755 and 0600, got %o and %o", rules[0].Mode, rules[1].Mode)
+	}
+
+	if err := yaml.Unmarshal([]byte("- src: x\n  mode: rwx\n"), &rules); err == nil {
+		t.Fatalf("expected an invalid mode to be rejected")
+	}
+}
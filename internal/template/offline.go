@@ -0,0 +1,38 @@
+package template
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// OfflineEnv is the environment variable that turns on offline mode, like
+// the --offline flag.
+const OfflineEnv = "INCUBATOR_OFFLINE"
+
+// ErrOffline is returned by anything that would need the network while
+// offline mode is on.
+var ErrOffline = errors.New("offline mode is on (--offline or " + OfflineEnv + "), not touching the network")
+
+var offline bool
+
+// SetOffline turns offline mode on or off for the process. In offline mode
+// templates come from the cache only and nothing is fetched.
+func SetOffline(on bool) {
+	offline = on
+}
+
+// Offline reports whether offline mode is on, through SetOffline or
+// INCUBATOR_OFFLINE set to anything but an empty or false value such as 0.
+func Offline() bool {
+	if offline {
+		return true
+	}
+	value := strings.TrimSpace(os.Getenv(OfflineEnv))
+	if value == "" {
+		return false
+	}
+	on, err := strconv.ParseBool(value)
+	return err != nil || on
+}
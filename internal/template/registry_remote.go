@@ -22,6 +22,9 @@ type CommunityRegistry struct {
 
 // FetchCommunityRegistry fetches the community template registry from GitHub
 func FetchCommunityRegistry() (*CommunityRegistry, error) {
+	if Offline() {
+		return nil, ErrOffline
+	}
 	url := "https://raw.githubusercontent.com/HungSloth/sloth-incubator/main/community-templates.json"
 
 	resp, err := http.Get(url)
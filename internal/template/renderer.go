@@ -1,12 +1,18 @@
 package template
 
 import (
+	"bytes"
 	"fmt"
 	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
 	"text/template"
+	"time"
+	"unicode/utf8"
 )
 
 // Renderer handles template rendering
@@ -15,6 +21,12 @@ type Renderer struct {
 	answers  map[string]interface{}
 	// SkipExisting avoids overwriting files that are already present.
 	SkipExisting bool
+	// LicenseHolder is the copyright holder written to LICENSE when the answers
+	// have no author, usually the configured GitHub user.
+	LicenseHolder string
+	// Now is the clock behind `now`, `year` and the LICENSE year. Nil means
+	// time.Now; template tests pin it so golden files stay stable.
+	Now func() time.Time
 }
 
 // NewRenderer creates a new template renderer
@@ -25,9 +37,66 @@ func NewRenderer(manifest *TemplateManifest, answers map[string]interface{}) *Re
 	}
 }
 
-// RenderTo renders the template to the target directory using the provided filesystem
-func (r *Renderer) RenderTo(targetDir string, sourceFS fs.FS) error {
-	return fs.WalkDir(sourceFS, ".", func(path string, d fs.DirEntry, err error) error {
+// defaultFileMode is the permission of generated files and of sources that
+// carry no permission bits.
+const defaultFileMode fs.FileMode = 0644
+
+// RenderedFile is a single file produced by rendering a template.
+type RenderedFile struct {
+	// Path is slash-separated and relative to the project root.
+	Path    string
+	Content []byte
+	Mode    fs.FileMode
+	// Source is the path of the template file it was rendered from. It is empty
+	// for files generated from the manifest, such as devcontainer.json.
+	Source string
+	// Rule is the file rule that included the file, or nil if no rule covers it.
+	Rule *FileRule
+	// Binary files are copied byte-for-byte and never run through text/template.
+	Binary bool
+}
+
+// RenderResult is an in-memory render of a template.
+type RenderResult struct {
+	// Files are sorted by path.
+	Files []RenderedFile
+	// Dirs lists directories that are empty in the template and would not be
+	// created by writing Files.
+	Dirs []string
+}
+
+// Paths returns the path of every rendered file.
+func (res RenderResult) Paths() []string {
+	paths := make([]string, len(res.Files))
+	for i, f := range res.Files {
+		paths[i] = f.Path
+	}
+	return paths
+}
+
+// File returns the rendered file at path.
+func (res RenderResult) File(path string) (RenderedFile, bool) {
+	i := sort.Search(len(res.Files), func(i int) bool { return res.Files[i].Path >= path })
+	if i < len(res.Files) && res.Files[i].Path == path {
+		return res.Files[i], true
+	}
+	return RenderedFile{}, false
+}
+
+// Render renders the template from sourceFS in memory without touching disk.
+func (r *Renderer) Render(sourceFS fs.FS) (RenderResult, error) {
+	var result RenderResult
+	index := make(map[string]int)
+	add := func(file RenderedFile) {
+		if i, ok := index[file.Path]; ok {
+			result.Files[i] = file
+			return
+		}
+		index[file.Path] = len(result.Files)
+		result.Files = append(result.Files, file)
+	}
+
+	err := fs.WalkDir(sourceFS, ".", func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
@@ -38,19 +107,40 @@ func (r *Renderer) RenderTo(targetDir string, sourceFS fs.FS) error {
 		}
 
 		// Check conditional file inclusion
-		if !r.shouldInclude(path) {
+		rule, include, err := r.includeRule(path)
+		if err != nil {
+			return err
+		}
+
+		// Expand template variables in path names; an empty segment drops the entry
+		targetPath, keep, err := r.expandPath(path)
+		if err != nil {
+			return fmt.Errorf("expanding path %s: %w", path, err)
+		}
+		if !keep {
 			if d.IsDir() {
 				return fs.SkipDir
 			}
 			return nil
 		}
 
-		// Expand template variables in path names
-		expandedPath := r.expandPath(path)
-		targetPath := filepath.Join(targetDir, expandedPath)
-
 		if d.IsDir() {
-			return os.MkdirAll(targetPath, 0755)
+			// Directories are created for the files in them. Only directories that
+			// are empty in the template need to be listed; excluded ones are still
+			// walked because a later rule may include files inside them.
+			if include {
+				entries, err := fs.ReadDir(sourceFS, path)
+				if err != nil {
+					return fmt.Errorf("reading template directory %s: %w", path, err)
+				}
+				if len(entries) == 0 {
+					result.Dirs = append(result.Dirs, targetPath)
+				}
+			}
+			return nil
+		}
+		if !include {
+			return nil
 		}
 
 		// Read source file
@@ -59,16 +149,106 @@ func (r *Renderer) RenderTo(targetDir string, sourceFS fs.FS) error {
 			return fmt.Errorf("reading template file %s: %w", path, err)
 		}
 
+		mode, err := fileMode(rule, d)
+		if err != nil {
+			return fmt.Errorf("reading template file %s: %w", path, err)
+		}
+		binary := IsBinary(content)
+
 		// Process .tmpl files through text/template
 		if strings.HasSuffix(path, ".tmpl") {
 			targetPath = strings.TrimSuffix(targetPath, ".tmpl")
-			processed, err := r.processTemplate(path, string(content))
-			if err != nil {
-				return fmt.Errorf("processing template %s: %w", path, err)
+			if !binary {
+				processed, err := r.processTemplate(path, string(content))
+				if err != nil {
+					return fmt.Errorf("processing template %s: %w", path, err)
+				}
+				content = []byte(processed)
 			}
-			content = []byte(processed)
 		}
 
+		add(RenderedFile{Path: targetPath, Content: content, Mode: mode, Source: path, Rule: rule, Binary: binary})
+		return nil
+	})
+	if err != nil {
+		return RenderResult{}, err
+	}
+
+	// Merge the manifest's devcontainer section into the rendered file, if any.
+	if !r.manifest.Devcontainer.IsEmpty() {
+		file := RenderedFile{Path: DevcontainerPath, Mode: defaultFileMode}
+		if i, ok := index[DevcontainerPath]; ok {
+			file = result.Files[i]
+		}
+		content, err := RenderDevcontainer(r.manifest.Devcontainer, r.answers, file.Content)
+		if err != nil {
+			return RenderResult{}, err
+		}
+		file.Content = content
+		add(file)
+	}
+
+	if err := r.renderGitignore(&result, add); err != nil {
+		return RenderResult{}, err
+	}
+	if err := r.renderLicense(&result, add); err != nil {
+		return RenderResult{}, err
+	}
+
+	sort.Slice(result.Files, func(i, j int) bool { return result.Files[i].Path < result.Files[j].Path })
+	return result, nil
+}
+
+// fileMode picks the permissions for a rendered file: the rule's mode if it sets
+// one, otherwise the source file's permission bits.
+func fileMode(rule *FileRule, d fs.DirEntry) (fs.FileMode, error) {
+	if rule != nil && rule.Mode != 0 {
+		return fs.FileMode(rule.Mode), nil
+	}
+	info, err := d.Info()
+	if err != nil {
+		return 0, err
+	}
+	if perm := info.Mode().Perm(); perm != 0 {
+		return perm, nil
+	}
+	return defaultFileMode, nil
+}
+
+// binarySniffLen is how much of a file is searched for NUL bytes.
+const binarySniffLen = 8000
+
+// IsBinary reports whether data looks like a binary file such as an image or a
+// font: it has a NUL byte near the start or is not valid UTF-8.
+func IsBinary(data []byte) bool {
+	sniff := data
+	if len(sniff) > binarySniffLen {
+		sniff = sniff[:binarySniffLen]
+	}
+	return bytes.IndexByte(sniff, 0) >= 0 || !utf8.Valid(data)
+}
+
+// RenderTo renders the template to the target directory using the provided filesystem
+func (r *Renderer) RenderTo(targetDir string, sourceFS fs.FS) error {
+	result, err := r.Render(sourceFS)
+	if err != nil {
+		return err
+	}
+	return r.Write(targetDir, result)
+}
+
+// Write creates a rendered tree under targetDir, leaving existing files alone if
+// SkipExisting is set.
+func (r *Renderer) Write(targetDir string, result RenderResult) error {
+	for _, dir := range result.Dirs {
+		if err := os.MkdirAll(filepath.Join(targetDir, filepath.FromSlash(dir)), 0755); err != nil {
+			return err
+		}
+	}
+
+	for _, file := range result.Files {
+		targetPath := filepath.Join(targetDir, filepath.FromSlash(file.Path))
+
 		// Ensure parent directory exists
 		if err := os.MkdirAll(filepath.Dir(targetPath), 0755); err != nil {
 			return err
@@ -76,60 +256,91 @@ func (r *Renderer) RenderTo(targetDir string, sourceFS fs.FS) error {
 
 		if r.SkipExisting {
 			if _, err := os.Stat(targetPath); err == nil {
-				return nil
+				continue
 			} else if !os.IsNotExist(err) {
 				return err
 			}
 		}
 
-		return os.WriteFile(targetPath, content, 0644)
-	})
-}
-
-// shouldInclude checks if a file/directory should be included based on manifest rules
-func (r *Renderer) shouldInclude(path string) bool {
-	// If no file rules defined, include everything
-	if len(r.manifest.Files) == 0 {
-		return true
+		if err := os.WriteFile(targetPath, file.Content, file.Mode); err != nil {
+			return err
+		}
+		// WriteFile leaves the mode of an existing file alone and applies the
+		// umask to a new one.
+		if err := os.Chmod(targetPath, file.Mode); err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	for _, rule := range r.manifest.Files {
-		if rule.Always {
-			if matchesGlob(rule.Src, path) {
-				return true
-			}
+// includeRule decides whether a source file or directory is rendered. Rules are
+// applied in order and the last one that matches the path wins:
+//
+//   - `src: pattern` includes matching paths when its condition holds (`always`,
+//     a truthy `when`, or neither) and excludes them otherwise
+//   - `src: "!pattern"` excludes matching paths when its condition holds and has
+//     no effect otherwise
+//
+// Paths that no rule covers are included. The returned rule is the one that
+// included the path, or nil if no rule covers it.
+func (r *Renderer) includeRule(path string) (*FileRule, bool, error) {
+	var decided *FileRule
+	include := true
+	for i := range r.manifest.Files {
+		rule := &r.manifest.Files[i]
+		pattern, negated := rule.Pattern()
+		matched, err := matchGlob(pattern, path)
+		if err == nil && !matched && strings.HasSuffix(path, ".tmpl") {
+			// Rules may name a .tmpl file by its rendered name.
+			matched, err = matchGlob(pattern, strings.TrimSuffix(path, ".tmpl"))
+		}
+		if err != nil {
+			return nil, false, fmt.Errorf("file rule %d: %w", i+1, err)
+		}
+		if !matched {
 			continue
 		}
 
-		if rule.When != "" {
-			// Evaluate the when condition as a Go template
-			if r.evaluateCondition(rule.When) && matchesGlob(rule.Src, path) {
-				return true
-			}
+		holds := r.ruleApplies(*rule)
+		switch {
+		case negated && holds:
+			decided, include = nil, false
+		case !negated:
+			decided, include = rule, holds
 		}
 	}
-
-	// If rules are defined but none match, still include files not covered by any rule
-	// This handles the case where rules only cover specific directories
-	for _, rule := range r.manifest.Files {
-		expandedSrc := r.expandPath(rule.Src)
-		if matchesGlob(expandedSrc, path) {
-			return false // A rule covers this path but conditions weren't met
-		}
+	if !include {
+		return nil, false, nil
 	}
+	return decided, true, nil
+}
 
-	return true // No rule covers this path, include by default
+// ruleApplies reports whether a rule's condition holds. A rule with neither
+// `always` nor `when` applies unconditionally.
+func (r *Renderer) ruleApplies(rule FileRule) bool {
+	if rule.Always || rule.When == "" {
+		return true
+	}
+	return r.evaluateCondition(rule.When)
 }
 
 // evaluateCondition evaluates a when condition template expression
 func (r *Renderer) evaluateCondition(condition string) bool {
-	tmpl, err := template.New("condition").Option("missingkey=zero").Parse(condition)
+	return EvaluateCondition(condition, r.answers)
+}
+
+// EvaluateCondition executes a `when` expression against the answers and reports
+// whether it rendered a truthy value. Empty output, "false", "0" and parse or
+// execution errors all count as false.
+func EvaluateCondition(condition string, answers map[string]interface{}) bool {
+	tmpl, err := template.New("condition").Funcs(templateFuncs()).Option("missingkey=zero").Parse(condition)
 	if err != nil {
 		return false
 	}
 
 	var buf strings.Builder
-	if err := tmpl.Execute(&buf, r.answers); err != nil {
+	if err := tmpl.Execute(&buf, answers); err != nil {
 		return false
 	}
 
@@ -137,46 +348,85 @@ func (r *Renderer) evaluateCondition(condition string) bool {
 	return result != "" && result != "false" && result != "0" && result != "<no value>"
 }
 
-// matchesGlob checks if a path matches a glob pattern
-func matchesGlob(pattern, path string) bool {
-	// Handle ** glob patterns
-	if strings.Contains(pattern, "**") {
-		prefix := strings.Split(pattern, "**")[0]
-		return strings.HasPrefix(path, prefix)
+// legacyPlaceholder matches the {{name}} form of path variables, which predates
+// full template expressions in paths.
+var legacyPlaceholder = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// templateKeywords are bare words legacyPlaceholder must not turn into fields.
+var templateKeywords = map[string]bool{
+	"if": true, "else": true, "end": true, "range": true, "with": true, "define": true,
+	"template": true, "block": true, "break": true, "continue": true, "nil": true,
+	"true": true, "false": true,
+}
+
+// expandPath renders every segment of a template path with the same engine and
+// functions as file contents, e.g. cmd/{{.project_name | kebab}}. ok is false
+// when a segment renders empty, which drops the file or directory.
+func (r *Renderer) expandPath(p string) (expanded string, ok bool, err error) {
+	segments := splitPathSegments(p)
+	for i, segment := range segments {
+		if !strings.Contains(segment, "{{") {
+			continue
+		}
+		rendered, err := r.processTemplate(p, r.legacyPathSegment(segment))
+		if err != nil {
+			return "", false, err
+		}
+		rendered = strings.ReplaceAll(rendered, "<no value>", "")
+		if strings.TrimSpace(rendered) == "" {
+			return "", false, nil
+		}
+		segments[i] = rendered
 	}
 
-	matched, _ := filepath.Match(pattern, path)
-	if matched {
-		return true
+	expanded = path.Clean(strings.Join(segments, "/"))
+	if expanded == ".." || strings.HasPrefix(expanded, "../") || path.IsAbs(expanded) {
+		return "", false, fmt.Errorf("%q expands to %q, which is outside the project", p, expanded)
 	}
+	return expanded, true, nil
+}
 
-	// Also check if the path is within the pattern's directory
-	dir := filepath.Dir(pattern)
-	return strings.HasPrefix(path, dir+"/")
+// legacyPathSegment rewrites {{name}} placeholders in a path segment to
+// {{.name}}. A name that is both an answer and a function, such as a prompt
+// called `title`, means the answer; other function names stay calls.
+func (r *Renderer) legacyPathSegment(segment string) string {
+	funcs := r.funcs()
+	return legacyPlaceholder.ReplaceAllStringFunc(segment, func(match string) string {
+		name := legacyPlaceholder.FindStringSubmatch(match)[1]
+		if _, isAnswer := r.answers[name]; isAnswer && !templateKeywords[name] {
+			return "{{." + name + "}}"
+		}
+		if _, isFunc := funcs[name]; isFunc || templateKeywords[name] {
+			return match
+		}
+		return "{{." + name + "}}"
+	})
 }
 
-// expandPath replaces {{variable}} patterns in path names
-func (r *Renderer) expandPath(path string) string {
-	result := path
-	for key, val := range r.answers {
-		placeholder := "{{" + key + "}}"
-		result = strings.ReplaceAll(result, placeholder, fmt.Sprintf("%v", val))
+// splitPathSegments splits a slash-separated path, ignoring slashes inside
+// {{ }} actions so expressions like {{replace "/" "-" .x}} stay whole.
+func splitPathSegments(p string) []string {
+	var segments []string
+	depth, start := 0, 0
+	for i := 0; i < len(p); i++ {
+		switch {
+		case strings.HasPrefix(p[i:], "{{"):
+			depth++
+			i++
+		case strings.HasPrefix(p[i:], "}}") && depth > 0:
+			depth--
+			i++
+		case p[i] == '/' && depth == 0:
+			segments = append(segments, p[start:i])
+			start = i + 1
+		}
 	}
-	return result
+	return append(segments, p[start:])
 }
 
 // processTemplate processes a single template file
 func (r *Renderer) processTemplate(name, content string) (string, error) {
-	funcMap := template.FuncMap{
-		"ne": func(a, b interface{}) bool {
-			return fmt.Sprintf("%v", a) != fmt.Sprintf("%v", b)
-		},
-		"eq": func(a, b interface{}) bool {
-			return fmt.Sprintf("%v", a) == fmt.Sprintf("%v", b)
-		},
-	}
-
-	tmpl, err := template.New(name).Funcs(funcMap).Option("missingkey=zero").Parse(content)
+	tmpl, err := template.New(name).Funcs(r.funcs()).Option("missingkey=zero").Parse(content)
 	if err != nil {
 		return "", err
 	}
@@ -189,25 +439,37 @@ func (r *Renderer) processTemplate(name, content string) (string, error) {
 	return buf.String(), nil
 }
 
+// funcs returns the template functions with the renderer's clock.
+func (r *Renderer) funcs() template.FuncMap {
+	funcs := templateFuncs()
+	if r.Now != nil {
+		funcs["now"] = r.Now
+		funcs["year"] = func() int { return r.Now().Year() }
+	}
+	return funcs
+}
+
+func (r *Renderer) now() time.Time {
+	if r.Now != nil {
+		return r.Now()
+	}
+	return time.Now()
+}
+
 // ListFiles returns the list of files that would be created
 func (r *Renderer) ListFiles(sourceFS fs.FS) ([]string, error) {
-	var files []string
-	err := fs.WalkDir(sourceFS, ".", func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return err
-		}
-		if path == "." || d.IsDir() {
-			return nil
-		}
-		if !r.shouldInclude(path) {
-			return nil
-		}
-		expandedPath := r.expandPath(path)
-		if strings.HasSuffix(expandedPath, ".tmpl") {
-			expandedPath = strings.TrimSuffix(expandedPath, ".tmpl")
+	result, err := r.Render(sourceFS)
+	if err != nil {
+		return nil, err
+	}
+	return result.Paths(), nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
 		}
-		files = append(files, expandedPath)
-		return nil
-	})
-	return files, err
+	}
+	return false
 }
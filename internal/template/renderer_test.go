@@ -0,0 +1,213 @@
+package template
+
+import (
+	"bytes"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestRenderBuildsTreeInMemory(t *testing.T) {
+	manifest := &TemplateManifest{
+		Name: "test",
+		Files: []FileRule{
+			{Src: "docs/**", When: "{{if .with_docs}}true{{end}}"},
+			{Src: "ci/**", When: "{{if .with_ci}}true{{end}}"},
+		},
+	}
+	sourceFS := fstest.MapFS{
+		"README.md.tmpl":       {Data: []byte("# {{.project_name}}\n")},
+		"cmd/{{project_name}}": {Data: []byte("main\n")},
+		"docs/index.md":        {Data: []byte("docs\n")},
+		"ci/build.yaml":        {Data: []byte("ci\n")},
+	}
+	renderer := NewRenderer(manifest, map[string]interface{}{"project_name": "demo", "with_docs": true})
+
+	result, err := renderer.Render(sourceFS)
+	if err != nil {
+		t.Fatalf("Render returned error: %v", err)
+	}
+
+	paths := result.Paths()
+	want := []string{"README.md", "cmd/demo", "docs/index.md"}
+	if len(paths) != len(want) {
+		t.Fatalf("expected %v, got %v", want, paths)
+	}
+	for i := range want {
+		if paths[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, paths)
+		}
+	}
+
+	readme, ok := result.File("README.md")
+	if !ok || string(readme.Content) != "# demo\n" || readme.Source != "README.md.tmpl" || readme.Mode != 0644 {
+		t.Fatalf("unexpected README.md: %+v", readme)
+	}
+	if readme.Rule != nil {
+		t.Fatalf("expected README.md not to be covered by a rule, got %+v", readme.Rule)
+	}
+	docs, _ := result.File("docs/index.md")
+	if docs.Rule == nil || docs.Rule.Src != "docs/**" {
+		t.Fatalf("expected docs/index.md to record its rule, got %+v", docs.Rule)
+	}
+	if _, ok := result.File("ci/build.yaml"); ok {
+		t.Fatalf("expected ci/build.yaml to be excluded")
+	}
+}
+
+func TestRenderKeepsModesAndCopiesBinaryFiles(t *testing.T) {
+	manifest := &TemplateManifest{
+		Name:  "test",
+		Files: []FileRule{{Src: "hooks/*", Always: true, Mode: 0700}},
+	}
+	logo := []byte("\x89PNG\r\n\x1a\n\x00\x00{{.project_name}}")
+	sourceFS := fstest.MapFS{
+		"run.sh":        {Data: []byte("#!/bin/sh\n"), Mode: 0755},
+		"hooks/pre":     {Data: []byte("#!/bin/sh\n"), Mode: 0644},
+		"logo.png.tmpl": {Data: logo},
+		"notes.txt":     {Data: []byte("notes\n"), Mode: 0444},
+		"secret.env":    {Data: []byte("KEY=\n"), Mode: 0600},
+		"tool.sh":       {Data: []byte("#!/bin/sh\n"), Mode: 0750},
+	}
+
+	result, err := NewRenderer(manifest, map[string]interface{}{"project_name": "demo"}).Render(sourceFS)
+	if err != nil {
+		t.Fatalf("Render returned error: %v", err)
+	}
+
+	modes := map[string]fs.FileMode{"run.sh": 0755, "hooks/pre": 0700, "notes.txt": 0444, "logo.png": 0644, "secret.env": 0600, "tool.sh": 0750}
+	for path, mode := range modes {
+		file, ok := result.File(path)
+		if !ok || file.Mode != mode {
+			t.Fatalf("expected %s with mode %o, got %+v", path, mode, file)
+		}
+	}
+	file, _ := result.File("logo.png")
+	if !file.Binary || !bytes.Equal(file.Content, logo) {
+		t.Fatalf("expected logo.png to be copied byte-for-byte, got %q", file.Content)
+	}
+}
+
+func TestWriteSetsModesOfExistingFiles(t *testing.T) {
+	targetDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(targetDir, "run.sh"), []byte("old\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	manifest := &TemplateManifest{Name: "test", Files: []FileRule{{Src: "run.sh", Mode: 0755}}}
+	sourceFS := fstest.MapFS{"run.sh": {Data: []byte("#!/bin/sh\n"), Mode: 0644}}
+	if err := NewRenderer(manifest, nil).RenderTo(targetDir, sourceFS); err != nil {
+		t.Fatalf("RenderTo returned error: %v", err)
+	}
+	info, err := os.Stat(filepath.Join(targetDir, "run.sh"))
+	if err != nil || info.Mode().Perm() != 0755 {
+		t.Fatalf("expected run.sh to be made 0755, got %v (%v)", info.Mode(), err)
+	}
+
+	// Embedded files are read-only in the binary but rendered writable.
+	embedded, err := GetEmbeddedEmptyTemplate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := NewRenderer(GetBuiltinManifest(), map[string]interface{}{"project_name": "demo"}).Render(embedded)
+	if err != nil {
+		t.Fatalf("Render returned error: %v", err)
+	}
+	if readme, ok := result.File("README.md"); !ok || readme.Mode != 0644 {
+		t.Fatalf("expected the built-in README.md with mode 0644, got %+v", readme)
+	}
+}
+
+func TestExpandPath(t *testing.T) {
+	renderer := NewRenderer(&TemplateManifest{Name: "test"}, map[string]interface{}{
+		"project_name": "My App",
+		"use_docker":   false,
+		"module":       "github.com/me/app",
+	})
+	tests := []struct {
+		path string
+		want string
+		keep bool
+	}{
+		{"README.md", "README.md", true},
+		{"{{project_name}}/main.go", "My App/main.go", true},
+		{"cmd/{{.project_name | kebab}}/main.go", "cmd/my-app/main.go", true},
+		{"{{if .use_docker}}docker{{end}}/Dockerfile", "", false},
+		{"src/{{if .use_docker}}Dockerfile{{end}}", "", false},
+		{"{{missing}}/file", "", false},
+		{"{{.module | replace \"/\" \"_\"}}.txt", "github.com_me_app.txt", true},
+		{"LICENSE-{{year}}", "LICENSE-" + strconv.Itoa(time.Now().Year()), true},
+	}
+	for _, tt := range tests {
+		got, keep, err := renderer.expandPath(tt.path)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.path, err)
+		}
+		if got != tt.want || keep != tt.keep {
+			t.Fatalf("%s: expected (%q, %v), got (%q, %v)", tt.path, tt.want, tt.keep, got, keep)
+		}
+	}
+
+	// A prompt named like a function is still an answer in the {{name}} form.
+	named := NewRenderer(&TemplateManifest{Name: "test"}, map[string]interface{}{"title": "Getting Started"})
+	result, err := named.Render(fstest.MapFS{"docs/{{title}}.md": {Data: []byte("# docs\n")}, "{{year}}.txt": {Data: []byte("\n")}})
+	if err != nil {
+		t.Fatalf("Render returned error: %v", err)
+	}
+	for _, path := range []string{"docs/Getting Started.md", strconv.Itoa(time.Now().Year()) + ".txt"} {
+		if _, ok := result.File(path); !ok {
+			t.Fatalf("expected %s in %v", path, result.Paths())
+		}
+	}
+
+	escaping := NewRenderer(&TemplateManifest{Name: "test"}, map[string]interface{}{"dir": "../.."})
+	if _, _, err := escaping.expandPath("{{.dir}}/x"); err == nil {
+		t.Fatalf("expected a path outside the project to be rejected")
+	}
+}
+
+func TestRenderDropsDirectoriesWithEmptyNames(t *testing.T) {
+	sourceFS := fstest.MapFS{
+		"{{if .with_docs}}docs{{end}}/index.md": {Data: []byte("docs\n")},
+		"{{if .with_docs}}docs{{end}}/guide.md": {Data: []byte("guide\n")},
+		"{{if .with_ci}}.github{{end}}/ci.yaml": {Data: []byte("ci\n")},
+	}
+	result, err := NewRenderer(&TemplateManifest{Name: "test"}, map[string]interface{}{"with_docs": true}).Render(sourceFS)
+	if err != nil {
+		t.Fatalf("Render returned error: %v", err)
+	}
+	paths := result.Paths()
+	if len(paths) != 2 || paths[0] != "docs/guide.md" || paths[1] != "docs/index.md" {
+		t.Fatalf("expected only the docs files, got %v", paths)
+	}
+	for _, dir := range result.Dirs {
+		if dir != "docs" {
+			t.Fatalf("expected only the docs directory, got %v", result.Dirs)
+		}
+	}
+}
+
+func TestRenderPicksGitignoreFragmentsFromAnswers(t *testing.T) {
+	manifest := &TemplateManifest{Name: "test", Gitignore: []string{"os", `{{join "," .languages}}`}}
+	sourceFS := fstest.MapFS{".gitignore": {Data: []byte("/local/\n")}}
+	answers := map[string]interface{}{"languages": []interface{}{"Go", "node", "cobol"}}
+
+	result, err := NewRenderer(manifest, answers).Render(sourceFS)
+	if err != nil {
+		t.Fatalf("Render returned error: %v", err)
+	}
+	file, _ := result.File(GitignorePath)
+	for _, want := range []string{"/local/", ".DS_Store", "*.test", "node_modules/"} {
+		if !bytes.Contains(file.Content, []byte(want)) {
+			t.Fatalf("expected %s in .gitignore:\n%s", want, file.Content)
+		}
+	}
+
+	manifest.Gitignore = []string{"cobol"}
+	if _, err := NewRenderer(manifest, answers).Render(sourceFS); err == nil {
+		t.Fatalf("expected an unknown literal fragment to fail")
+	}
+}
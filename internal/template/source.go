@@ -5,10 +5,40 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
 )
 
 // ResolveTemplateFS resolves the filesystem for either built-in or remote templates.
+// Remote manifests remember their repo; templateRepo is only a fallback for
+// manifests that were constructed without one. Composed templates get their
+// layers' files underneath their own, each resolved from its own source.
 func ResolveTemplateFS(manifest *TemplateManifest, cacheDir, templateRepo string) (fs.FS, error) {
+	own, err := resolveOwnFS(manifest, cacheDir, templateRepo)
+	if err != nil || manifest == nil {
+		return own, err
+	}
+	return layerFS(manifest, own, cacheDir)
+}
+
+// layerFS stacks top over the files of the manifest's layers.
+func layerFS(manifest *TemplateManifest, top fs.FS, cacheDir string) (fs.FS, error) {
+	if len(manifest.Layers) == 0 {
+		return top, nil
+	}
+	layers := make(overlayFS, 0, len(manifest.Layers)+1)
+	for _, layer := range manifest.Layers {
+		files, err := resolveOwnFS(layer, cacheDir, "")
+		if err != nil {
+			return nil, fmt.Errorf("loading %s: %w", layer.QualifiedName(), err)
+		}
+		layers = append(layers, files)
+	}
+	return append(layers, top), nil
+}
+
+func resolveOwnFS(manifest *TemplateManifest, cacheDir, templateRepo string) (fs.FS, error) {
 	if manifest == nil || manifest.IsBuiltin || manifest.SourcePath == "" {
 		return GetEmbeddedEmptyTemplate()
 	}
@@ -21,6 +51,106 @@ func ResolveTemplateFS(manifest *TemplateManifest, cacheDir, templateRepo string
 		return os.DirFS(filesDir), nil
 	}
 
+	if manifest.Repo != "" {
+		templateRepo = manifest.Repo
+	}
 	loader := NewLoader(cacheDir, templateRepo)
 	return loader.GetTemplateFS(manifest.SourcePath)
 }
+
+// TemplateDir returns the directory a template was loaded from, or "" for the
+// built-in template.
+func TemplateDir(manifest *TemplateManifest, cacheDir string) string {
+	if manifest == nil || manifest.IsBuiltin || manifest.SourcePath == "" {
+		return ""
+	}
+	if filepath.IsAbs(manifest.SourcePath) {
+		return manifest.SourcePath
+	}
+	return filepath.Join(NewLoader(cacheDir, manifest.Repo).TemplatesDir(), manifest.SourcePath)
+}
+
+// LoadTemplateDir loads the template in dir the way authoring tools see it:
+// the manifest composed with its bases from known, and its files layered over
+// theirs.
+func LoadTemplateDir(dir string, known []*TemplateManifest, cacheDir string) (*TemplateManifest, fs.FS, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "template.yaml"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading template.yaml: %w", err)
+	}
+	var manifest TemplateManifest
+	if err := yaml.Unmarshal(data, &manifest); err != nil {
+		return nil, nil, fmt.Errorf("parsing template.yaml: %w", err)
+	}
+	if manifest.Name == "" {
+		manifest.Name = filepath.Base(dir)
+	}
+	manifest.ApplyDefaults()
+	return composeTemplateDir(&manifest, TemplateFilesFS(dir), known, cacheDir)
+}
+
+// composeTemplateDir resolves a manifest's extends and include against known
+// and layers own, the template's files, over its bases' files.
+func composeTemplateDir(manifest *TemplateManifest, own fs.FS, known []*TemplateManifest, cacheDir string) (*TemplateManifest, fs.FS, error) {
+	if manifest.Extends == "" && len(manifest.Include) == 0 {
+		return manifest, own, nil
+	}
+	layers, err := resolveLayers(manifest, append(append([]*TemplateManifest(nil), known...), manifest), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	composed := composeManifest(manifest, layers)
+	layered, err := layerFS(composed, own, cacheDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	return composed, layered, nil
+}
+
+// TestsDir is the directory in a template holding its `incubator template test`
+// cases. It is never rendered into projects.
+const TestsDir = "tests"
+
+// TemplateFilesFS returns the files of the template in templateDir, from the
+// directory TemplateFilesDir picks. When they sit next to template.yaml, tests/
+// is left out.
+func TemplateFilesFS(templateDir string) fs.FS {
+	filesDir := TemplateFilesDir(templateDir)
+	if filesDir != templateDir {
+		return os.DirFS(filesDir)
+	}
+	return withoutTests(os.DirFS(templateDir))
+}
+
+// withoutTests hides the tests/ directory of a template whose files sit next
+// to template.yaml.
+func withoutTests(fsys fs.FS) fs.FS {
+	return hideDir{FS: fsys, name: TestsDir}
+}
+
+// hideDir leaves one top-level directory out of a filesystem.
+type hideDir struct {
+	fs.FS
+	name string
+}
+
+func (h hideDir) Open(name string) (fs.File, error) {
+	if name == h.name || strings.HasPrefix(name, h.name+"/") {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return h.FS.Open(name)
+}
+
+func (h hideDir) ReadDir(name string) ([]fs.DirEntry, error) {
+	entries, err := fs.ReadDir(h.FS, name)
+	if err != nil || name != "." {
+		return entries, err
+	}
+	kept := entries[:0]
+	for _, entry := range entries {
+		if entry.Name() != h.name {
+			kept = append(kept, entry)
+		}
+	}
+	return kept, nil
+}
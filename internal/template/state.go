@@ -0,0 +1,136 @@
+package template
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// StateFile is the project-relative path where the render state is recorded.
+const StateFile = ".incubator/state.yaml"
+
+// ProjectState links a scaffolded project back to the template it came from so
+// it can be upgraded later.
+type ProjectState struct {
+	Template string                 `yaml:"template"`
+	Repo     string                 `yaml:"repo,omitempty"`
+	Version  string                 `yaml:"version"`
+	Commit   string                 `yaml:"commit,omitempty"`
+	Answers  map[string]interface{} `yaml:"answers"`
+}
+
+// QualifiedName returns the template name in the same form as
+// TemplateManifest.QualifiedName.
+func (s *ProjectState) QualifiedName() string {
+	if s.Repo == "" {
+		return s.Template
+	}
+	return s.Repo + ":" + s.Template
+}
+
+// NewProjectState records the manifest and answers used for a render. Secret
+// answers are left out.
+func NewProjectState(manifest *TemplateManifest, answers map[string]interface{}, cacheDir string) *ProjectState {
+	return &ProjectState{
+		Template: manifest.Name,
+		Repo:     manifest.Repo,
+		Version:  manifest.Version,
+		Commit:   TemplateCommit(manifest, cacheDir),
+		Answers:  PersistableAnswers(manifest, answers),
+	}
+}
+
+// ReadState loads .incubator/state.yaml from a project directory.
+func ReadState(projectDir string) (*ProjectState, error) {
+	data, err := os.ReadFile(filepath.Join(projectDir, filepath.FromSlash(StateFile)))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%s not found in %s: the project was not created by incubator or predates state tracking", StateFile, projectDir)
+		}
+		return nil, fmt.Errorf("reading %s: %w", StateFile, err)
+	}
+
+	var state ProjectState
+	if err := yaml.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", StateFile, err)
+	}
+	if state.Answers == nil {
+		state.Answers = map[string]interface{}{}
+	}
+	return &state, nil
+}
+
+// WriteState writes .incubator/state.yaml into a project directory.
+func WriteState(projectDir string, state *ProjectState) error {
+	statePath := filepath.Join(projectDir, filepath.FromSlash(StateFile))
+	if err := os.MkdirAll(filepath.Dir(statePath), 0755); err != nil {
+		return fmt.Errorf("creating state directory: %w", err)
+	}
+
+	data, err := yaml.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("marshaling state: %w", err)
+	}
+	header := "# Written by incubator. Used by `incubator upgrade`; commit it with the project.\n"
+	return os.WriteFile(statePath, append([]byte(header), data...), 0644)
+}
+
+// TemplateCommit returns the git commit the template is rendered from, or "" for
+// built-in templates and local templates outside a git repository. A repo
+// cache that is not a git checkout, such as a downloaded archive, reports the
+// commit recorded in the lockfile.
+func TemplateCommit(manifest *TemplateManifest, cacheDir string) string {
+	dir := templateRepoDir(manifest, cacheDir)
+	if dir == "" {
+		return ""
+	}
+	// Local templates may live anywhere inside a repository; a repo cache must
+	// be a checkout of its own, or git would answer for a parent repository.
+	if !filepath.IsAbs(manifest.SourcePath) && !isGitTopLevel(dir) {
+		lock, err := ReadLock(cacheDir)
+		if err != nil {
+			return ""
+		}
+		return lock.Repos[manifest.Repo].Commit
+	}
+	cmd := exec.Command("git", "rev-parse", "HEAD")
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// isGitTopLevel reports whether dir is the top level of a git checkout rather
+// than a directory inside one.
+func isGitTopLevel(dir string) bool {
+	top, err := gitOutput(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return false
+	}
+	resolvedTop, err := filepath.EvalSymlinks(filepath.FromSlash(top))
+	if err != nil {
+		return false
+	}
+	resolvedDir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return false
+	}
+	return resolvedTop == resolvedDir
+}
+
+// templateRepoDir returns the directory holding the template's git checkout.
+func templateRepoDir(manifest *TemplateManifest, cacheDir string) string {
+	if manifest == nil || manifest.IsBuiltin || manifest.SourcePath == "" {
+		return ""
+	}
+	if filepath.IsAbs(manifest.SourcePath) {
+		return manifest.SourcePath
+	}
+	return NewLoader(cacheDir, manifest.Repo).TemplatesDir()
+}
@@ -0,0 +1,150 @@
+package template
+
+import (
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// namedValidator checks a single answer and explains what a valid value looks like.
+type namedValidator struct {
+	pattern *regexp.Regexp
+	check   func(string) bool
+	message string
+}
+
+func (v namedValidator) valid(value string) bool {
+	if v.check != nil {
+		return v.check(value)
+	}
+	return v.pattern.MatchString(value)
+}
+
+// namedValidators are the validators a prompt can reference by name in `validate`.
+// Any other value is treated as a regular expression.
+var namedValidators = map[string]namedValidator{
+	"slug": {
+		pattern: regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`),
+		message: "must be a lowercase slug (letters, digits and single dashes)",
+	},
+	"semver": {
+		pattern: regexp.MustCompile(`^v?(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)(-[0-9A-Za-z-]+(\.[0-9A-Za-z-]+)*)?(\+[0-9A-Za-z-]+(\.[0-9A-Za-z-]+)*)?$`),
+		message: "must be a semantic version like 1.2.3",
+	},
+	"email": {
+		check: func(value string) bool {
+			addr, err := mail.ParseAddress(value)
+			return err == nil && addr.Address == value
+		},
+		message: "must be an email address like name@example.com",
+	},
+	"go-module-path": {
+		check:   validGoModulePath,
+		message: "must be a Go module path like github.com/owner/repo",
+	},
+	"npm-package-name": {
+		check: func(value string) bool {
+			return len(value) <= 214 && npmPackagePattern.MatchString(value)
+		},
+		message: "must be a valid npm package name (lowercase, optionally @scope/name)",
+	},
+}
+
+var (
+	npmPackagePattern     = regexp.MustCompile(`^(@[a-z0-9][a-z0-9._~-]*/)?[a-z0-9][a-z0-9._~-]*$`)
+	goModuleElemPattern   = regexp.MustCompile(`^[A-Za-z0-9._~-]+$`)
+	goModuleDomainPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?(\.[a-z0-9]([a-z0-9-]*[a-z0-9])?)*$`)
+)
+
+// validGoModulePath applies the main rules from golang.org/x/mod/module.CheckPath:
+// slash-separated elements of safe characters, no empty or dot-only elements, and a
+// first element that looks like a lowercase domain name.
+func validGoModulePath(value string) bool {
+	if value == "" || strings.HasPrefix(value, "/") || strings.HasSuffix(value, "/") {
+		return false
+	}
+	elems := strings.Split(value, "/")
+	for _, elem := range elems {
+		if elem == "" || elem == "." || elem == ".." || strings.HasPrefix(elem, ".") || strings.HasSuffix(elem, ".") {
+			return false
+		}
+		if !goModuleElemPattern.MatchString(elem) {
+			return false
+		}
+	}
+	first := elems[0]
+	return strings.Contains(first, ".") && goModuleDomainPattern.MatchString(first)
+}
+
+// ValidatorNames returns the names usable in a prompt's `validate` field.
+func ValidatorNames() []string {
+	return []string{"slug", "semver", "email", "go-module-path", "npm-package-name"}
+}
+
+// ValidateAnswer checks a single answer against the prompt's `required` and
+// `validate` settings. Empty optional answers are always valid.
+func ValidateAnswer(p Prompt, value interface{}) error {
+	if isEmptyAnswer(value) {
+		if p.Required {
+			return fmt.Errorf("is required")
+		}
+		return nil
+	}
+
+	if p.Type == PromptNumber {
+		if err := validateNumber(p, value); err != nil {
+			return err
+		}
+	}
+
+	rule := strings.TrimSpace(p.Validate)
+	if rule == "" {
+		return nil
+	}
+	str, ok := value.(string)
+	if !ok {
+		return nil
+	}
+
+	if v, ok := namedValidators[rule]; ok {
+		if !v.valid(str) {
+			return fmt.Errorf("%s", v.message)
+		}
+		return nil
+	}
+
+	re, err := regexp.Compile(rule)
+	if err != nil {
+		return fmt.Errorf("template has an invalid validate pattern %q: %v", rule, err)
+	}
+	if !re.MatchString(str) {
+		return fmt.Errorf("must match %s", rule)
+	}
+	return nil
+}
+
+// validateNumber checks that value is numeric and within the prompt's min/max.
+func validateNumber(p Prompt, value interface{}) error {
+	coerced, err := coerceNumber(value)
+	if err != nil {
+		return fmt.Errorf("must be a number")
+	}
+	var n float64
+	switch v := coerced.(type) {
+	case int:
+		n = float64(v)
+	case float64:
+		n = v
+	default:
+		return nil
+	}
+	if p.Min != nil && n < *p.Min {
+		return fmt.Errorf("must be at least %s", strconv.FormatFloat(*p.Min, 'f', -1, 64))
+	}
+	if p.Max != nil && n > *p.Max {
+		return fmt.Errorf("must be at most %s", strconv.FormatFloat(*p.Max, 'f', -1, 64))
+	}
+	return nil
+}
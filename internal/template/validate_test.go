@@ -0,0 +1,40 @@
+package template
+
+import "testing"
+
+func TestValidateAnswer(t *testing.T) {
+	tests := []struct {
+		name    string
+		prompt  Prompt
+		value   interface{}
+		wantErr bool
+	}{
+		{"required empty", Prompt{Required: true}, "  ", true},
+		{"optional empty skips validator", Prompt{Validate: "slug"}, "", false},
+		{"slug ok", Prompt{Validate: "slug"}, "my-app-2", false},
+		{"slug uppercase", Prompt{Validate: "slug"}, "My-App", true},
+		{"slug double dash", Prompt{Validate: "slug"}, "my--app", true},
+		{"semver ok", Prompt{Validate: "semver"}, "1.2.3-rc.1+build.5", false},
+		{"semver leading zero", Prompt{Validate: "semver"}, "01.2.3", true},
+		{"email ok", Prompt{Validate: "email"}, "dev@example.com", false},
+		{"email with name", Prompt{Validate: "email"}, "Dev <dev@example.com>", true},
+		{"go module ok", Prompt{Validate: "go-module-path"}, "github.com/owner/repo/v2", false},
+		{"go module no domain", Prompt{Validate: "go-module-path"}, "myapp", true},
+		{"go module empty element", Prompt{Validate: "go-module-path"}, "github.com//repo", true},
+		{"npm ok", Prompt{Validate: "npm-package-name"}, "@scope/my-pkg", false},
+		{"npm uppercase", Prompt{Validate: "npm-package-name"}, "MyPkg", true},
+		{"regex ok", Prompt{Validate: `^[A-Z]{3}$`}, "ABC", false},
+		{"regex mismatch", Prompt{Validate: `^[A-Z]{3}$`}, "abcd", true},
+		{"invalid regex", Prompt{Validate: `([`}, "x", true},
+		{"non-string values skip validator", Prompt{Validate: "slug"}, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateAnswer(tt.prompt, tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateAnswer(%v) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+		})
+	}
+}
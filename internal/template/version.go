@@ -0,0 +1,115 @@
+package template
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LoadTemplateVersion returns the template as it was when its manifest declared
+// version. For a remote template that is at another version, the repo history
+// is searched for the newest commit with that version and the repo is checked
+// out at it as owner/repo@<commit>, which is the Repo of the returned
+// manifest. Templates it extends or includes from the same repo are taken at
+// the same commit.
+func LoadTemplateVersion(manifest *TemplateManifest, cacheDir, version string) (*TemplateManifest, error) {
+	if manifest.Version == version {
+		return manifest, nil
+	}
+	if manifest.Source() != SourceRemote {
+		return nil, fmt.Errorf("template %s is at version %s, not %s; only remote templates can be used at other versions", manifest.QualifiedName(), manifest.Version, version)
+	}
+
+	loader := NewLoader(cacheDir, manifest.Repo)
+	if !isGitTopLevel(loader.TemplatesDir()) {
+		return nil, fmt.Errorf("template %s is cached without git history; only repos fetched with git can be used at other versions", manifest.QualifiedName())
+	}
+	commit, err := findVersionCommit(loader.TemplatesDir(), manifest.SourcePath, version)
+	if err != nil {
+		return nil, err
+	}
+	if commit == "" {
+		return nil, fmt.Errorf("template %s has no version %s in its history", manifest.QualifiedName(), version)
+	}
+
+	repo, _ := SplitRef(manifest.Repo)
+	pinned := NewLoader(cacheDir, repo+"@"+commit)
+	if err := pinned.checkoutFrom(loader.TemplatesDir(), commit); err != nil {
+		return nil, err
+	}
+
+	manifests, invalid, err := pinned.LoadTemplates()
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range invalid {
+		if t.Path == filepath.Join(pinned.TemplatesDir(), manifest.SourcePath) {
+			return nil, fmt.Errorf("loading %s at version %s: %w", manifest.QualifiedName(), version, t.Err)
+		}
+	}
+	composed, failed := ComposeManifests(append([]*TemplateManifest{GetBuiltinManifest()}, manifests...))
+	for _, t := range failed {
+		if t.Manifest.SourcePath == manifest.SourcePath {
+			return nil, fmt.Errorf("composing %s at version %s: %w", manifest.QualifiedName(), version, t.Err)
+		}
+	}
+	for _, m := range composed {
+		if m.Repo == pinned.Repo() && m.SourcePath == manifest.SourcePath {
+			return m, nil
+		}
+	}
+	return nil, fmt.Errorf("template %s is not in the registry at version %s", manifest.QualifiedName(), version)
+}
+
+// findVersionCommit returns the newest commit in repoDir whose template.yaml
+// under templatePath declares version, or "" if there is none. A shallow
+// cache is deepened first unless offline.
+func findVersionCommit(repoDir, templatePath, version string) (string, error) {
+	// Offline, only the history already in the cache is searched.
+	if !Offline() {
+		if err := fetchHistory(repoDir); err != nil {
+			return "", fmt.Errorf("fetching template history: %w", err)
+		}
+	}
+
+	manifestPath := path.Join(filepath.ToSlash(templatePath), "template.yaml")
+	output, err := gitOutput(repoDir, "log", "--format=%H", "HEAD", "--", manifestPath)
+	if err != nil {
+		return "", fmt.Errorf("reading template history: %w", err)
+	}
+	for _, commit := range strings.Fields(output) {
+		data, err := gitOutput(repoDir, "show", commit+":"+manifestPath)
+		if err != nil {
+			continue
+		}
+		var m TemplateManifest
+		if yaml.Unmarshal([]byte(data), &m) == nil && m.Version == version {
+			return commit, nil
+		}
+	}
+	return "", nil
+}
+
+// checkoutFrom creates the loader's cache as a clone of the local repository
+// src detached at commit, pointing origin at the real remote. An existing
+// cache is reused.
+func (l *Loader) checkoutFrom(src, commit string) error {
+	dir := l.TemplatesDir()
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err != nil {
+		if _, err := gitOutput(filepath.Dir(dir), "clone", "-q", "--no-checkout", src, dir); err != nil {
+			return fmt.Errorf("cloning templates at %s: %w", commit, err)
+		}
+		if _, err := gitOutput(dir, "remote", "set-url", "origin", l.URL()); err != nil {
+			os.RemoveAll(dir)
+			return err
+		}
+	}
+	if _, err := gitOutput(dir, "checkout", "-q", "--detach", commit); err != nil {
+		return fmt.Errorf("checking out templates at %s: %w", commit, err)
+	}
+	return l.lockCommit(dir)
+}
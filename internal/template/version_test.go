@@ -0,0 +1,106 @@
+package template
+
+import (
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func gitRun(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %s: %v", args, output, err)
+	}
+	return string(output)
+}
+
+func TestSplitRef(t *testing.T) {
+	tests := []struct {
+		in, name, ref string
+	}{
+		{"owner/repo", "owner/repo", ""},
+		{"owner/repo@v1.4.0", "owner/repo", "v1.4.0"},
+		{"go-cli@1.2.0", "go-cli", "1.2.0"},
+		{"owner/repo:go-cli@1.2.0", "owner/repo:go-cli", "1.2.0"},
+		{"owner/repo@v1:go-cli", "owner/repo@v1:go-cli", ""},
+		{"owner/repo@v1:go-cli@2.0.0", "owner/repo@v1:go-cli", "2.0.0"},
+	}
+	for _, tt := range tests {
+		name, ref := SplitRef(tt.in)
+		if name != tt.name || ref != tt.ref {
+			t.Fatalf("SplitRef(%q) = %q, %q; want %q, %q", tt.in, name, ref, tt.name, tt.ref)
+		}
+	}
+}
+
+func TestLoadTemplateVersionChecksOutHistory(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	upstream := writeTemplateDir(t, map[string]string{
+		"registry.yaml":         "templates:\n  - name: go-cli\n    path: go-cli\n",
+		"go-cli/template.yaml":  "name: go-cli\nversion: 1.0.0\n",
+		"go-cli/README.md.tmpl": "v1\n",
+	})
+	gitRun(t, upstream, "init", "-q")
+	gitRun(t, upstream, "add", ".")
+	gitRun(t, upstream, "commit", "-q", "-m", "v1")
+	v1 := gitRun(t, upstream, "rev-parse", "HEAD")[:40]
+	for name, content := range map[string]string{
+		"go-cli/template.yaml":  "name: go-cli\nversion: 2.0.0\n",
+		"go-cli/README.md.tmpl": "v2\n",
+	} {
+		if err := os.WriteFile(filepath.Join(upstream, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	gitRun(t, upstream, "commit", "-q", "-am", "v2")
+
+	// The cache starts as the shallow clone FetchTemplates makes.
+	cacheDir := t.TempDir()
+	loader := NewLoader(cacheDir, "acme/templates")
+	gitRun(t, cacheDir, "clone", "-q", "--depth=1", "file://"+upstream, loader.TemplatesDir())
+	manifests, err := loader.LoadAllManifests()
+	if err != nil || len(manifests) != 1 {
+		t.Fatalf("loading manifests: %v (%d manifests)", err, len(manifests))
+	}
+
+	old, err := LoadTemplateVersion(manifests[0], cacheDir, "1.0.0")
+	if err != nil {
+		t.Fatalf("LoadTemplateVersion returned error: %v", err)
+	}
+	if old.Version != "1.0.0" || old.Repo != "acme/templates@"+v1 {
+		t.Fatalf("expected go-cli 1.0.0 from acme/templates@%s, got %s from %s", v1, old.Version, old.Repo)
+	}
+	templateFS, err := ResolveTemplateFS(old, cacheDir, "")
+	if err != nil {
+		t.Fatalf("ResolveTemplateFS returned error: %v", err)
+	}
+	if content, err := fs.ReadFile(templateFS, "README.md.tmpl"); err != nil || string(content) != "v1\n" {
+		t.Fatalf("expected the v1 README, got %q (%v)", content, err)
+	}
+	if got := TemplateCommit(old, cacheDir); got != v1 {
+		t.Fatalf("expected commit %s, got %s", v1, got)
+	}
+
+	lock, err := ReadLock(cacheDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if locked := lock.Repos[old.Repo]; locked.Commit != v1 || locked.Ref != v1 {
+		t.Fatalf("expected the pinned checkout in the lockfile, got %+v", lock.Repos)
+	}
+
+	if _, err := LoadTemplateVersion(manifests[0], cacheDir, "3.0.0"); err == nil {
+		t.Fatal("expected an error for a version that never existed")
+	}
+	if _, err := LoadTemplateVersion(GetBuiltinManifest(), cacheDir, "0.0.1"); err == nil {
+		t.Fatal("expected an error for a built-in template at another version")
+	}
+}
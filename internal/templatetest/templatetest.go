@@ -0,0 +1,237 @@
+// Package templatetest runs a template's golden-file regression tests.
+//
+// A template keeps its cases in tests/<case>/: answers.yaml holds the answers
+// and expected/ the tree the template must render for them.
+package templatetest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/HungSloth/sloth-incubator/internal/diff"
+	"github.com/HungSloth/sloth-incubator/internal/template"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// TestsDir is the directory in a template holding its test cases.
+	TestsDir = template.TestsDir
+	// AnswersFile and ExpectedDir are the parts of a test case.
+	AnswersFile = "answers.yaml"
+	ExpectedDir = "expected"
+)
+
+// Clock is the time every case is rendered at, so `year`, `now` and LICENSE
+// stay the same from one run to the next. No license holder is configured
+// either, so goldens do not depend on who runs the tests.
+var Clock = time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// Options configures a test run.
+type Options struct {
+	// Dir is the template directory, holding template.yaml and tests/.
+	Dir string
+	// Templates are the templates `extends` and `include` may refer to.
+	Templates []*template.TemplateManifest
+	CacheDir  string
+	// Update rewrites each case's expected/ tree with the rendered output.
+	Update bool
+	// Verify is a shell command run inside each rendered tree, e.g. "go build ./...".
+	Verify string
+}
+
+// Result is the outcome of one test case.
+type Result struct {
+	Name string
+	// Diffs describe how the render differs from expected/, one entry per file.
+	Diffs []string
+	// Updated is set when expected/ was rewritten.
+	Updated bool
+	// VerifyOutput is the combined output of a failed verify command.
+	VerifyOutput string
+	// Err is set when the case could not be rendered or verified.
+	Err error
+}
+
+// Passed reports whether the case rendered as expected and verified cleanly.
+func (r Result) Passed() bool {
+	return r.Err == nil && len(r.Diffs) == 0
+}
+
+// Run renders every test case of the template and compares it with its golden
+// tree. An error is returned only when the template or its tests cannot be
+// loaded; failures of individual cases are in the results.
+func Run(opts Options) ([]Result, error) {
+	manifest, sourceFS, err := template.LoadTemplateDir(opts.Dir, opts.Templates, opts.CacheDir)
+	if err != nil {
+		return nil, err
+	}
+
+	cases, err := Cases(opts.Dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(cases) == 0 {
+		return nil, fmt.Errorf("no test cases in %s: add %s/<case>/%s", filepath.Join(opts.Dir, TestsDir), TestsDir, AnswersFile)
+	}
+
+	results := make([]Result, 0, len(cases))
+	for _, name := range cases {
+		result := Result{Name: name}
+		result.Diffs, result.Updated, result.VerifyOutput, result.Err = runCase(opts, manifest, sourceFS, filepath.Join(opts.Dir, TestsDir, name))
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// Cases lists the test case names of the template in dir.
+func Cases(dir string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(dir, TestsDir))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading test cases: %w", err)
+	}
+	var cases []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(dir, TestsDir, entry.Name(), AnswersFile)); err == nil {
+			cases = append(cases, entry.Name())
+		}
+	}
+	return cases, nil
+}
+
+func runCase(opts Options, manifest *template.TemplateManifest, sourceFS fs.FS, caseDir string) (diffs []string, updated bool, verifyOutput string, err error) {
+	data, err := os.ReadFile(filepath.Join(caseDir, AnswersFile))
+	if err != nil {
+		return nil, false, "", fmt.Errorf("reading %s: %w", AnswersFile, err)
+	}
+	provided := map[string]interface{}{}
+	if err := yaml.Unmarshal(data, &provided); err != nil {
+		return nil, false, "", fmt.Errorf("parsing %s: %w", AnswersFile, err)
+	}
+	answers, err := template.ResolveAnswers(manifest, provided)
+	if err != nil {
+		return nil, false, "", err
+	}
+
+	renderer := template.NewRenderer(manifest, answers)
+	renderer.Now = func() time.Time { return Clock }
+	rendered, err := renderer.Render(sourceFS)
+	if err != nil {
+		return nil, false, "", fmt.Errorf("rendering: %w", err)
+	}
+
+	outDir, err := os.MkdirTemp("", "incubator-template-test-*")
+	if err != nil {
+		return nil, false, "", fmt.Errorf("creating output directory: %w", err)
+	}
+	defer os.RemoveAll(outDir)
+	if err := renderer.Write(outDir, rendered); err != nil {
+		return nil, false, "", fmt.Errorf("writing render: %w", err)
+	}
+
+	expectedDir := filepath.Join(caseDir, ExpectedDir)
+	if opts.Update {
+		if err := os.RemoveAll(expectedDir); err != nil {
+			return nil, false, "", fmt.Errorf("clearing %s: %w", ExpectedDir, err)
+		}
+		if err := renderer.Write(expectedDir, rendered); err != nil {
+			return nil, false, "", fmt.Errorf("updating %s: %w", ExpectedDir, err)
+		}
+		updated = true
+	} else {
+		diffs, err = compareTree(expectedDir, outDir)
+		if err != nil {
+			return nil, false, "", err
+		}
+	}
+
+	if strings.TrimSpace(opts.Verify) != "" {
+		cmd := exec.Command("sh", "-c", opts.Verify)
+		cmd.Dir = outDir
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			return diffs, updated, string(output), fmt.Errorf("verify %q: %w", opts.Verify, err)
+		}
+	}
+	return diffs, updated, "", nil
+}
+
+// compareTree diffs every file under expectedDir and renderedDir. Directories
+// are ignored, since empty ones do not survive version control.
+func compareTree(expectedDir, renderedDir string) ([]string, error) {
+	expected, err := readTree(expectedDir)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", ExpectedDir, err)
+	}
+	rendered, err := readTree(renderedDir)
+	if err != nil {
+		return nil, fmt.Errorf("reading render: %w", err)
+	}
+
+	paths := make([]string, 0, len(expected)+len(rendered))
+	for p := range expected {
+		paths = append(paths, p)
+	}
+	for p := range rendered {
+		if _, ok := expected[p]; !ok {
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+
+	var diffs []string
+	for _, p := range paths {
+		want, inExpected := expected[p]
+		got, inRendered := rendered[p]
+		switch {
+		case !inRendered:
+			diffs = append(diffs, fmt.Sprintf("missing: %s is expected but was not rendered\n", p))
+		case !inExpected:
+			diffs = append(diffs, fmt.Sprintf("unexpected: %s was rendered but is not expected\n", p))
+		case !bytes.Equal(want, got):
+			diffs = append(diffs, diff.Unified("expected/"+p, "rendered/"+p, string(want), string(got), 3))
+		}
+	}
+	return diffs, nil
+}
+
+// readTree reads every regular file under root, keyed by slash-separated path.
+// A missing root reads as an empty tree.
+func readTree(root string) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if p == root && errors.Is(err, fs.ErrNotExist) {
+				return fs.SkipAll
+			}
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = data
+		return nil
+	})
+	return files, err
+}
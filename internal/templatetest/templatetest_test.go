@@ -0,0 +1,115 @@
+package templatetest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for relPath, content := range files {
+		fullPath := filepath.Join(root, relPath)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRunUpdatesAndComparesGoldens(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"template.yaml": `name: svc
+version: 1.0.0
+prompts:
+  - name: project_name
+    required: true
+  - name: with_docs
+    type: confirm
+files:
+  - src: "docs/**"
+    when: "{{.with_docs}}"
+`,
+		"files/README.md.tmpl":         "# {{.project_name | title}} ({{year}})\n",
+		"files/docs/index.md":          "docs\n",
+		"tests/basic/answers.yaml":     "project_name: my-svc\n",
+		"tests/with-docs/answers.yaml": "project_name: docs-svc\nwith_docs: true\n",
+	})
+
+	results, err := Run(Options{Dir: dir, Update: true})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if len(results) != 2 || !results[0].Updated || !results[1].Updated {
+		t.Fatalf("expected both cases updated, got %+v", results)
+	}
+	readme, err := os.ReadFile(filepath.Join(dir, "tests/basic/expected/README.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(readme) != "# My Svc (2000)\n" {
+		t.Fatalf("unexpected golden README: %q", readme)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "tests/basic/expected/docs")); !os.IsNotExist(err) {
+		t.Fatalf("expected no docs in the basic golden tree, got %v", err)
+	}
+
+	results, err = Run(Options{Dir: dir})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	for _, r := range results {
+		if !r.Passed() {
+			t.Fatalf("expected %s to pass against fresh goldens, got %+v", r.Name, r)
+		}
+	}
+
+	// A template change shows up as a diff, and stale goldens as missing files.
+	writeFiles(t, dir, map[string]string{
+		"files/README.md.tmpl":           "# {{.project_name}}\n",
+		"tests/basic/expected/stale.txt": "old\n",
+	})
+	results, err = Run(Options{Dir: dir})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	report := strings.Join(results[0].Diffs, "")
+	for _, want := range []string{"-# My Svc (2000)", "+# my-svc", "missing: stale.txt"} {
+		if !strings.Contains(report, want) {
+			t.Fatalf("expected %q in diffs:\n%s", want, report)
+		}
+	}
+}
+
+func TestRunVerifiesRenderedOutput(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"template.yaml":            "name: repo-layout\nversion: 1.0.0\nprompts:\n  - name: project_name\n",
+		"main.txt.tmpl":            "{{.project_name}}\n",
+		"tests/one/answers.yaml":   "project_name: one\n",
+		"tests/one/expected/x.txt": "ignored\n",
+	})
+
+	results, err := Run(Options{Dir: dir, Update: true, Verify: "test -f main.txt && test ! -d tests"})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if !results[0].Passed() {
+		t.Fatalf("expected verify to pass, got %+v", results[0])
+	}
+	if _, err := os.Stat(filepath.Join(dir, "tests/one/expected/x.txt")); !os.IsNotExist(err) {
+		t.Fatal("expected --update to replace the old golden tree")
+	}
+
+	results, err = Run(Options{Dir: dir, Verify: "echo broken; exit 3"})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if results[0].Passed() || !strings.Contains(results[0].VerifyOutput, "broken") {
+		t.Fatalf("expected a failed verify with its output, got %+v", results[0])
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"path/filepath"
 
 	"github.com/HungSloth/sloth-incubator/internal/config"
+	"github.com/HungSloth/sloth-incubator/internal/scaffold"
 	"github.com/HungSloth/sloth-incubator/internal/template"
+	"github.com/charmbracelet/bubbles/spinner"
 	tea "github.com/charmbracelet/bubbletea"
 )
 
@@ -43,6 +45,18 @@ type App struct {
 	quitting         bool
 	initMode         bool
 	initDir          string
+	noHooks          bool
+	dryRun           bool
+	dryRunDone       bool
+
+	// refresh fetches templates in the background when the app starts.
+	refresh func() ([]*template.TemplateManifest, error)
+	// refreshing is set until the refresh finishes. The fetch replaces repo
+	// caches on disk, so a template read from them is not used before then.
+	refreshing bool
+	// pendingTemplate was picked while refreshing and is opened once the
+	// refresh is done, as the refreshed version.
+	pendingTemplate *template.TemplateManifest
 }
 
 // NewApp creates a new App model
@@ -71,8 +85,53 @@ func NewInitApp(manifests []*template.TemplateManifest, cfg *config.Config, init
 	}
 }
 
+// WithNoHooks disables post-create hooks for every project created by the app.
+func (a App) WithNoHooks(noHooks bool) App {
+	a.noHooks = noHooks
+	return a
+}
+
+// WithDryRun makes the init flow stop after the confirm screen without writing
+// anything. The outcome is available from DryRunResult once the program exits.
+func (a App) WithDryRun(dryRun bool) App {
+	a.dryRun = dryRun
+	return a
+}
+
+// WithTemplateRefresh runs refresh in the background on start and reloads the
+// picker with the templates it returns. firstFetch shows a spinner in the
+// picker until it finishes, for when no templates have been fetched yet.
+func (a App) WithTemplateRefresh(refresh func() ([]*template.TemplateManifest, error), firstFetch bool) App {
+	a.refresh = refresh
+	a.refreshing = refresh != nil
+	if firstFetch {
+		a.picker = a.picker.withFetching()
+	}
+	return a
+}
+
+// DryRunResult returns the files and per-file choices from a finished dry run.
+// ok is false if the user quit before reaching the end.
+func (a App) DryRunResult() (files []scaffold.InitFile, actions map[string]scaffold.InitAction, ok bool) {
+	if !a.dryRunDone {
+		return nil, nil, false
+	}
+	return a.confirm.initFiles, a.confirm.initActions, true
+}
+
 func (a App) Init() tea.Cmd {
-	return a.menu.Init()
+	if a.refresh == nil {
+		return a.menu.Init()
+	}
+	refresh := a.refresh
+	cmds := []tea.Cmd{a.menu.Init(), func() tea.Msg {
+		manifests, err := refresh()
+		return templatesRefreshedMsg{manifests: manifests, err: err}
+	}}
+	if a.picker.fetching {
+		cmds = append(cmds, a.picker.spinner.Tick)
+	}
+	return tea.Batch(cmds...)
 }
 
 func (a App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -98,6 +157,31 @@ func (a App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	}
 
 	switch msg := msg.(type) {
+	case templatesRefreshedMsg:
+		a.refreshing = false
+		a.picker = a.picker.setTemplates(msg.manifests, msg.err)
+		pending := a.pendingTemplate
+		a.pendingTemplate = nil
+		if pending == nil {
+			return a, nil
+		}
+		for _, m := range a.picker.allTemplates {
+			if m.QualifiedName() == pending.QualifiedName() {
+				return a.selectTemplate(m)
+			}
+		}
+		// The template is gone upstream; the picker shows what is left.
+		return a, nil
+
+	case spinner.TickMsg:
+		// The picker's spinner keeps turning while another screen is shown.
+		if a.picker.fetching && a.screen != ScreenPicker {
+			var cmd tea.Cmd
+			if a.picker, cmd = a.picker.Update(msg); cmd != nil {
+				return a, cmd
+			}
+		}
+
 	case menuCreateProjectMsg:
 		a.screen = ScreenPicker
 		return a, a.picker.Init()
@@ -108,18 +192,20 @@ func (a App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return a, a.templateCreator.Init()
 
 	case templateSelectedMsg:
-		a.selectedTemplate = msg.manifest
-		formDefaults := map[string]interface{}{}
-		if a.initMode && a.initDir != "" {
-			formDefaults["project_name"] = filepath.Base(a.initDir)
+		if a.refreshing && readsRepoCache(msg.manifest) {
+			// Wait in the picker, spinner and all, until the cache settles.
+			a.pendingTemplate = msg.manifest
+			if a.picker.fetching {
+				return a, nil
+			}
+			a.picker = a.picker.withFetching()
+			return a, a.picker.spinner.Tick
 		}
-		a.form = NewFormModel(msg.manifest, formDefaults)
-		a.screen = ScreenForm
-		return a, a.form.Init()
+		return a.selectTemplate(msg.manifest)
 
 	case formCompletedMsg:
 		a.answers = msg.answers
-		a.confirm = NewConfirmModel(a.selectedTemplate, a.answers, a.cfg, a.initMode, a.initDir)
+		a.confirm = NewConfirmModel(a.selectedTemplate, a.answers, a.cfg, a.initMode, a.initDir, a.noHooks).WithDryRun(a.dryRun)
 		a.screen = ScreenConfirm
 		return a, nil
 
@@ -128,7 +214,13 @@ func (a App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return a, nil
 
 	case confirmProceedMsg:
-		a.progress = NewProgressModel(a.selectedTemplate, a.answers, a.cfg, a.initMode, a.initDir)
+		if a.dryRun {
+			a.dryRunDone = true
+			a.quitting = true
+			return a, tea.Quit
+		}
+		a.progress = NewProgressModel(a.selectedTemplate, a.answers, a.cfg, a.initMode, a.initDir, a.noHooks || msg.skipHooks).
+			WithInitActions(msg.initActions)
 		a.screen = ScreenProgress
 		return a, a.progress.Init()
 
@@ -140,6 +232,9 @@ func (a App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		a.projectDir = msg.projectDir
 		a.repoURL = msg.repoURL
 		a.done = NewDoneModel(a.projectDir, a.repoURL, a.initMode)
+		if scaffold.GitHubSkippedOffline(scaffold.Options{Answers: a.answers, InitMode: a.initMode}) {
+			a.done = a.done.WithNote(scaffold.OfflineGitHubNote)
+		}
 		a.screen = ScreenDone
 		return a, nil
 
@@ -178,6 +273,32 @@ func (a App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return a, cmd
 }
 
+// selectTemplate opens the form for manifest.
+func (a App) selectTemplate(manifest *template.TemplateManifest) (App, tea.Cmd) {
+	a.selectedTemplate = manifest
+	formDefaults := map[string]interface{}{}
+	if a.initMode && a.initDir != "" {
+		formDefaults["project_name"] = filepath.Base(a.initDir)
+	}
+	a.form = NewFormModel(manifest, formDefaults)
+	a.screen = ScreenForm
+	return a, a.form.Init()
+}
+
+// readsRepoCache reports whether the template or any template it is composed
+// from is read from a repo cache that a fetch may replace.
+func readsRepoCache(manifest *template.TemplateManifest) bool {
+	if manifest.Source() == template.SourceRemote {
+		return true
+	}
+	for _, layer := range manifest.Layers {
+		if layer.Source() == template.SourceRemote {
+			return true
+		}
+	}
+	return false
+}
+
 func (a App) View() string {
 	if a.quitting {
 		return ""
@@ -215,7 +336,10 @@ type formCompletedMsg struct {
 
 type formBackMsg struct{}
 
-type confirmProceedMsg struct{}
+type confirmProceedMsg struct {
+	skipHooks   bool
+	initActions map[string]scaffold.InitAction
+}
 
 type confirmBackMsg struct{}
 
@@ -226,6 +350,12 @@ type progressDoneMsg struct {
 
 type quitMsg struct{}
 
+// templatesRefreshedMsg carries the templates loaded after a background fetch.
+type templatesRefreshedMsg struct {
+	manifests []*template.TemplateManifest
+	err       error
+}
+
 type menuCreateProjectMsg struct{}
 
 type menuCreateTemplateMsg struct{}
@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/HungSloth/sloth-incubator/internal/config"
+	"github.com/HungSloth/sloth-incubator/internal/license"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 )
@@ -37,11 +38,12 @@ func NewConfigModel(cfg *config.Config) ConfigModel {
 	fields := []ConfigField{
 		newTextField("GitHub User", "github_user", cfg.GitHubUser),
 		newSelectField("Default Visibility", "default_visibility", cfg.DefaultVisibility, []string{"private", "public"}),
-		newSelectField("Default License", "default_license", cfg.DefaultLicense, []string{"MIT", "Apache-2.0", "GPL-3.0", "none"}),
+		newSelectField("Default License", "default_license", cfg.DefaultLicense, license.Options()),
 		newTextField("Project Directory", "project_dir", cfg.ProjectDir),
 		newSelectField("Editor", "editor", cfg.Editor, []string{"none", "cursor", "code", "vim"}),
 		newTextField("Template Repo", "template_repo", cfg.TemplateRepo),
 		newToggleField("Auto Update Check", "auto_update_check", cfg.AutoUpdateCheck),
+		newTextField("Template Cache TTL", "template_cache_ttl", cfg.TemplateCacheTTL),
 	}
 
 	// Focus the first text input
@@ -210,6 +212,8 @@ func (m *ConfigModel) applyToConfig() {
 			m.cfg.TemplateRepo = field.textInput.Value()
 		case "auto_update_check":
 			m.cfg.AutoUpdateCheck = field.toggleValue
+		case "template_cache_ttl":
+			m.cfg.TemplateCacheTTL = field.textInput.Value()
 		}
 	}
 }
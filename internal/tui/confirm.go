@@ -2,11 +2,10 @@ package tui
 
 import (
 	"fmt"
-	"os"
-	"path/filepath"
 	"strings"
 
 	"github.com/HungSloth/sloth-incubator/internal/config"
+	"github.com/HungSloth/sloth-incubator/internal/scaffold"
 	"github.com/HungSloth/sloth-incubator/internal/template"
 	tea "github.com/charmbracelet/bubbletea"
 )
@@ -20,44 +19,90 @@ type ConfirmModel struct {
 	targetDir     string
 	newFiles      []string
 	existingFiles []string
+
+	// Init mode compares every rendered file with the project's copy.
+	initFiles   []scaffold.InitFile
+	initActions map[string]scaffold.InitAction
+	diffView    DiffViewModel
+	showDiff    bool
+	dryRun      bool
+
+	// Post-create hook review state
+	hookOpts    scaffold.Options
+	runHook     bool
+	hookScript  string
+	hookTrusted bool
+	errorMsg    string
 }
 
+// maxHookPreviewLines bounds how much of the hook script the confirm screen shows.
+const maxHookPreviewLines = 15
+
 // NewConfirmModel creates a new confirmation model
-func NewConfirmModel(manifest *template.TemplateManifest, answers map[string]interface{}, cfg *config.Config, initMode bool, targetDir string) ConfirmModel {
-	// Get the list of files from the selected template source.
-	renderer := template.NewRenderer(manifest, answers)
-	templateRepo := config.DefaultConfig().TemplateRepo
-	if cfg != nil && cfg.TemplateRepo != "" {
-		templateRepo = cfg.TemplateRepo
-	}
-	templateFS, err := template.ResolveTemplateFS(manifest, config.ConfigDir(), templateRepo)
-	var files []string
-	if err == nil {
-		files, _ = renderer.ListFiles(templateFS)
+func NewConfirmModel(manifest *template.TemplateManifest, answers map[string]interface{}, cfg *config.Config, initMode bool, targetDir string, noHooks bool) ConfirmModel {
+	m := ConfirmModel{
+		manifest:    manifest,
+		answers:     answers,
+		initMode:    initMode,
+		targetDir:   targetDir,
+		initActions: make(map[string]scaffold.InitAction),
+		hookOpts: scaffold.Options{
+			Manifest: manifest,
+			Answers:  answers,
+			Config:   cfg,
+			InitMode: initMode,
+			InitDir:  targetDir,
+			NoHooks:  noHooks,
+		},
 	}
 
-	newFiles := make([]string, 0, len(files))
-	existingFiles := make([]string, 0, len(files))
+	// Render the template in memory to list, and in init mode diff, its files.
 	if initMode && targetDir != "" {
-		for _, f := range files {
-			targetPath := filepath.Join(targetDir, f)
-			if _, err := os.Stat(targetPath); err == nil {
-				existingFiles = append(existingFiles, f)
+		initFiles, err := scaffold.PlanInit(m.hookOpts)
+		if err != nil {
+			m.errorMsg = err.Error()
+		}
+		m.initFiles = initFiles
+		for _, f := range initFiles {
+			m.files = append(m.files, f.Path)
+			if f.Exists() {
+				m.existingFiles = append(m.existingFiles, f.Path)
 			} else {
-				newFiles = append(newFiles, f)
+				m.newFiles = append(m.newFiles, f.Path)
 			}
 		}
+		m.diffView = NewDiffViewModel(initFiles, m.initActions)
+	} else {
+		files, err := scaffold.ListFiles(m.hookOpts)
+		if err != nil {
+			m.errorMsg = err.Error()
+		}
+		m.files = files
 	}
 
-	return ConfirmModel{
-		manifest:      manifest,
-		answers:       answers,
-		files:         files,
-		initMode:      initMode,
-		targetDir:     targetDir,
-		newFiles:      newFiles,
-		existingFiles: existingFiles,
+	if scaffold.ShouldRunHooks(m.hookOpts) {
+		m.runHook = true
+		script, err := scaffold.HookScript(m.hookOpts)
+		if err != nil {
+			m.errorMsg = err.Error()
+		}
+		m.hookScript = script
+		m.hookTrusted, _ = scaffold.HookTrusted(m.hookOpts)
 	}
+
+	return m
+}
+
+// WithDryRun makes proceeding end the flow without writing anything. Hooks need
+// no consent since they will not run.
+func (m ConfirmModel) WithDryRun(dryRun bool) ConfirmModel {
+	m.dryRun = dryRun
+	return m
+}
+
+// needsHookConsent reports whether the user still has to decide about the hook.
+func (m ConfirmModel) needsHookConsent() bool {
+	return m.runHook && !m.hookTrusted && !m.dryRun
 }
 
 func (m ConfirmModel) Init() tea.Cmd {
@@ -65,11 +110,47 @@ func (m ConfirmModel) Init() tea.Cmd {
 }
 
 func (m ConfirmModel) Update(msg tea.Msg) (ConfirmModel, tea.Cmd) {
+	if _, ok := msg.(diffViewClosedMsg); ok {
+		m.showDiff = false
+		return m, nil
+	}
+	if m.showDiff {
+		var cmd tea.Cmd
+		m.diffView, cmd = m.diffView.Update(msg)
+		return m, cmd
+	}
+
 	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.diffView, _ = m.diffView.Update(msg)
 	case tea.KeyMsg:
 		switch msg.String() {
 		case "enter":
-			return m, func() tea.Msg { return confirmProceedMsg{} }
+			if m.needsHookConsent() {
+				m.errorMsg = "This template runs a post-create hook. Press y to trust it or n to skip it."
+				return m, nil
+			}
+			return m, m.proceed(!m.runHook)
+		case "d":
+			if m.initMode && len(m.existingFiles) > 0 {
+				m.showDiff = true
+			}
+			return m, nil
+		case "y":
+			if !m.needsHookConsent() {
+				return m, nil
+			}
+			if err := scaffold.TrustHook(m.hookOpts); err != nil {
+				m.errorMsg = fmt.Sprintf("Could not save hook trust: %v", err)
+				return m, nil
+			}
+			m.hookTrusted = true
+			return m, m.proceed(false)
+		case "n":
+			if !m.needsHookConsent() {
+				return m, nil
+			}
+			return m, m.proceed(true)
 		case "esc":
 			return m, func() tea.Msg { return confirmBackMsg{} }
 		case "q":
@@ -79,7 +160,17 @@ func (m ConfirmModel) Update(msg tea.Msg) (ConfirmModel, tea.Cmd) {
 	return m, nil
 }
 
+func (m ConfirmModel) proceed(skipHooks bool) tea.Cmd {
+	return func() tea.Msg {
+		return confirmProceedMsg{skipHooks: skipHooks, initActions: m.initActions}
+	}
+}
+
 func (m ConfirmModel) View() string {
+	if m.showDiff {
+		return m.diffView.View()
+	}
+
 	var b strings.Builder
 
 	// Header
@@ -101,6 +192,9 @@ func (m ConfirmModel) View() string {
 			b.WriteString(fmt.Sprintf("  %s  %s\n", labelStyle.Render("Visibility:"), valueStyle.Render(vis)))
 		}
 	}
+	if scaffold.GitHubSkippedOffline(m.hookOpts) {
+		b.WriteString(fmt.Sprintf("  %s  %s\n", labelStyle.Render("GitHub:"), mutedStyle.Render("skipped in offline mode")))
+	}
 	if lic := m.getAnswer("license", ""); lic != "" {
 		b.WriteString(fmt.Sprintf("  %s  %s\n", labelStyle.Render("License:"), valueStyle.Render(lic)))
 	}
@@ -114,9 +208,11 @@ func (m ConfirmModel) View() string {
 			}
 		}
 		if len(m.existingFiles) > 0 {
-			b.WriteString(fmt.Sprintf("\n  %s\n", titleStyle.Render("Existing files (skipped):")))
-			for _, f := range m.existingFiles {
-				b.WriteString(fmt.Sprintf("    %s %s\n", mutedStyle.Render(f), mutedStyle.Render("(skip)")))
+			b.WriteString(fmt.Sprintf("\n  %s\n", titleStyle.Render("Existing files:")))
+			for _, f := range m.initFiles {
+				if f.Exists() {
+					b.WriteString(fmt.Sprintf("    %s %s\n", mutedStyle.Render(f.Path), initActionLabel(f, m.initActions[f.Path])))
+				}
 			}
 		}
 	} else if len(m.files) > 0 {
@@ -126,8 +222,41 @@ func (m ConfirmModel) View() string {
 		}
 	}
 
+	// Hook
+	if m.runHook {
+		b.WriteString(fmt.Sprintf("\n  %s  %s\n", titleStyle.Render("Post-create hook:"), valueStyle.Render(m.manifest.Hooks.PostCreate)))
+		lines := strings.Split(strings.TrimRight(m.hookScript, "\n"), "\n")
+		for i, line := range lines {
+			if i == maxHookPreviewLines {
+				b.WriteString(fmt.Sprintf("    %s\n", mutedStyle.Render(fmt.Sprintf("... %d more lines", len(lines)-i))))
+				break
+			}
+			b.WriteString(fmt.Sprintf("    %s\n", mutedStyle.Render(line)))
+		}
+		if m.hookTrusted {
+			b.WriteString(fmt.Sprintf("  %s\n", successStyle.Render("Hook trusted")))
+		} else {
+			b.WriteString(fmt.Sprintf("  %s\n", errorStyle.Render("This hook runs on your machine. Only trust templates you have reviewed.")))
+		}
+	}
+
+	if m.errorMsg != "" {
+		b.WriteString(fmt.Sprintf("\n  %s\n", errorStyle.Render(m.errorMsg)))
+	}
+
 	// Help
-	b.WriteString(helpStyle.Render("\n  enter create • esc back • q cancel"))
+	diffHelp := ""
+	if m.initMode && len(m.existingFiles) > 0 {
+		diffHelp = "d diff & choose • "
+	}
+	switch {
+	case m.dryRun:
+		b.WriteString(helpStyle.Render("\n  " + diffHelp + "enter finish dry run • esc back • q cancel"))
+	case m.needsHookConsent():
+		b.WriteString(helpStyle.Render("\n  " + diffHelp + "y trust hook & create • n create without hook • esc back • q cancel"))
+	default:
+		b.WriteString(helpStyle.Render("\n  " + diffHelp + "enter create • esc back • q cancel"))
+	}
 
 	return b.String()
 }
@@ -0,0 +1,179 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/HungSloth/sloth-incubator/internal/diff"
+	"github.com/HungSloth/sloth-incubator/internal/scaffold"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// DiffViewModel shows each rendered template file against the project's copy
+// and lets the user choose whether init skips, overwrites or writes it as
+// .incubator-new.
+type DiffViewModel struct {
+	files   []scaffold.InitFile
+	actions map[string]scaffold.InitAction
+	cursor  int
+	scroll  int
+	height  int
+}
+
+type diffViewClosedMsg struct{}
+
+// NewDiffViewModel creates a diff viewer over the files that init would change.
+// Choices are recorded in actions, keyed by file path.
+func NewDiffViewModel(files []scaffold.InitFile, actions map[string]scaffold.InitAction) DiffViewModel {
+	shown := make([]scaffold.InitFile, 0, len(files))
+	for _, f := range files {
+		if !f.Identical() {
+			shown = append(shown, f)
+		}
+	}
+	return DiffViewModel{files: shown, actions: actions, height: 24}
+}
+
+func (m DiffViewModel) Update(msg tea.Msg) (DiffViewModel, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.height = msg.Height
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "up", "k":
+			if m.cursor > 0 {
+				m.cursor--
+				m.scroll = 0
+			}
+		case "down", "j":
+			if m.cursor < len(m.files)-1 {
+				m.cursor++
+				m.scroll = 0
+			}
+		case "pgdown", "ctrl+d", "J":
+			m.scroll = clampScroll(m.scroll+m.diffHeight()/2, len(m.diffLines()), m.diffHeight())
+		case "pgup", "ctrl+u", "K":
+			m.scroll = clampScroll(m.scroll-m.diffHeight()/2, len(m.diffLines()), m.diffHeight())
+		case "s":
+			m.choose(scaffold.InitSkip)
+		case "o":
+			m.choose(scaffold.InitOverwrite)
+		case "n":
+			m.choose(scaffold.InitWriteNew)
+		case "esc", "enter", "d", "q":
+			return m, func() tea.Msg { return diffViewClosedMsg{} }
+		}
+	}
+	return m, nil
+}
+
+// choose records the action for the selected file. New files are always created.
+func (m *DiffViewModel) choose(action scaffold.InitAction) {
+	if len(m.files) == 0 || !m.files[m.cursor].Exists() {
+		return
+	}
+	m.actions[m.files[m.cursor].Path] = action
+}
+
+func (m DiffViewModel) diffHeight() int {
+	if h := m.height - len(m.files) - 8; h > 5 {
+		return h
+	}
+	return 5
+}
+
+func (m DiffViewModel) diffLines() []string {
+	if len(m.files) == 0 {
+		return nil
+	}
+	f := m.files[m.cursor]
+	return diff.SplitLines(diff.Unified("existing/"+f.Path, "template/"+f.Path, string(f.Existing), string(f.Rendered), 3))
+}
+
+func (m DiffViewModel) View() string {
+	var b strings.Builder
+
+	b.WriteString(headerStyle.Render("  Template vs. existing files"))
+	b.WriteString("\n\n")
+
+	if len(m.files) == 0 {
+		b.WriteString(mutedStyle.Render("  Every template file already matches the project.\n"))
+		b.WriteString(helpStyle.Render("\n  esc back"))
+		return b.String()
+	}
+
+	for i, f := range m.files {
+		cursor := "  "
+		style := inactiveItemStyle
+		if i == m.cursor {
+			cursor = "> "
+			style = activeItemStyle
+		}
+		b.WriteString(fmt.Sprintf("%s%s  %s\n", cursor, style.Render(f.Path), initActionLabel(f, m.actions[f.Path])))
+	}
+
+	b.WriteString("\n")
+	writeDiffWindow(&b, m.diffLines(), m.scroll, m.diffHeight())
+
+	b.WriteString(helpStyle.Render("\n  ↑/↓ file • pgup/pgdown scroll • s skip • o overwrite • n write .incubator-new • esc back"))
+	return b.String()
+}
+
+// initActionLabel describes what init will do with a file.
+func initActionLabel(f scaffold.InitFile, action scaffold.InitAction) string {
+	switch {
+	case !f.Exists():
+		return successStyle.Render("(create)")
+	case f.Identical():
+		return mutedStyle.Render("(identical)")
+	case action == scaffold.InitOverwrite:
+		return errorStyle.Render("(overwrite)")
+	case action == scaffold.InitWriteNew:
+		return valueStyle.Render("(write " + f.Path + scaffold.IncubatorNewSuffix + ")")
+	}
+	return mutedStyle.Render("(skip)")
+}
+
+// writeDiffWindow writes the visible slice of a unified diff.
+func writeDiffWindow(b *strings.Builder, lines []string, scroll, height int) {
+	start := scroll
+	if start > len(lines) {
+		start = len(lines)
+	}
+	end := start + height
+	if end > len(lines) {
+		end = len(lines)
+	}
+	for _, line := range lines[start:end] {
+		b.WriteString("  " + styleDiffLine(line) + "\n")
+	}
+	if end < len(lines) {
+		b.WriteString(mutedStyle.Render(fmt.Sprintf("  ... %d more lines (pgdown)", len(lines)-end)) + "\n")
+	}
+}
+
+// clampScroll keeps a diff scroll offset within the diff's length.
+func clampScroll(scroll, total, height int) int {
+	if limit := total - height; scroll > limit {
+		scroll = limit
+	}
+	if scroll < 0 {
+		scroll = 0
+	}
+	return scroll
+}
+
+// styleDiffLine colours a unified diff line by its marker.
+func styleDiffLine(line string) string {
+	switch {
+	case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+		return labelStyle.Render(line)
+	case strings.HasPrefix(line, "@@"):
+		return mutedStyle.Render(line)
+	case strings.HasPrefix(line, "+"):
+		return successStyle.Render(line)
+	case strings.HasPrefix(line, "-"):
+		return errorStyle.Render(line)
+	}
+	return line
+}
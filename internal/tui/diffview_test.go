@@ -0,0 +1,40 @@
+package tui
+
+import (
+	"testing"
+
+	"github.com/HungSloth/sloth-incubator/internal/scaffold"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestDiffViewRecordsActionsForExistingFilesOnly(t *testing.T) {
+	files := []scaffold.InitFile{
+		{Path: "README.md", Rendered: []byte("# demo\n"), Existing: []byte("# mine\n")},
+		{Path: "Makefile", Rendered: []byte("build:\n"), Existing: []byte("build:\n")},
+		{Path: "new.txt", Rendered: []byte("new\n")},
+	}
+	actions := map[string]scaffold.InitAction{}
+	model := NewDiffViewModel(files, actions)
+	if len(model.files) != 2 {
+		t.Fatalf("expected identical files to be hidden, got %d files", len(model.files))
+	}
+
+	model, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("n")})
+	if actions["README.md"] != scaffold.InitWriteNew {
+		t.Fatalf("expected README.md to be written as .incubator-new, got %q", actions["README.md"])
+	}
+
+	model, _ = model.Update(tea.KeyMsg{Type: tea.KeyDown})
+	model, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("o")})
+	if _, ok := actions["new.txt"]; ok {
+		t.Fatalf("expected no action for a file that does not exist yet")
+	}
+
+	_, cmd := model.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if cmd == nil {
+		t.Fatalf("expected esc to close the diff viewer")
+	}
+	if _, ok := cmd().(diffViewClosedMsg); !ok {
+		t.Fatalf("expected diffViewClosedMsg")
+	}
+}
@@ -15,6 +15,8 @@ type DoneModel struct {
 	repoURL    string
 	editor     string
 	initMode   bool
+	// note is shown under the project's location, e.g. that GitHub was skipped.
+	note string
 }
 
 // NewDoneModel creates a new done model
@@ -32,6 +34,12 @@ func NewDoneModel(projectDir, repoURL string, initMode bool) DoneModel {
 	}
 }
 
+// WithNote adds a line of explanation under the project's location.
+func (m DoneModel) WithNote(note string) DoneModel {
+	m.note = note
+	return m
+}
+
 func (m DoneModel) Init() tea.Cmd {
 	return nil
 }
@@ -100,6 +108,9 @@ func (m DoneModel) View() string {
 	if m.repoURL != "" {
 		b.WriteString(fmt.Sprintf("  %s  %s\n", labelStyle.Render("Remote:"), valueStyle.Render(m.repoURL)))
 	}
+	if m.note != "" {
+		b.WriteString(fmt.Sprintf("\n  %s\n", mutedStyle.Render(m.note)))
+	}
 
 	b.WriteString(fmt.Sprintf("\n  %s\n", mutedStyle.Render(fmt.Sprintf("cd %s", m.projectDir))))
 
@@ -4,23 +4,49 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/HungSloth/sloth-incubator/internal/template"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textarea"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 )
 
-// FormField represents a single form field (text, select, or confirm)
+// FormField represents a single form field
 type FormField struct {
 	prompt    template.Prompt
 	textInput textinput.Model
-	// For select fields
+	// For multiline fields
+	textArea textarea.Model
+	// For select and multiselect fields
 	selectOptions []template.PromptOption
 	selectCursor  int
+	// For multiselect fields, keyed by option index
+	selected map[int]bool
 	// For confirm fields
 	confirmValue bool
+	// Validation error shown under the field, if any
+	err string
+	// hidden is set when the prompt's `when` expression is false
+	hidden bool
+}
+
+// usesTextInput reports whether the prompt type is edited with a single-line text input.
+func usesTextInput(t template.PromptType) bool {
+	switch t {
+	case template.PromptText, template.PromptNumber, template.PromptPath, template.PromptPassword:
+		return true
+	}
+	return false
+}
+
+// isEditable reports whether the field takes typed text, so letter keys belong to it.
+func (f FormField) isEditable() bool {
+	return usesTextInput(f.prompt.Type) || f.prompt.Type == template.PromptMultiline
 }
 
 // FormModel handles the dynamic form
@@ -44,22 +70,46 @@ func NewFormModel(manifest *template.TemplateManifest, defaults ...map[string]in
 			prompt: p,
 		}
 
+		initial := p.Default
+		if providedDefaults != nil {
+			if value, ok := providedDefaults[p.Name]; ok {
+				initial = value
+			}
+		}
+
 		switch p.Type {
-		case template.PromptText:
+		case template.PromptText, template.PromptNumber, template.PromptPath, template.PromptPassword:
 			ti := textinput.New()
 			ti.Placeholder = p.Label
-			if providedDefaults != nil {
-				if value, ok := providedDefaults[p.Name]; ok {
-					ti.SetValue(fmt.Sprintf("%v", value))
-				}
+			switch p.Type {
+			case template.PromptPassword:
+				ti.EchoMode = textinput.EchoPassword
+				ti.EchoCharacter = '•'
+			case template.PromptPath:
+				ti.ShowSuggestions = true
+				// tab moves between fields, so suggestions are accepted with →.
+				ti.KeyMap.AcceptSuggestion = key.NewBinding(key.WithKeys("right"))
 			}
-			if ti.Value() == "" && p.Default != nil {
-				ti.SetValue(fmt.Sprintf("%v", p.Default))
+			if initial != nil {
+				ti.SetValue(template.FormatAnswer(initial))
 			}
-			if i == 0 {
-				ti.Focus()
+			if ti.Value() == "" && p.Default != nil {
+				ti.SetValue(template.FormatAnswer(p.Default))
 			}
 			field.textInput = ti
+			if p.Type == template.PromptPath {
+				field.refreshPathSuggestions()
+			}
+
+		case template.PromptMultiline:
+			ta := textarea.New()
+			ta.Placeholder = p.Label
+			ta.ShowLineNumbers = false
+			ta.SetHeight(4)
+			if initial != nil {
+				ta.SetValue(template.FormatAnswer(initial))
+			}
+			field.textArea = ta
 
 		case template.PromptSelect:
 			field.selectOptions = p.Options
@@ -74,6 +124,19 @@ func NewFormModel(manifest *template.TemplateManifest, defaults ...map[string]in
 				}
 			}
 
+		case template.PromptMultiSelect:
+			field.selectOptions = p.Options
+			field.selected = make(map[int]bool)
+			if values, err := template.CoerceAnswer(p, initial); err == nil {
+				for _, value := range values.([]string) {
+					for j, opt := range p.Options {
+						if opt.Value == value {
+							field.selected[j] = true
+						}
+					}
+				}
+			}
+
 		case template.PromptConfirm:
 			if p.Default != nil {
 				if bVal, ok := p.Default.(bool); ok {
@@ -85,21 +148,96 @@ func NewFormModel(manifest *template.TemplateManifest, defaults ...map[string]in
 		fields[i] = field
 	}
 
-	return FormModel{
+	m := FormModel{
 		manifest: manifest,
 		fields:   fields,
 		cursor:   0,
 	}
+	m.refreshVisibility()
+	if len(m.fields) > 0 && m.fields[0].hidden {
+		if next := m.nextVisible(0); next >= 0 {
+			m.cursor = next
+		}
+	}
+	if len(m.fields) > 0 {
+		m.fields[m.cursor].focus()
+	}
+
+	return m
 }
 
 func (m FormModel) Init() tea.Cmd {
-	if len(m.fields) > 0 && m.fields[0].prompt.Type == template.PromptText {
-		return m.fields[0].textInput.Focus()
+	if len(m.fields) > 0 {
+		return m.fields[m.cursor].focus()
 	}
 	return nil
 }
 
+// focus gives keyboard focus to the field's input, if it has one.
+func (f *FormField) focus() tea.Cmd {
+	switch {
+	case usesTextInput(f.prompt.Type):
+		return f.textInput.Focus()
+	case f.prompt.Type == template.PromptMultiline:
+		return f.textArea.Focus()
+	}
+	return nil
+}
+
+// blur removes keyboard focus from the field's input, if it has one.
+func (f *FormField) blur() {
+	switch {
+	case usesTextInput(f.prompt.Type):
+		f.textInput.Blur()
+	case f.prompt.Type == template.PromptMultiline:
+		f.textArea.Blur()
+	}
+}
+
+// refreshPathSuggestions offers the entries of the directory typed so far as
+// completions, keeping the user's own prefix (including ~) so they match.
+func (f *FormField) refreshPathSuggestions() {
+	value := f.textInput.Value()
+	dirPart := ""
+	if idx := strings.LastIndex(value, "/"); idx >= 0 {
+		dirPart = value[:idx+1]
+	}
+
+	dir := dirPart
+	if strings.HasPrefix(dir, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			dir = filepath.Join(home, dir[2:])
+		}
+	}
+	if dir == "" {
+		dir = "."
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		f.textInput.SetSuggestions(nil)
+		return
+	}
+	suggestions := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		name := dirPart + entry.Name()
+		if entry.IsDir() {
+			name += "/"
+		}
+		suggestions = append(suggestions, name)
+	}
+	sort.Strings(suggestions)
+	f.textInput.SetSuggestions(suggestions)
+}
+
 func (m FormModel) Update(msg tea.Msg) (FormModel, tea.Cmd) {
+	m, cmd := m.update(msg)
+	// Answers may have changed, so conditional prompts can appear or disappear.
+	m.refreshVisibility()
+	return m, cmd
+}
+
+func (m FormModel) update(msg tea.Msg) (FormModel, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
 		fieldType := "unknown"
@@ -113,17 +251,30 @@ func (m FormModel) Update(msg tea.Msg) (FormModel, tea.Cmd) {
 			"fieldType": fieldType,
 		})
 		// #endregion
+		multiline := fieldType == string(template.PromptMultiline)
 		switch msg.String() {
-		case "tab", "down":
+		case "ctrl+s":
+			return m.submit()
+		case "tab":
 			return m.nextField()
-		case "shift+tab", "up":
+		case "shift+tab":
 			return m.prevField()
+		case "down":
+			if !multiline {
+				return m.nextField()
+			}
+		case "up":
+			if !multiline {
+				return m.prevField()
+			}
 		case "enter":
-			// If on the last field, submit
-			if m.cursor == len(m.fields)-1 {
-				return m, func() tea.Msg {
-					return formCompletedMsg{answers: m.collectAnswers()}
-				}
+			// Multiline fields take enter as a newline; tab moves on instead.
+			if multiline {
+				break
+			}
+			// If on the last visible field, submit
+			if m.nextVisible(m.cursor) < 0 {
+				return m.submit()
 			}
 			return m.nextField()
 		case "esc":
@@ -139,7 +290,7 @@ func (m FormModel) Update(msg tea.Msg) (FormModel, tea.Cmd) {
 			// For select and confirm fields
 			field := &m.fields[m.cursor]
 			switch field.prompt.Type {
-			case template.PromptSelect:
+			case template.PromptSelect, template.PromptMultiSelect:
 				if field.selectCursor > 0 {
 					field.selectCursor--
 				}
@@ -158,7 +309,7 @@ func (m FormModel) Update(msg tea.Msg) (FormModel, tea.Cmd) {
 			// #endregion
 			field := &m.fields[m.cursor]
 			switch field.prompt.Type {
-			case template.PromptSelect:
+			case template.PromptSelect, template.PromptMultiSelect:
 				if field.selectCursor < len(field.selectOptions)-1 {
 					field.selectCursor++
 				}
@@ -167,15 +318,42 @@ func (m FormModel) Update(msg tea.Msg) (FormModel, tea.Cmd) {
 				field.confirmValue = !field.confirmValue
 				return m, nil
 			}
+		case " ":
+			field := &m.fields[m.cursor]
+			if field.prompt.Type == template.PromptMultiSelect && len(field.selectOptions) > 0 {
+				field.selected[field.selectCursor] = !field.selected[field.selectCursor]
+				if field.err != "" {
+					m.validateField(m.cursor)
+				}
+				return m, nil
+			}
+		}
+	}
+
+	// Update the focused text area
+	if m.cursor < len(m.fields) && m.fields[m.cursor].prompt.Type == template.PromptMultiline {
+		var cmd tea.Cmd
+		before := m.fields[m.cursor].textArea.Value()
+		m.fields[m.cursor].textArea, cmd = m.fields[m.cursor].textArea.Update(msg)
+		if before != m.fields[m.cursor].textArea.Value() && m.fields[m.cursor].err != "" {
+			m.validateField(m.cursor)
 		}
+		return m, cmd
 	}
 
 	// Update the focused text input
-	if m.cursor < len(m.fields) && m.fields[m.cursor].prompt.Type == template.PromptText {
+	if m.cursor < len(m.fields) && usesTextInput(m.fields[m.cursor].prompt.Type) {
 		var cmd tea.Cmd
 		before := m.fields[m.cursor].textInput.Value()
 		m.fields[m.cursor].textInput, cmd = m.fields[m.cursor].textInput.Update(msg)
 		after := m.fields[m.cursor].textInput.Value()
+		if before != after && m.fields[m.cursor].prompt.Type == template.PromptPath {
+			m.fields[m.cursor].refreshPathSuggestions()
+		}
+		if before != after && m.fields[m.cursor].err != "" {
+			// Re-check as the user types so a fixed value clears its error.
+			m.validateField(m.cursor)
+		}
 		if keyMsg, ok := msg.(tea.KeyMsg); ok {
 			// #region agent log
 			writeDebugLog("repro-1", "H2", "internal/tui/form.go:Update:136", "text input update result", map[string]interface{}{
@@ -192,52 +370,149 @@ func (m FormModel) Update(msg tea.Msg) (FormModel, tea.Cmd) {
 	return m, nil
 }
 
-func (m FormModel) nextField() (FormModel, tea.Cmd) {
-	// Blur current text input
-	if m.fields[m.cursor].prompt.Type == template.PromptText {
-		m.fields[m.cursor].textInput.Blur()
+// submit validates every field and only completes the form when all pass,
+// otherwise it focuses the first invalid field.
+func (m FormModel) submit() (FormModel, tea.Cmd) {
+	m.refreshVisibility()
+	firstInvalid := -1
+	for i := range m.fields {
+		if m.fields[i].hidden {
+			continue
+		}
+		if !m.validateField(i) && firstInvalid < 0 {
+			firstInvalid = i
+		}
+	}
+	if firstInvalid >= 0 {
+		return m.focusField(firstInvalid)
 	}
+	return m, func() tea.Msg {
+		return formCompletedMsg{answers: m.collectAnswers()}
+	}
+}
 
-	m.cursor++
-	if m.cursor >= len(m.fields) {
-		m.cursor = len(m.fields) - 1
+// validateField records the validation error for field i and reports whether it is valid.
+func (m *FormModel) validateField(i int) bool {
+	field := &m.fields[i]
+	if err := template.ValidateAnswer(field.prompt, m.fieldValue(i)); err != nil {
+		field.err = err.Error()
+		return false
 	}
+	field.err = ""
+	return true
+}
+
+// focusField moves the cursor to field i, updating input focus.
+func (m FormModel) focusField(i int) (FormModel, tea.Cmd) {
+	m.fields[m.cursor].blur()
+	m.cursor = i
+	return m, m.fields[m.cursor].focus()
+}
 
-	// Focus new text input
-	if m.fields[m.cursor].prompt.Type == template.PromptText {
-		return m, m.fields[m.cursor].textInput.Focus()
+func (m FormModel) nextField() (FormModel, tea.Cmd) {
+	// Blur current input
+	m.fields[m.cursor].blur()
+	m.validateField(m.cursor)
+	m.refreshVisibility()
+
+	if next := m.nextVisible(m.cursor); next >= 0 {
+		m.cursor = next
 	}
-	return m, nil
+
+	// Focus new input
+	return m, m.fields[m.cursor].focus()
 }
 
 func (m FormModel) prevField() (FormModel, tea.Cmd) {
-	if m.fields[m.cursor].prompt.Type == template.PromptText {
-		m.fields[m.cursor].textInput.Blur()
+	m.fields[m.cursor].blur()
+	m.validateField(m.cursor)
+	m.refreshVisibility()
+
+	if prev := m.prevVisible(m.cursor); prev >= 0 {
+		m.cursor = prev
 	}
 
-	m.cursor--
-	if m.cursor < 0 {
-		m.cursor = 0
+	return m, m.fields[m.cursor].focus()
+}
+
+// refreshVisibility re-evaluates every prompt's `when` expression against the
+// answers to the visible prompts before it.
+func (m *FormModel) refreshVisibility() {
+	answers := make(map[string]interface{})
+	for i := range m.fields {
+		field := &m.fields[i]
+		field.hidden = !template.PromptVisible(field.prompt, answers)
+		if field.hidden {
+			field.err = ""
+			continue
+		}
+		if value := m.fieldValue(i); value != nil {
+			answers[field.prompt.Name] = value
+		}
 	}
+}
 
-	if m.fields[m.cursor].prompt.Type == template.PromptText {
-		return m, m.fields[m.cursor].textInput.Focus()
+// nextVisible returns the index of the next visible field after i, or -1.
+func (m FormModel) nextVisible(i int) int {
+	for j := i + 1; j < len(m.fields); j++ {
+		if !m.fields[j].hidden {
+			return j
+		}
 	}
-	return m, nil
+	return -1
+}
+
+// prevVisible returns the index of the previous visible field before i, or -1.
+func (m FormModel) prevVisible(i int) int {
+	for j := i - 1; j >= 0; j-- {
+		if !m.fields[j].hidden {
+			return j
+		}
+	}
+	return -1
+}
+
+// fieldValue returns the typed answer currently held by field i.
+func (m FormModel) fieldValue(i int) interface{} {
+	field := m.fields[i]
+	switch field.prompt.Type {
+	case template.PromptText, template.PromptPath, template.PromptPassword:
+		return field.textInput.Value()
+	case template.PromptMultiline:
+		return field.textArea.Value()
+	case template.PromptNumber:
+		value, err := template.CoerceAnswer(field.prompt, field.textInput.Value())
+		if err != nil {
+			// Keep the raw text so validation can explain what is wrong.
+			return field.textInput.Value()
+		}
+		return value
+	case template.PromptMultiSelect:
+		values := []string{}
+		for j, opt := range field.selectOptions {
+			if field.selected[j] {
+				values = append(values, opt.Value)
+			}
+		}
+		return values
+	case template.PromptSelect:
+		if len(field.selectOptions) > 0 {
+			return field.selectOptions[field.selectCursor].Value
+		}
+	case template.PromptConfirm:
+		return field.confirmValue
+	}
+	return nil
 }
 
 func (m FormModel) collectAnswers() map[string]interface{} {
 	answers := make(map[string]interface{})
-	for _, field := range m.fields {
-		switch field.prompt.Type {
-		case template.PromptText:
-			answers[field.prompt.Name] = field.textInput.Value()
-		case template.PromptSelect:
-			if len(field.selectOptions) > 0 {
-				answers[field.prompt.Name] = field.selectOptions[field.selectCursor].Value
-			}
-		case template.PromptConfirm:
-			answers[field.prompt.Name] = field.confirmValue
+	for i, field := range m.fields {
+		if field.hidden {
+			continue
+		}
+		if value := m.fieldValue(i); value != nil {
+			answers[field.prompt.Name] = value
 		}
 	}
 	return answers
@@ -253,6 +528,9 @@ func (m FormModel) View() string {
 
 	// Fields
 	for i, field := range m.fields {
+		if field.hidden {
+			continue
+		}
 		isFocused := i == m.cursor
 		label := field.prompt.Label + ":"
 		if isFocused {
@@ -264,9 +542,33 @@ func (m FormModel) View() string {
 		b.WriteString(fmt.Sprintf("  %s  ", label))
 
 		switch field.prompt.Type {
-		case template.PromptText:
+		case template.PromptText, template.PromptNumber, template.PromptPath, template.PromptPassword:
 			b.WriteString(field.textInput.View())
 
+		case template.PromptMultiline:
+			b.WriteString("\n")
+			for _, line := range strings.Split(field.textArea.View(), "\n") {
+				b.WriteString("    " + line + "\n")
+			}
+
+		case template.PromptMultiSelect:
+			for j, opt := range field.selectOptions {
+				box := "[ ]"
+				if field.selected[j] {
+					box = "[x]"
+				}
+				item := fmt.Sprintf("%s %s", box, opt.Label)
+				switch {
+				case isFocused && j == field.selectCursor:
+					b.WriteString(focusedStyle.Render(item))
+				case field.selected[j]:
+					b.WriteString(item)
+				default:
+					b.WriteString(mutedStyle.Render(item))
+				}
+				b.WriteString("  ")
+			}
+
 		case template.PromptSelect:
 			for j, opt := range field.selectOptions {
 				if j == field.selectCursor {
@@ -301,10 +603,14 @@ func (m FormModel) View() string {
 		}
 
 		b.WriteString("\n")
+
+		if field.err != "" {
+			b.WriteString(fmt.Sprintf("  %s\n", errorStyle.Render("↳ "+field.prompt.Label+" "+field.err)))
+		}
 	}
 
 	// Help
-	b.WriteString(helpStyle.Render("\n  tab/↓ next • shift+tab/↑ prev • ←/→ change • enter confirm • esc back"))
+	b.WriteString(helpStyle.Render("\n  tab/↓ next • shift+tab/↑ prev • ←/→ change • space toggle • enter confirm • ctrl+s submit • esc back"))
 
 	return b.String()
 }
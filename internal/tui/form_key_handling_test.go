@@ -63,3 +63,38 @@ func TestFormSelectFieldUsesHLForNavigation(t *testing.T) {
 		t.Fatalf("expected select cursor to move left to 0, got %d", got)
 	}
 }
+
+func TestFormConditionalPromptsFollowAnswers(t *testing.T) {
+	manifest := &template.TemplateManifest{
+		Name: "test",
+		Prompts: []template.Prompt{
+			{Name: "use_db", Label: "Database", Type: template.PromptConfirm},
+			{Name: "db_name", Label: "Database name", Type: template.PromptText, Required: true, When: "{{if .use_db}}true{{end}}"},
+			{Name: "license", Label: "License", Type: template.PromptSelect, Options: []template.PromptOption{{Label: "MIT", Value: "MIT"}}},
+		},
+	}
+	model := NewFormModel(manifest)
+	if !model.fields[1].hidden {
+		t.Fatalf("expected db_name to start hidden")
+	}
+
+	// Tab skips the hidden field.
+	model, _ = model.Update(tea.KeyMsg{Type: tea.KeyTab})
+	if model.cursor != 2 {
+		t.Fatalf("expected tab to skip hidden db_name, got cursor %d", model.cursor)
+	}
+	if _, ok := model.collectAnswers()["db_name"]; ok {
+		t.Fatalf("expected hidden db_name to be left out of answers")
+	}
+
+	// Turning the confirm on reveals the follow-up question.
+	model, _ = model.Update(tea.KeyMsg{Type: tea.KeyShiftTab})
+	model, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'l'}})
+	if model.fields[1].hidden {
+		t.Fatalf("expected db_name to appear once use_db is true")
+	}
+	model, _ = model.Update(tea.KeyMsg{Type: tea.KeyTab})
+	if model.cursor != 1 {
+		t.Fatalf("expected tab to land on db_name, got cursor %d", model.cursor)
+	}
+}
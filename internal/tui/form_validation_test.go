@@ -0,0 +1,92 @@
+package tui
+
+import (
+	"testing"
+
+	"github.com/HungSloth/sloth-incubator/internal/template"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestFormSubmitBlockedByInvalidFields(t *testing.T) {
+	manifest := &template.TemplateManifest{
+		Name: "test",
+		Prompts: []template.Prompt{
+			{Name: "project_name", Label: "Project name", Type: template.PromptText, Required: true, Validate: "slug"},
+			{Name: "use_db", Label: "Database", Type: template.PromptConfirm},
+		},
+	}
+	model := NewFormModel(manifest)
+	model.cursor = 1
+
+	model, cmd := model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd != nil {
+		if _, ok := cmd().(formCompletedMsg); ok {
+			t.Fatalf("expected submit to be blocked while project_name is empty")
+		}
+	}
+	if model.cursor != 0 {
+		t.Fatalf("expected focus to move to the invalid field, got cursor %d", model.cursor)
+	}
+	if model.fields[0].err == "" {
+		t.Fatalf("expected an inline error on project_name")
+	}
+
+	model.fields[0].textInput.SetValue("Not A Slug")
+	model.cursor = 1
+	model, _ = model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if model.fields[0].err == "" {
+		t.Fatalf("expected slug validation error")
+	}
+
+	model.fields[0].textInput.SetValue("my-app")
+	model.cursor = 1
+	_, cmd = model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd == nil {
+		t.Fatalf("expected submit command once all fields are valid")
+	}
+	msg, ok := cmd().(formCompletedMsg)
+	if !ok {
+		t.Fatalf("expected formCompletedMsg, got %T", cmd())
+	}
+	if msg.answers["project_name"] != "my-app" {
+		t.Fatalf("expected project_name my-app, got %v", msg.answers["project_name"])
+	}
+}
+
+func TestFormMultiSelectTogglesOptions(t *testing.T) {
+	manifest := &template.TemplateManifest{
+		Name: "test",
+		Prompts: []template.Prompt{
+			{
+				Name:    "languages",
+				Label:   "Languages",
+				Type:    template.PromptMultiSelect,
+				Default: []interface{}{"go"},
+				Options: []template.PromptOption{
+					{Label: "Go", Value: "go"},
+					{Label: "Rust", Value: "rust"},
+					{Label: "Zig", Value: "zig"},
+				},
+			},
+		},
+	}
+	model := NewFormModel(manifest)
+
+	model, _ = model.Update(tea.KeyMsg{Type: tea.KeyRight})
+	model, _ = model.Update(tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}})
+	model, _ = model.Update(tea.KeyMsg{Type: tea.KeyLeft})
+	model, _ = model.Update(tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}})
+
+	_, cmd := model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd == nil {
+		t.Fatalf("expected submit command")
+	}
+	msg, ok := cmd().(formCompletedMsg)
+	if !ok {
+		t.Fatalf("expected formCompletedMsg, got %T", cmd())
+	}
+	languages, ok := msg.answers["languages"].([]string)
+	if !ok || len(languages) != 1 || languages[0] != "rust" {
+		t.Fatalf("expected only rust to be selected, got %#v", msg.answers["languages"])
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/HungSloth/sloth-incubator/internal/template"
+	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 )
@@ -16,6 +17,11 @@ type PickerModel struct {
 	cursor       int
 	searchInput  textinput.Model
 	searching    bool
+
+	// fetching is set while the first template fetch runs.
+	fetching   bool
+	spinner    spinner.Model
+	refreshErr error
 }
 
 // NewPickerModel creates a new picker model
@@ -23,13 +29,48 @@ func NewPickerModel(templates []*template.TemplateManifest) PickerModel {
 	ti := textinput.New()
 	ti.Placeholder = "Search templates..."
 
+	s := spinner.New()
+	s.Spinner = spinner.Dot
+
 	return PickerModel{
 		allTemplates: templates,
 		filtered:     templates,
 		cursor:       0,
 		searchInput:  ti,
 		searching:    false,
+		spinner:      s,
+	}
+}
+
+// withFetching shows a spinner above the list until setTemplates is called.
+func (m PickerModel) withFetching() PickerModel {
+	m.fetching = true
+	return m
+}
+
+// setTemplates replaces the listed templates after a refresh, keeping the
+// search and the highlighted template. A failed refresh keeps the current
+// list and shows err.
+func (m PickerModel) setTemplates(templates []*template.TemplateManifest, err error) PickerModel {
+	m.fetching = false
+	m.refreshErr = err
+	if templates == nil {
+		return m
+	}
+
+	selected := ""
+	if m.cursor < len(m.filtered) {
+		selected = m.filtered[m.cursor].QualifiedName()
+	}
+	m.allTemplates = templates
+	m.filterTemplates()
+	for i, t := range m.filtered {
+		if t.QualifiedName() == selected {
+			m.cursor = i
+			break
+		}
 	}
+	return m
 }
 
 func (m PickerModel) Init() tea.Cmd {
@@ -38,6 +79,14 @@ func (m PickerModel) Init() tea.Cmd {
 
 func (m PickerModel) Update(msg tea.Msg) (PickerModel, tea.Cmd) {
 	switch msg := msg.(type) {
+	case spinner.TickMsg:
+		if !m.fetching {
+			return m, nil
+		}
+		var cmd tea.Cmd
+		m.spinner, cmd = m.spinner.Update(msg)
+		return m, cmd
+
 	case tea.KeyMsg:
 		if m.searching {
 			switch msg.String() {
@@ -108,7 +157,7 @@ func (m *PickerModel) filterTemplates() {
 
 	var filtered []*template.TemplateManifest
 	for _, t := range m.allTemplates {
-		name := strings.ToLower(t.Name)
+		name := strings.ToLower(t.QualifiedName())
 		desc := strings.ToLower(t.Description)
 		if strings.Contains(name, query) || strings.Contains(desc, query) {
 			filtered = append(filtered, t)
@@ -128,6 +177,13 @@ func (m PickerModel) View() string {
 	b.WriteString(header)
 	b.WriteString("\n\n")
 
+	if m.fetching {
+		b.WriteString(fmt.Sprintf("  %s Fetching templates...\n\n", m.spinner.View()))
+	} else if m.refreshErr != nil {
+		b.WriteString(mutedStyle.Render(fmt.Sprintf("  Could not refresh templates: %v", m.refreshErr)))
+		b.WriteString("\n\n")
+	}
+
 	// Search bar
 	if m.searching {
 		b.WriteString(fmt.Sprintf("  Search: %s\n\n", m.searchInput.View()))
@@ -147,6 +203,9 @@ func (m PickerModel) View() string {
 
 			name := style.Render(fmt.Sprintf("%-15s", t.Name))
 			desc := mutedStyle.Render(t.Description)
+			if t.Repo != "" {
+				desc = mutedStyle.Render(fmt.Sprintf("%s (%s)", t.Description, t.Repo))
+			}
 			b.WriteString(fmt.Sprintf("%s%s  %s\n", cursor, name, desc))
 		}
 	}
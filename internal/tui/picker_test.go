@@ -0,0 +1,71 @@
+package tui
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/HungSloth/sloth-incubator/internal/template"
+)
+
+func TestAppReloadsPickerAfterTemplateRefresh(t *testing.T) {
+	builtin := template.GetBuiltinManifest()
+	remote := &template.TemplateManifest{Name: "go-cli", Repo: "acme/templates", Description: "Go CLI"}
+
+	app := NewApp([]*template.TemplateManifest{builtin}, nil).WithTemplateRefresh(func() ([]*template.TemplateManifest, error) {
+		return []*template.TemplateManifest{remote, builtin}, nil
+	}, true)
+	if !strings.Contains(app.picker.View(), "Fetching templates") {
+		t.Fatalf("expected a spinner during the first fetch:\n%s", app.picker.View())
+	}
+	if app.Init() == nil {
+		t.Fatal("expected Init to start the refresh")
+	}
+
+	model, _ := app.Update(templatesRefreshedMsg{manifests: []*template.TemplateManifest{remote, builtin}})
+	app = model.(App)
+	view := app.picker.View()
+	if strings.Contains(view, "Fetching templates") || !strings.Contains(view, "go-cli") {
+		t.Fatalf("expected the refreshed list without a spinner:\n%s", view)
+	}
+	// The highlighted template stays highlighted when the list changes.
+	if selected := app.picker.filtered[app.picker.cursor]; selected != builtin {
+		t.Fatalf("expected %s to stay selected, got %s", builtin.Name, selected.Name)
+	}
+
+	model, _ = app.Update(templatesRefreshedMsg{err: errors.New("network is down")})
+	app = model.(App)
+	if len(app.picker.allTemplates) != 2 || !strings.Contains(app.picker.View(), "network is down") {
+		t.Fatalf("expected a failed refresh to keep the list and show the error:\n%s", app.picker.View())
+	}
+}
+
+func TestAppWaitsForRefreshBeforeOpeningRemoteTemplate(t *testing.T) {
+	builtin := template.GetBuiltinManifest()
+	cached := &template.TemplateManifest{Name: "go-cli", Repo: "acme/templates", Version: "1.0.0"}
+	fetched := &template.TemplateManifest{Name: "go-cli", Repo: "acme/templates", Version: "1.1.0"}
+	refresh := func() ([]*template.TemplateManifest, error) {
+		return []*template.TemplateManifest{builtin, fetched}, nil
+	}
+
+	// Built-in templates are not read from the cache and open right away.
+	app := NewApp([]*template.TemplateManifest{builtin, cached}, nil).WithTemplateRefresh(refresh, false)
+	model, _ := app.Update(templateSelectedMsg{manifest: builtin})
+	if model.(App).screen != ScreenForm {
+		t.Fatalf("expected the built-in template to open during a refresh")
+	}
+
+	// A cached template waits for the fetch that is replacing its files.
+	app.screen = ScreenPicker
+	model, cmd := app.Update(templateSelectedMsg{manifest: cached})
+	app = model.(App)
+	if app.screen != ScreenPicker || cmd == nil || !strings.Contains(app.picker.View(), "Fetching templates") {
+		t.Fatalf("expected the picker to wait for the refresh:\n%s", app.picker.View())
+	}
+
+	model, _ = app.Update(templatesRefreshedMsg{manifests: []*template.TemplateManifest{builtin, fetched}})
+	app = model.(App)
+	if app.screen != ScreenForm || app.selectedTemplate != fetched {
+		t.Fatalf("expected the refreshed template to open, got screen %v with %+v", app.screen, app.selectedTemplate)
+	}
+}
@@ -1,13 +1,14 @@
 package tui
 
 import (
+	"bufio"
+	"bytes"
 	"fmt"
-	"os"
-	"path/filepath"
+	"io"
 	"strings"
 
 	"github.com/HungSloth/sloth-incubator/internal/config"
-	"github.com/HungSloth/sloth-incubator/internal/git"
+	"github.com/HungSloth/sloth-incubator/internal/scaffold"
 	"github.com/HungSloth/sloth-incubator/internal/template"
 	"github.com/charmbracelet/bubbles/spinner"
 	tea "github.com/charmbracelet/bubbletea"
@@ -31,12 +32,13 @@ type ProgressStep struct {
 }
 
 const (
-	stepCreateProjectDir = "Creating project directory"
-	stepRenderTemplates  = "Rendering templates"
-	stepInitGitRepo      = "Initializing git repo"
-	stepCommitScaffold   = "Committing scaffold files"
-	stepCreateGitHubRepo = "Creating GitHub repo"
-	stepPushToOrigin     = "Pushing to origin"
+	stepCreateProjectDir = scaffold.StepCreateProjectDir
+	stepRenderTemplates  = scaffold.StepRenderTemplates
+	stepRunPostCreate    = scaffold.StepRunPostCreate
+	stepInitGitRepo      = scaffold.StepInitGitRepo
+	stepCommitScaffold   = scaffold.StepCommitScaffold
+	stepCreateGitHubRepo = scaffold.StepCreateGitHubRepo
+	stepPushToOrigin     = scaffold.StepPushToOrigin
 )
 
 // ProgressModel handles the progress screen
@@ -54,8 +56,24 @@ type ProgressModel struct {
 	repoURL    string
 	initMode   bool
 	initDir    string
+	noHooks    bool
+	// initActions says what to do with existing files in init mode.
+	initActions map[string]scaffold.InitAction
+
+	// Post-create hook output streamed while the hook step runs.
+	hookOutput  []string
+	hookReader  *io.PipeReader
+	hookScanner *bufio.Scanner
+	hookResult  chan error
 }
 
+// maxHookOutputLines bounds how much hook output the progress screen keeps.
+const maxHookOutputLines = 12
+
+// maxHookLineBytes is where a long hook output line is cut into pieces, so a
+// progress bar that never prints a newline can't stall the scanner.
+const maxHookLineBytes = 4096
+
 // Step result messages
 type stepDoneMsg struct {
 	projectDir string
@@ -66,25 +84,23 @@ type stepErrorMsg struct {
 	err error
 }
 
+type hookOutputMsg struct {
+	line string
+}
+
 // NewProgressModel creates a new progress model
-func NewProgressModel(manifest *template.TemplateManifest, answers map[string]interface{}, cfg *config.Config, initMode bool, initDir string) ProgressModel {
+func NewProgressModel(manifest *template.TemplateManifest, answers map[string]interface{}, cfg *config.Config, initMode bool, initDir string, noHooks bool) ProgressModel {
 	s := spinner.New()
 	s.Spinner = spinner.Dot
 
-	steps := []ProgressStep{
-		{Name: stepRenderTemplates, Status: StepPending},
-	}
-	if initMode {
-		steps = append(steps, ProgressStep{Name: stepCommitScaffold, Status: StepPending})
-	} else {
-		steps = append([]ProgressStep{{Name: stepCreateProjectDir, Status: StepPending}}, steps...)
-		steps = append(steps, ProgressStep{Name: stepInitGitRepo, Status: StepPending})
-	}
-	if !initMode && shouldCreateGitHubRepo(answers) {
-		steps = append(steps,
-			ProgressStep{Name: stepCreateGitHubRepo, Status: StepPending},
-			ProgressStep{Name: stepPushToOrigin, Status: StepPending},
-		)
+	var steps []ProgressStep
+	for _, name := range scaffold.Steps(scaffold.Options{
+		Manifest: manifest,
+		Answers:  answers,
+		InitMode: initMode,
+		NoHooks:  noHooks,
+	}) {
+		steps = append(steps, ProgressStep{Name: name, Status: StepPending})
 	}
 
 	return ProgressModel{
@@ -92,13 +108,21 @@ func NewProgressModel(manifest *template.TemplateManifest, answers map[string]in
 		answers:  answers,
 		cfg:      cfg,
 		steps:    steps,
-		current: 0,
-		spinner: s,
+		current:  0,
+		spinner:  s,
 		initMode: initMode,
 		initDir:  initDir,
+		noHooks:  noHooks,
 	}
 }
 
+// WithInitActions sets what init mode does with template files that already
+// exist in the project.
+func (m ProgressModel) WithInitActions(actions map[string]scaffold.InitAction) ProgressModel {
+	m.initActions = actions
+	return m
+}
+
 func (m ProgressModel) Init() tea.Cmd {
 	return tea.Batch(m.spinner.Tick, m.runCurrentStep())
 }
@@ -110,6 +134,13 @@ func (m ProgressModel) Update(msg tea.Msg) (ProgressModel, tea.Cmd) {
 		m.spinner, cmd = m.spinner.Update(msg)
 		return m, cmd
 
+	case hookOutputMsg:
+		m.hookOutput = append(m.hookOutput, msg.line)
+		if len(m.hookOutput) > maxHookOutputLines {
+			m.hookOutput = m.hookOutput[len(m.hookOutput)-maxHookOutputLines:]
+		}
+		return m, m.waitForHookOutput()
+
 	case stepDoneMsg:
 		m.steps[m.current].Status = StepDone
 		if msg.projectDir != "" {
@@ -131,7 +162,8 @@ func (m ProgressModel) Update(msg tea.Msg) (ProgressModel, tea.Cmd) {
 		}
 
 		m.steps[m.current].Status = StepRunning
-		return m, m.runCurrentStep()
+		cmd := m.runCurrentStep()
+		return m, cmd
 
 	case stepErrorMsg:
 		m.steps[m.current].Status = StepFailed
@@ -151,7 +183,8 @@ func (m ProgressModel) Update(msg tea.Msg) (ProgressModel, tea.Cmd) {
 				}
 			}
 			m.steps[m.current].Status = StepRunning
-			return m, m.runCurrentStep()
+			cmd := m.runCurrentStep()
+			return m, cmd
 		}
 		return m, nil
 
@@ -176,107 +209,99 @@ func (m ProgressModel) Update(msg tea.Msg) (ProgressModel, tea.Cmd) {
 	return m, nil
 }
 
-func (m ProgressModel) runCurrentStep() tea.Cmd {
-	step := m.current
-	answers := m.answers
-	manifest := m.manifest
-	cfg := m.cfg
-	initMode := m.initMode
-	initDir := m.initDir
+func (m *ProgressModel) runCurrentStep() tea.Cmd {
+	stepName := m.steps[m.current].Name
+	opts := scaffold.Options{
+		Manifest:    m.manifest,
+		Answers:     m.answers,
+		Config:      m.cfg,
+		InitMode:    m.initMode,
+		InitDir:     m.initDir,
+		NoHooks:     m.noHooks,
+		InitActions: m.initActions,
+	}
+
+	if stepName == stepRunPostCreate {
+		return m.startHook(opts)
+	}
 
 	return func() tea.Msg {
-		projectDir := initDir
-		if !initMode {
-			projectName := fmt.Sprintf("%v", answers["project_name"])
-			baseDir := filepath.Join(os.Getenv("HOME"), "projects")
-			if cfg != nil {
-				baseDir = cfg.GetProjectDir()
-			}
-			projectDir = filepath.Join(baseDir, projectName)
+		result, err := scaffold.RunStep(stepName, opts)
+		if err != nil {
+			return stepErrorMsg{err: err}
 		}
-		stepName := m.steps[step].Name
-
-		switch stepName {
-		case stepCreateProjectDir:
-			if err := os.MkdirAll(projectDir, 0755); err != nil {
-				return stepErrorMsg{err: fmt.Errorf("creating directory: %w", err)}
-			}
-			return stepDoneMsg{projectDir: projectDir}
-
-		case stepRenderTemplates:
-			renderer := template.NewRenderer(manifest, answers)
-			renderer.SkipExisting = initMode
-			templateRepo := config.DefaultConfig().TemplateRepo
-			if cfg != nil && cfg.TemplateRepo != "" {
-				templateRepo = cfg.TemplateRepo
-			}
-			templateFS, err := template.ResolveTemplateFS(manifest, config.ConfigDir(), templateRepo)
-			if err != nil {
-				return stepErrorMsg{err: fmt.Errorf("loading template: %w", err)}
-			}
-			if err := renderer.RenderTo(projectDir, templateFS); err != nil {
-				return stepErrorMsg{err: fmt.Errorf("rendering templates: %w", err)}
-			}
-			return stepDoneMsg{}
-
-		case stepInitGitRepo:
-			if err := git.InitRepo(projectDir); err != nil {
-				return stepErrorMsg{err: err}
-			}
-			if err := git.InitialCommit(projectDir); err != nil {
-				return stepErrorMsg{err: err}
-			}
-			return stepDoneMsg{}
-
-		case stepCommitScaffold:
-			if git.HasRepo(projectDir) {
-				if err := git.CommitAll(projectDir, "Add incubator scaffolding"); err != nil {
-					if strings.Contains(err.Error(), "nothing to commit") {
-						return stepDoneMsg{}
-					}
-					return stepErrorMsg{err: err}
-				}
-				return stepDoneMsg{}
-			}
-			if err := git.InitRepo(projectDir); err != nil {
-				return stepErrorMsg{err: err}
-			}
-			if err := git.InitialCommit(projectDir); err != nil {
-				return stepErrorMsg{err: err}
-			}
-			return stepDoneMsg{}
-
-		case stepCreateGitHubRepo:
-			if !shouldCreateGitHubRepo(answers) {
-				return stepDoneMsg{}
-			}
-
-			projectName := fmt.Sprintf("%v", answers["project_name"])
-			visibility := "private"
-			if v, ok := answers["visibility"]; ok {
-				visibility = fmt.Sprintf("%v", v)
-			}
-			isPrivate := visibility == "private"
+		return stepDoneMsg{projectDir: result.ProjectDir, repoURL: result.RepoURL}
+	}
+}
 
-			repoURL, err := git.CreateRepo(projectName, isPrivate, projectDir)
-			if err != nil {
-				return stepErrorMsg{err: err}
-			}
-			return stepDoneMsg{repoURL: repoURL}
+// startHook runs the post-create hook in the background and streams its output
+// into the model one line at a time via hookOutputMsg.
+func (m *ProgressModel) startHook(opts scaffold.Options) tea.Cmd {
+	reader, writer := io.Pipe()
+	opts.HookOutput = writer
+	m.hookReader = reader
+	m.hookScanner = bufio.NewScanner(reader)
+	m.hookScanner.Split(scanHookLines)
+	m.hookResult = make(chan error, 1)
+
+	result := m.hookResult
+	go func() {
+		_, err := scaffold.RunStep(stepRunPostCreate, opts)
+		writer.Close()
+		result <- err
+	}()
+
+	return m.waitForHookOutput()
+}
 
-		case stepPushToOrigin:
-			if !shouldCreateGitHubRepo(answers) {
-				return stepDoneMsg{}
-			}
+func (m ProgressModel) waitForHookOutput() tea.Cmd {
+	reader := m.hookReader
+	scanner := m.hookScanner
+	result := m.hookResult
+	return func() tea.Msg {
+		if scanner.Scan() {
+			return hookOutputMsg{line: scanner.Text()}
+		}
+		// Drain the rest if the scanner gave up early: closing the pipe would
+		// fail the hook's next write and so the hook itself.
+		io.Copy(io.Discard, reader)
+		if err := <-result; err != nil {
+			return stepErrorMsg{err: err}
+		}
+		return stepDoneMsg{}
+	}
+}
 
-			if err := git.Push(projectDir); err != nil {
-				return stepErrorMsg{err: err}
+// scanHookLines splits hook output at "\n", "\r" or "\r\n", so each redraw of
+// a "\r" progress bar is its own line, and cuts lines longer than
+// maxHookLineBytes.
+func scanHookLines(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		if data[i] == '\n' {
+			return i + 1, data[:i], nil
+		}
+		if i+1 < len(data) {
+			if data[i+1] == '\n' {
+				return i + 2, data[:i], nil
 			}
-			return stepDoneMsg{}
+			return i + 1, data[:i], nil
 		}
-
-		return stepDoneMsg{}
+		if atEOF {
+			return i + 1, data[:i], nil
+		}
+		// Wait for the next byte to tell "\r" from "\r\n".
+		return 0, nil, nil
+	}
+	if len(data) >= maxHookLineBytes {
+		return maxHookLineBytes, data[:maxHookLineBytes], nil
 	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
 }
 
 // progressPercent returns the overall progress percentage
@@ -284,6 +309,7 @@ func (m ProgressModel) progressPercent() float64 {
 	weights := map[string]float64{
 		stepCreateProjectDir: 0.10,
 		stepRenderTemplates:  0.30,
+		stepRunPostCreate:    0.20,
 		stepInitGitRepo:      0.10,
 		stepCommitScaffold:   0.20,
 		stepCreateGitHubRepo: 0.40,
@@ -312,23 +338,8 @@ func (m ProgressModel) progressPercent() float64 {
 	return total
 }
 
-func shouldCreateGitHubRepo(answers map[string]interface{}) bool {
-	if answers == nil {
-		return true
-	}
-	value, ok := answers["create_github_repo"]
-	if !ok {
-		return true
-	}
-	enabled, ok := value.(bool)
-	if !ok {
-		return true
-	}
-	return enabled
-}
-
 func isGitHubStep(stepName string) bool {
-	return stepName == stepCreateGitHubRepo || stepName == stepPushToOrigin
+	return scaffold.IsGitHubStep(stepName)
 }
 
 // renderProgressBar renders a visual progress bar
@@ -374,6 +385,12 @@ func (m ProgressModel) View() string {
 		if step.Status == StepFailed && step.Error != "" {
 			b.WriteString(fmt.Sprintf("    %s\n", errorStyle.Render(step.Error)))
 		}
+
+		if step.Name == stepRunPostCreate && step.Status != StepPending {
+			for _, line := range m.hookOutput {
+				b.WriteString(fmt.Sprintf("    %s\n", mutedStyle.Render(line)))
+			}
+		}
 	}
 
 	// Progress bar
@@ -1,8 +1,11 @@
 package tui
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/HungSloth/sloth-incubator/internal/scaffold"
 	"github.com/HungSloth/sloth-incubator/internal/template"
 )
 
@@ -13,7 +16,7 @@ func TestNewProgressModelSkipsGitHubStepsWhenDisabled(t *testing.T) {
 		"create_github_repo": false,
 	}
 
-	model := NewProgressModel(manifest, answers, nil, false, "")
+	model := NewProgressModel(manifest, answers, nil, false, "", false)
 	if len(model.steps) != 3 {
 		t.Fatalf("expected 3 steps when GitHub is disabled, got %d", len(model.steps))
 	}
@@ -31,7 +34,7 @@ func TestNewProgressModelIncludesGitHubStepsByDefault(t *testing.T) {
 		"project_name": "demo",
 	}
 
-	model := NewProgressModel(manifest, answers, nil, false, "")
+	model := NewProgressModel(manifest, answers, nil, false, "", false)
 	if len(model.steps) != 5 {
 		t.Fatalf("expected 5 steps by default, got %d", len(model.steps))
 	}
@@ -50,3 +53,50 @@ func TestNewProgressModelIncludesGitHubStepsByDefault(t *testing.T) {
 		t.Fatalf("expected GitHub steps to be present by default")
 	}
 }
+
+func TestHookWithLongOutputLineSucceeds(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	templateDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(templateDir, "files"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	// A progress bar redrawn with "\r" and no newline, longer than bufio's 64KB token limit.
+	script := "#!/bin/sh\nhead -c 100000 /dev/zero | tr '\\000' '#'\nprintf '\\rdone\\n'\necho after\n"
+	if err := os.WriteFile(filepath.Join(templateDir, "files", "setup.sh"), []byte(script), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	manifest := &template.TemplateManifest{Name: "hooked", Version: "1.0.0", SourcePath: templateDir, Hooks: template.HooksConfig{PostCreate: "setup.sh"}}
+	opts := scaffold.Options{
+		Manifest: manifest,
+		Answers:  map[string]interface{}{"project_name": "demo"},
+		InitMode: true,
+		InitDir:  t.TempDir(),
+	}
+	if err := scaffold.TrustHook(opts); err != nil {
+		t.Fatal(err)
+	}
+
+	model := NewProgressModel(manifest, opts.Answers, nil, true, opts.InitDir, false)
+	var lines []string
+	msg := model.startHook(opts)()
+	for {
+		out, ok := msg.(hookOutputMsg)
+		if !ok {
+			break
+		}
+		lines = append(lines, out.line)
+		msg = model.waitForHookOutput()()
+	}
+	if errMsg, ok := msg.(stepErrorMsg); ok {
+		t.Fatalf("expected the hook to succeed, got %v", errMsg.err)
+	}
+	if len(lines) < 2 || lines[len(lines)-2] != "done" || lines[len(lines)-1] != "after" {
+		t.Fatalf("expected the output after the long line to be streamed, got %d lines ending %q", len(lines), lines[len(lines)-1])
+	}
+	for _, line := range lines {
+		if len(line) > maxHookLineBytes {
+			t.Fatalf("expected lines of at most %d bytes, got %d", maxHookLineBytes, len(line))
+		}
+	}
+}
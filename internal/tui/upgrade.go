@@ -0,0 +1,186 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/HungSloth/sloth-incubator/internal/diff"
+	"github.com/HungSloth/sloth-incubator/internal/upgrade"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// UpgradeModel lets the user review an upgrade file by file, showing the diff
+// each change makes and choosing how conflicts are resolved.
+type UpgradeModel struct {
+	plan      *upgrade.Plan
+	cursor    int
+	scroll    int
+	height    int
+	confirmed bool
+	cancelled bool
+}
+
+// NewUpgradeModel creates a review screen for the plan. Resolutions chosen in the
+// review are stored on the plan's changes.
+func NewUpgradeModel(plan *upgrade.Plan) UpgradeModel {
+	return UpgradeModel{plan: plan, height: 24}
+}
+
+func (m UpgradeModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m UpgradeModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.height = msg.Height
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "up", "k":
+			if m.cursor > 0 {
+				m.cursor--
+				m.scroll = 0
+			}
+		case "down", "j":
+			if m.cursor < len(m.plan.Changes)-1 {
+				m.cursor++
+				m.scroll = 0
+			}
+		case "pgdown", "ctrl+d", "J":
+			m.scroll = clampScroll(m.scroll+m.diffHeight()/2, len(m.diffLines()), m.diffHeight())
+		case "pgup", "ctrl+u", "K":
+			m.scroll = clampScroll(m.scroll-m.diffHeight()/2, len(m.diffLines()), m.diffHeight())
+		case "m":
+			m.resolve(upgrade.ResolveKeepMine)
+		case "t":
+			m.resolve(upgrade.ResolveTakeTemplate)
+		case "p":
+			m.resolve(upgrade.ResolveProposed)
+		case "enter":
+			m.confirmed = true
+			return m, tea.Quit
+		case "esc", "q", "ctrl+c":
+			m.cancelled = true
+			return m, tea.Quit
+		}
+	}
+	return m, nil
+}
+
+func (m *UpgradeModel) resolve(resolution upgrade.Resolution) {
+	if len(m.plan.Changes) == 0 {
+		return
+	}
+	m.plan.Changes[m.cursor].Resolution = resolution
+	m.scroll = 0
+}
+
+// diffHeight is the number of diff lines that fit below the file list.
+func (m UpgradeModel) diffHeight() int {
+	used := len(m.plan.Changes) + 8
+	if m.plan.Warning != "" {
+		used += 2
+	}
+	if h := m.height - used; h > 5 {
+		return h
+	}
+	return 5
+}
+
+// diffLines renders the change under the cursor as the difference between the
+// project's current file and what the upgrade will write.
+func (m UpgradeModel) diffLines() []string {
+	if len(m.plan.Changes) == 0 {
+		return nil
+	}
+	c := m.plan.Changes[m.cursor]
+	result := c.Result()
+	if result == nil {
+		return []string{fmt.Sprintf("%s will be deleted", c.Path)}
+	}
+	unified := diff.Unified("current/"+c.Path, "upgraded/"+c.Path, string(c.Current), string(result), 3)
+	if unified == "" {
+		return []string{"No changes to this file with the current choice."}
+	}
+	return diff.SplitLines(unified)
+}
+
+func (m UpgradeModel) View() string {
+	var b strings.Builder
+
+	from, to := m.plan.From, m.plan.To
+	b.WriteString(headerStyle.Render(fmt.Sprintf("  Upgrade %s  %s → %s", to.QualifiedName(), from.Version, to.Version)))
+	b.WriteString("\n\n")
+
+	if m.plan.Warning != "" {
+		b.WriteString(fmt.Sprintf("  %s\n\n", errorStyle.Render(m.plan.Warning)))
+	}
+
+	for i, c := range m.plan.Changes {
+		cursor := "  "
+		style := inactiveItemStyle
+		if i == m.cursor {
+			cursor = "> "
+			style = activeItemStyle
+		}
+
+		action := mutedStyle.Render(fmt.Sprintf("%-8s", c.Action))
+		if c.Action == upgrade.ActionConflict {
+			action = errorStyle.Render(fmt.Sprintf("%-8s", c.Action))
+		}
+		line := fmt.Sprintf("%s%s %s", cursor, action, style.Render(c.Path))
+		if c.Resolution != upgrade.ResolveProposed {
+			line += "  " + valueStyle.Render("("+resolutionLabel(c.Resolution)+")")
+		} else if c.Reason != "" {
+			line += "  " + mutedStyle.Render(c.Reason)
+		}
+		b.WriteString(line + "\n")
+	}
+
+	b.WriteString("\n")
+	writeDiffWindow(&b, m.diffLines(), m.scroll, m.diffHeight())
+
+	conflicts := 0
+	for _, c := range m.plan.Changes {
+		if c.Action == upgrade.ActionConflict && c.Resolution == upgrade.ResolveProposed {
+			conflicts++
+		}
+	}
+	if conflicts > 0 {
+		b.WriteString(fmt.Sprintf("\n  %s\n", errorStyle.Render(fmt.Sprintf("%d unresolved conflict(s) will be written with conflict markers", conflicts))))
+	}
+
+	b.WriteString(helpStyle.Render("\n  ↑/↓ file • pgup/pgdown scroll • m keep mine • t take template • p proposed • enter apply • q cancel"))
+	return b.String()
+}
+
+func resolutionLabel(r upgrade.Resolution) string {
+	switch r {
+	case upgrade.ResolveKeepMine:
+		return "keep mine"
+	case upgrade.ResolveTakeTemplate:
+		return "take template"
+	}
+	return string(r)
+}
+
+// Confirmed reports whether the user chose to apply the upgrade.
+func (m UpgradeModel) Confirmed() bool {
+	return m.confirmed && !m.cancelled
+}
+
+// RunUpgradeReview shows the review screen and reports whether the user chose to
+// apply the plan.
+func RunUpgradeReview(plan *upgrade.Plan) (bool, error) {
+	p := tea.NewProgram(NewUpgradeModel(plan), tea.WithAltScreen())
+	finalModel, err := p.Run()
+	if err != nil {
+		return false, err
+	}
+
+	upgradeModel, ok := finalModel.(UpgradeModel)
+	if !ok {
+		return false, fmt.Errorf("unexpected upgrade model type")
+	}
+	return upgradeModel.Confirmed(), nil
+}
@@ -0,0 +1,358 @@
+// Package upgrade re-renders a project's template at a newer version and merges
+// the result into the project's current files.
+package upgrade
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/HungSloth/sloth-incubator/internal/git"
+	"github.com/HungSloth/sloth-incubator/internal/template"
+)
+
+// Action describes what an upgrade does to a single file.
+type Action string
+
+const (
+	// ActionAdd creates a file the template gained.
+	ActionAdd Action = "add"
+	// ActionUpdate replaces a file the user never changed with the new version.
+	ActionUpdate Action = "update"
+	// ActionMerge combines the user's and the template's changes cleanly.
+	ActionMerge Action = "merge"
+	// ActionDelete removes a file the template dropped and the user never changed.
+	ActionDelete Action = "delete"
+	// ActionConflict needs a decision from the user.
+	ActionConflict Action = "conflict"
+)
+
+// Resolution picks the content written for a change.
+type Resolution string
+
+const (
+	// ResolveProposed writes the proposed content. For conflicts this keeps the
+	// conflict markers so they can be resolved in an editor.
+	ResolveProposed Resolution = "proposed"
+	// ResolveKeepMine leaves the project's current file as it is.
+	ResolveKeepMine Resolution = "mine"
+	// ResolveTakeTemplate writes the new template version, discarding local edits.
+	ResolveTakeTemplate Resolution = "template"
+)
+
+// FileChange is a single file touched by an upgrade. Nil content means the file
+// does not exist on that side.
+type FileChange struct {
+	Path       string
+	Action     Action
+	Current    []byte
+	Template   []byte
+	Proposed   []byte
+	Resolution Resolution
+	// Reason explains a conflict that has no conflict markers.
+	Reason string
+	// Mode is the template's permission for the file, used when it is created.
+	Mode fs.FileMode
+}
+
+// Result returns the content that will be written for the change's resolution,
+// or nil if the file will be removed.
+func (c FileChange) Result() []byte {
+	switch c.Resolution {
+	case ResolveKeepMine:
+		return c.Current
+	case ResolveTakeTemplate:
+		return c.Template
+	default:
+		return c.Proposed
+	}
+}
+
+// Options describes an upgrade run.
+type Options struct {
+	ProjectDir string
+	// Manifest is the template version to upgrade to.
+	Manifest *template.TemplateManifest
+	CacheDir string
+	// Answers override or add to the answers recorded in the project state, e.g.
+	// for prompts the new version introduced.
+	Answers map[string]interface{}
+	// LicenseHolder is credited in a generated LICENSE; see template.Renderer.
+	LicenseHolder string
+}
+
+// Plan holds every change an upgrade would make.
+type Plan struct {
+	ProjectDir string
+	From       *template.ProjectState
+	To         *template.ProjectState
+	Changes    []FileChange
+	// Warning is set when the original render could not be reproduced. Every
+	// difference from the template is then reported as a conflict.
+	Warning string
+}
+
+// Conflicts returns the number of changes that need a decision.
+func (p *Plan) Conflicts() int {
+	count := 0
+	for _, c := range p.Changes {
+		if c.Action == ActionConflict {
+			count++
+		}
+	}
+	return count
+}
+
+// Prepare renders the original and the new template version and compares both
+// with the project's current files. Nothing in the project is modified.
+func Prepare(opts Options) (*Plan, error) {
+	state, err := template.ReadState(opts.ProjectDir)
+	if err != nil {
+		return nil, err
+	}
+
+	answers, err := template.ResolveAnswers(opts.Manifest, knownAnswers(opts.Manifest, state.Answers, opts.Answers))
+	if err != nil {
+		return nil, fmt.Errorf("%w\nanswer new prompts with --set name=value", err)
+	}
+	templateFS, err := template.ResolveTemplateFS(opts.Manifest, opts.CacheDir, "")
+	if err != nil {
+		return nil, fmt.Errorf("loading template: %w", err)
+	}
+	renderer := template.NewRenderer(opts.Manifest, answers)
+	renderer.LicenseHolder = opts.LicenseHolder
+	rendered, err := renderer.Render(templateFS)
+	if err != nil {
+		return nil, fmt.Errorf("rendering new template version: %w", err)
+	}
+	newFiles := contents(rendered)
+
+	plan := &Plan{
+		ProjectDir: opts.ProjectDir,
+		From:       state,
+		To:         template.NewProjectState(opts.Manifest, answers, opts.CacheDir),
+	}
+
+	baseFiles, err := renderBase(opts, state, answers)
+	if err != nil {
+		plan.Warning = fmt.Sprintf("could not reproduce the original render (%v); every difference is shown as a conflict", err)
+		baseFiles = map[string][]byte{}
+	}
+
+	paths := make([]string, 0, len(newFiles)+len(baseFiles))
+	for p := range newFiles {
+		paths = append(paths, p)
+	}
+	for p := range baseFiles {
+		if _, ok := newFiles[p]; !ok {
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+
+	for _, p := range paths {
+		if p == template.StateFile {
+			continue
+		}
+		current, err := readFile(filepath.Join(opts.ProjectDir, filepath.FromSlash(p)))
+		if err != nil {
+			return nil, err
+		}
+		change, err := compare(p, baseFiles[p], newFiles[p], current)
+		if err != nil {
+			return nil, err
+		}
+		if change != nil {
+			if file, ok := rendered.File(p); ok {
+				change.Mode = file.Mode
+			}
+			plan.Changes = append(plan.Changes, *change)
+		}
+	}
+
+	return plan, nil
+}
+
+// renderBase reproduces the project's original render. Secret answers are
+// never recorded, so the ones used for the new render stand in for them.
+func renderBase(opts Options, state *template.ProjectState, current map[string]interface{}) (map[string][]byte, error) {
+	manifest := opts.Manifest
+	var sourceFS fs.FS
+	switch {
+	case state.Commit != "":
+		checkoutDir, err := os.MkdirTemp("", "incubator-upgrade-*")
+		if err != nil {
+			return nil, fmt.Errorf("creating upgrade work directory: %w", err)
+		}
+		defer os.RemoveAll(checkoutDir)
+
+		old, oldFS, err := template.CheckoutTemplateAt(opts.Manifest, opts.CacheDir, state.Commit, checkoutDir)
+		if err != nil {
+			return nil, err
+		}
+		manifest, sourceFS = old, oldFS
+	case state.Version == opts.Manifest.Version:
+		// Without history, the same version is assumed to have the same files.
+		currentFS, err := template.ResolveTemplateFS(opts.Manifest, opts.CacheDir, "")
+		if err != nil {
+			return nil, err
+		}
+		sourceFS = currentFS
+	default:
+		return nil, fmt.Errorf("no template commit recorded for version %s", state.Version)
+	}
+
+	known := knownAnswers(manifest, state.Answers, opts.Answers)
+	for _, p := range manifest.Prompts {
+		if _, ok := known[p.Name]; ok || !p.IsSecret() {
+			continue
+		}
+		if value, ok := current[p.Name]; ok {
+			known[p.Name] = value
+		}
+	}
+	answers, err := template.ResolveAnswers(manifest, known)
+	if err != nil {
+		return nil, err
+	}
+	renderer := template.NewRenderer(manifest, answers)
+	renderer.LicenseHolder = opts.LicenseHolder
+	rendered, err := renderer.Render(sourceFS)
+	if err != nil {
+		return nil, err
+	}
+	return contents(rendered), nil
+}
+
+// compare decides what happens to one file given its original render (base), the
+// new render and the project's current content. It returns nil when nothing
+// needs to change.
+func compare(path string, base, next, current []byte) (*FileChange, error) {
+	change := &FileChange{Path: path, Current: current, Template: next, Resolution: ResolveProposed}
+
+	switch {
+	case next != nil && current != nil && bytes.Equal(next, current):
+		return nil, nil
+	case base != nil && next != nil && bytes.Equal(base, next):
+		// The template did not change this file; keep whatever the user has.
+		return nil, nil
+
+	case next == nil:
+		if current == nil {
+			return nil, nil
+		}
+		if bytes.Equal(current, base) {
+			change.Action = ActionDelete
+			return change, nil
+		}
+		change.Action = ActionConflict
+		change.Proposed = current
+		change.Reason = "removed from the template but changed locally"
+		return change, nil
+
+	case current == nil:
+		change.Proposed = next
+		if base == nil {
+			change.Action = ActionAdd
+			return change, nil
+		}
+		change.Action = ActionConflict
+		change.Reason = "deleted locally but changed in the template"
+		return change, nil
+
+	case base != nil && bytes.Equal(current, base):
+		change.Action = ActionUpdate
+		change.Proposed = next
+		return change, nil
+	}
+
+	if template.IsBinary(current) || template.IsBinary(next) || template.IsBinary(base) {
+		change.Action = ActionConflict
+		change.Proposed = current
+		change.Reason = "binary file changed on both sides"
+		return change, nil
+	}
+
+	merged, conflict, err := git.MergeFile(current, base, next, [3]string{"current", "original template", "new template"})
+	if err != nil {
+		return nil, fmt.Errorf("merging %s: %w", path, err)
+	}
+	change.Proposed = merged
+	change.Action = ActionMerge
+	if conflict {
+		change.Action = ActionConflict
+	}
+	return change, nil
+}
+
+// Apply writes every change with its chosen resolution and records the new
+// template version in the project state.
+func (p *Plan) Apply() error {
+	for _, c := range p.Changes {
+		target := filepath.Join(p.ProjectDir, filepath.FromSlash(c.Path))
+		content := c.Result()
+		if content == nil {
+			if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("removing %s: %w", c.Path, err)
+			}
+			continue
+		}
+
+		// Existing files keep their permissions.
+		mode := c.Mode
+		if info, err := os.Stat(target); err == nil {
+			mode = info.Mode().Perm()
+		} else if mode == 0 {
+			mode = 0644
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(target, content, mode); err != nil {
+			return fmt.Errorf("writing %s: %w", c.Path, err)
+		}
+	}
+	return template.WriteState(p.ProjectDir, p.To)
+}
+
+// knownAnswers merges recorded and override answers, dropping any whose prompt
+// no longer exists in the manifest.
+func knownAnswers(manifest *template.TemplateManifest, recorded, overrides map[string]interface{}) map[string]interface{} {
+	answers := make(map[string]interface{})
+	for _, p := range manifest.Prompts {
+		if value, ok := recorded[p.Name]; ok {
+			answers[p.Name] = value
+		}
+		if value, ok := overrides[p.Name]; ok {
+			answers[p.Name] = value
+		}
+	}
+	return answers
+}
+
+// contents maps every rendered file's path to its content.
+func contents(result template.RenderResult) map[string][]byte {
+	files := make(map[string][]byte, len(result.Files))
+	for _, f := range result.Files {
+		files[f.Path] = f.Content
+	}
+	return files
+}
+
+// readFile returns nil for missing files.
+func readFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		data = []byte{}
+	}
+	return data, nil
+}
@@ -0,0 +1,210 @@
+package upgrade
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/HungSloth/sloth-incubator/internal/template"
+)
+
+func gitRun(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+	cmd.Dir = dir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %s: %v", args, output, err)
+	}
+}
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func loadLocal(t *testing.T, dir string) *template.TemplateManifest {
+	t.Helper()
+	manifests, err := template.LoadLocalManifests(filepath.Dir(dir))
+	if err != nil || len(manifests) != 1 {
+		t.Fatalf("loading local template: %v (%d manifests)", err, len(manifests))
+	}
+	return manifests[0]
+}
+
+func TestPrepareMergesTemplateChangesWithLocalEdits(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	templateDir := filepath.Join(t.TempDir(), "templates", "svc")
+	writeFiles(t, templateDir, map[string]string{
+		"template.yaml":        "name: svc\nversion: 1.0.0\nprompts:\n  - name: project_name\n    type: text\n",
+		"files/README.md.tmpl": "# {{.project_name}}\n\nIntro\n\nUsage\n",
+		"files/Makefile":       "build:\n\tgo build\n",
+		"files/old.txt":        "obsolete\n",
+		"files/notes.txt":      "template notes\n",
+	})
+	gitRun(t, templateDir, "init", "-q")
+	gitRun(t, templateDir, "add", ".")
+	gitRun(t, templateDir, "commit", "-q", "-m", "v1")
+
+	// Scaffold the project from v1.
+	manifest := loadLocal(t, templateDir)
+	answers := map[string]interface{}{"project_name": "demo"}
+	projectDir := t.TempDir()
+	sourceFS, err := template.ResolveTemplateFS(manifest, t.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := template.NewRenderer(manifest, answers).RenderTo(projectDir, sourceFS); err != nil {
+		t.Fatal(err)
+	}
+	if err := template.WriteState(projectDir, template.NewProjectState(manifest, answers, "")); err != nil {
+		t.Fatal(err)
+	}
+
+	// The user edits the README and the notes; the template changes both too.
+	writeFiles(t, projectDir, map[string]string{
+		"README.md": "# demo\n\nIntro\n\nUsage\n\nMy section\n",
+		"notes.txt": "my notes\n",
+	})
+	writeFiles(t, templateDir, map[string]string{
+		"template.yaml":        "name: svc\nversion: 2.0.0\nprompts:\n  - name: project_name\n    type: text\n",
+		"files/README.md.tmpl": "# {{.project_name}}\n\nBetter intro\n\nUsage\n",
+		"files/Makefile":       "build:\n\tgo build ./...\n",
+		"files/new.txt":        "new file\n",
+		"files/notes.txt":      "new template notes\n",
+	})
+	if err := os.Remove(filepath.Join(templateDir, "files", "old.txt")); err != nil {
+		t.Fatal(err)
+	}
+	gitRun(t, templateDir, "add", "-A")
+	gitRun(t, templateDir, "commit", "-q", "-m", "v2")
+
+	plan, err := Prepare(Options{ProjectDir: projectDir, Manifest: loadLocal(t, templateDir), CacheDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Prepare returned error: %v", err)
+	}
+	if plan.Warning != "" {
+		t.Fatalf("unexpected warning: %s", plan.Warning)
+	}
+
+	actions := map[string]Action{}
+	for _, c := range plan.Changes {
+		actions[c.Path] = c.Action
+	}
+	want := map[string]Action{
+		"README.md": ActionMerge,
+		"Makefile":  ActionUpdate,
+		"new.txt":   ActionAdd,
+		"old.txt":   ActionDelete,
+		"notes.txt": ActionConflict,
+	}
+	for path, action := range want {
+		if actions[path] != action {
+			t.Fatalf("expected %s to be %s, got %v", path, action, actions)
+		}
+	}
+
+	if err := plan.Apply(); err != nil {
+		t.Fatalf("Apply returned error: %v", err)
+	}
+	readme, _ := os.ReadFile(filepath.Join(projectDir, "README.md"))
+	if !strings.Contains(string(readme), "Better intro") || !strings.Contains(string(readme), "My section") {
+		t.Fatalf("expected README to combine both changes, got:\n%s", readme)
+	}
+	notes, _ := os.ReadFile(filepath.Join(projectDir, "notes.txt"))
+	if !strings.Contains(string(notes), "<<<<<<< current") {
+		t.Fatalf("expected conflict markers in notes.txt, got:\n%s", notes)
+	}
+	if _, err := os.Stat(filepath.Join(projectDir, "old.txt")); !os.IsNotExist(err) {
+		t.Fatalf("expected old.txt to be removed")
+	}
+	state, err := template.ReadState(projectDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Version != "2.0.0" {
+		t.Fatalf("expected state to record version 2.0.0, got %s", state.Version)
+	}
+}
+
+func TestCompareLeavesUnchangedTemplateFilesAlone(t *testing.T) {
+	change, err := compare("a.txt", []byte("v1\n"), []byte("v1\n"), []byte("edited\n"))
+	if err != nil || change != nil {
+		t.Fatalf("expected no change when the template did not change, got %+v, %v", change, err)
+	}
+
+	change, err = compare("a.txt", nil, []byte("new\n"), nil)
+	if err != nil || change == nil || change.Action != ActionAdd {
+		t.Fatalf("expected an add, got %+v, %v", change, err)
+	}
+	change.Resolution = ResolveKeepMine
+	if change.Result() != nil {
+		t.Fatalf("expected keeping a missing file to leave it missing")
+	}
+}
+
+func TestPrepareReusesSecretAnswersForOriginalRender(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	templateDir := filepath.Join(t.TempDir(), "templates", "svc")
+	prompts := "prompts:\n  - name: project_name\n    type: text\n  - name: token\n    type: password\n    required: true\n"
+	writeFiles(t, templateDir, map[string]string{
+		"template.yaml":   "name: svc\nversion: 1.0.0\n" + prompts,
+		"files/Makefile":  "build:\n\tgo build\n",
+		"files/.env.tmpl": "TOKEN={{.token}}\n",
+	})
+	gitRun(t, templateDir, "init", "-q")
+	gitRun(t, templateDir, "add", ".")
+	gitRun(t, templateDir, "commit", "-q", "-m", "v1")
+
+	manifest := loadLocal(t, templateDir)
+	answers := map[string]interface{}{"project_name": "demo", "token": "s3cret"}
+	projectDir := t.TempDir()
+	sourceFS, err := template.ResolveTemplateFS(manifest, t.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := template.NewRenderer(manifest, answers).RenderTo(projectDir, sourceFS); err != nil {
+		t.Fatal(err)
+	}
+	if err := template.WriteState(projectDir, template.NewProjectState(manifest, answers, "")); err != nil {
+		t.Fatal(err)
+	}
+
+	writeFiles(t, templateDir, map[string]string{
+		"template.yaml":  "name: svc\nversion: 2.0.0\n" + prompts,
+		"files/Makefile": "build:\n\tgo build ./...\n",
+	})
+	gitRun(t, templateDir, "add", "-A")
+	gitRun(t, templateDir, "commit", "-q", "-m", "v2")
+
+	plan, err := Prepare(Options{
+		ProjectDir: projectDir,
+		Manifest:   loadLocal(t, templateDir),
+		CacheDir:   t.TempDir(),
+		Answers:    map[string]interface{}{"token": "s3cret"},
+	})
+	if err != nil {
+		t.Fatalf("Prepare returned error: %v", err)
+	}
+	if plan.Warning != "" {
+		t.Fatalf("unexpected warning: %s", plan.Warning)
+	}
+	if len(plan.Changes) != 1 || plan.Changes[0].Path != "Makefile" || plan.Changes[0].Action != ActionUpdate {
+		t.Fatalf("expected only a Makefile update, got %+v", plan.Changes)
+	}
+}